@@ -0,0 +1,100 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// goextract: a tool for extracting a sequence of Go statements into a
+// new function.
+//
+// Run with -help flag for usage information.
+//
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/build"
+	"os"
+
+	"code.google.com/p/go.tools/importer"
+	"code.google.com/p/go.tools/refactor/extract"
+)
+
+var posFlag = flag.String("pos", "",
+	"Filename and byte offset extent of the statements to extract, e.g. foo.go:#123,#456.")
+
+var nameFlag = flag.String("name", "", "Name of the new function.")
+
+var diffFlag = flag.Bool("d", false, "Display a diff of the change instead of applying it.")
+
+const useHelp = "Run 'goextract -help' for more information.\n"
+
+const helpMessage = `goextract: extract statements into a new function.
+Usage: goextract -pos <file>:#<start>,#<end> -name <name> [-d] <args> ...
+
+The selected statements, which must all belong to one block, are
+moved into a new package-level function declared after the enclosing
+declaration, and replaced by a call to it.
+
+The free local variables of the selection become the parameters of
+the new function.  Variables that the selection declares or updates
+and that may be used after it become its results, and are assigned
+by the call.  Return, break and continue statements that leave the
+selection are replaced by a return of a result code, which the call
+site tests to perform the same transfer of control.
+
+The extraction is refused if it would change the meaning of the
+program, for example if the selection takes the address of a local
+variable, calls recover, contains a goto, or defers a call that would
+run at a different time.
+
+With the -d flag, the change is displayed as a unified diff and the
+file is not modified.
+
+Example:
+
+Extract the statements between offsets 100 and 200 of foo.go:
+% goextract -pos foo.go:#100,#200 -name helper foo.go
+` + importer.InitialPackagesUsage
+
+func printHelp() {
+	fmt.Println(helpMessage)
+	fmt.Println("Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	// Don't print full help unless -help was requested.
+	// Just gently remind users that it's there.
+	flag.Usage = func() { fmt.Fprint(os.Stderr, useHelp) }
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError) // hack
+	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+		// (err has already been printed)
+		if err == flag.ErrHelp {
+			printHelp()
+		}
+		os.Exit(2)
+	}
+
+	args := flag.Args()
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, "Error: no package arguments.\n"+useHelp)
+		os.Exit(2)
+	}
+	if *posFlag == "" || *nameFlag == "" {
+		fmt.Fprint(os.Stderr, "Error: -pos and -name flags are required.\n"+useHelp)
+		os.Exit(2)
+	}
+
+	config := &extract.Config{
+		Build: &build.Default,
+		Args:  args,
+		Pos:   *posFlag,
+		Name:  *nameFlag,
+		Diff:  *diffFlag,
+		Out:   os.Stdout,
+	}
+	if err := extract.Main(config); err != nil {
+		fmt.Fprintf(os.Stderr, "goextract: %s.\n", err)
+		os.Exit(1)
+	}
+}
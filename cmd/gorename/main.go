@@ -0,0 +1,107 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// gorename: a tool for safe, type-aware renaming of Go identifiers.
+//
+// Run with -help flag for usage information.
+//
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/build"
+	"os"
+
+	"code.google.com/p/go.tools/importer"
+	"code.google.com/p/go.tools/refactor/rename"
+)
+
+var posFlag = flag.String("pos", "",
+	"Filename and byte offset of an identifier denoting the object to rename, e.g. foo.go:#123.")
+
+var fromFlag = flag.String("from", "",
+	"Qualified name of the object to rename, e.g. \"bytes.Buffer.Len\".")
+
+var toFlag = flag.String("to", "", "New name for the object.")
+
+var diffFlag = flag.Bool("d", false, "Display a diff of the changes instead of applying them.")
+
+const useHelp = "Run 'gorename -help' for more information.\n"
+
+const helpMessage = `gorename: safe, type-aware renaming of Go identifiers.
+Usage: gorename (-pos <file>:#<byte-offset> | -from <name>) -to <name> [-d] <args> ...
+
+The object to rename is specified either by the position of an
+identifier that refers to it (-pos) or by its qualified name (-from):
+
+	"pkg.Name"		a package-level const, var, type or func
+	"pkg.Type.Name"		a field or method of a package-level type
+
+where pkg is an import path.  Local variables may only be specified
+by -pos.
+
+All references to the object within the packages specified by the
+arguments are updated.  The renaming is refused if it would change the
+meaning of the program, for example by introducing a conflicting
+declaration, causing a reference to be shadowed or captured, or
+causing a type to no longer implement an interface.  Packages that
+are not loaded are not updated, so the arguments should include every
+package that depends on the object.
+
+With the -d flag, the changes are displayed as a unified diff and the
+files are not modified.
+
+Examples:
+
+Rename the Len method of bytes.Buffer in package mypkg:
+% gorename -from mypkg.Buffer.Len -to Size mypkg
+
+Rename the identifier at offset 123 of foo.go, showing a diff:
+% gorename -pos foo.go:#123 -to bar -d foo.go
+` + importer.InitialPackagesUsage
+
+func printHelp() {
+	fmt.Println(helpMessage)
+	fmt.Println("Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	// Don't print full help unless -help was requested.
+	// Just gently remind users that it's there.
+	flag.Usage = func() { fmt.Fprint(os.Stderr, useHelp) }
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError) // hack
+	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+		// (err has already been printed)
+		if err == flag.ErrHelp {
+			printHelp()
+		}
+		os.Exit(2)
+	}
+
+	args := flag.Args()
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, "Error: no package arguments.\n"+useHelp)
+		os.Exit(2)
+	}
+	if *toFlag == "" {
+		fmt.Fprint(os.Stderr, "Error: -to flag is required.\n"+useHelp)
+		os.Exit(2)
+	}
+
+	config := &rename.Config{
+		Build: &build.Default,
+		Args:  args,
+		Pos:   *posFlag,
+		From:  *fromFlag,
+		To:    *toFlag,
+		Diff:  *diffFlag,
+		Out:   os.Stdout,
+	}
+	if err := rename.Main(config); err != nil {
+		fmt.Fprintf(os.Stderr, "gorename: %s.\n", err)
+		os.Exit(1)
+	}
+}
@@ -19,9 +19,13 @@ import (
 	"go/build"
 	"io"
 	"log"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
 	"os"
 	"runtime"
 	"runtime/pprof"
+	"strings"
 
 	"code.google.com/p/go.tools/importer"
 	"code.google.com/p/go.tools/oracle"
@@ -34,7 +38,24 @@ var posFlag = flag.String("pos", "",
 var ptalogFlag = flag.String("ptalog", "",
 	"Location of the points-to analysis log file, or empty to disable logging.")
 
-var formatFlag = flag.String("format", "plain", "Output format.  One of {plain,json,xml}.")
+var formatFlag = flag.String("format", "plain", "Output format.  One of {plain,json,xml,dot}.")
+
+var serverFlag = flag.Bool("server", false,
+	"Run as a server, answering a sequence of JSON-RPC requests about the program.")
+
+var socketFlag = flag.String("socket", "",
+	"Location of the Unix domain socket on which the server listens, or empty for stdin/stdout.")
+
+var packagesFlag = flag.String("packages", "",
+	"Comma-separated list of import path patterns, e.g. foo/...,bar, of packages whose results to report.")
+
+var notestsFlag = flag.Bool("notests", false, "Exclude results in _test.go files.")
+
+var sortFlag = flag.Bool("sort", false, "Sort results by position.")
+
+var limitFlag = flag.Int("limit", 0, "Maximum number of results to report, or zero for no limit.")
+
+var startFlag = flag.String("start", "", "Continuation token from a previous query, to report its next page of results.")
 
 // TODO(adonovan): eliminate or flip this flag after PTA presolver is implemented.
 var reflectFlag = flag.Bool("reflect", true, "Analyze reflection soundly (slow).")
@@ -43,14 +64,30 @@ const useHelp = "Run 'oracle -help' for more information.\n"
 
 const helpMessage = `Go source code oracle.
 Usage: oracle [<flag> ...] <mode> <args> ...
+       oracle -server [-socket=<file>] [<flag> ...] <args> ...
 
 The -format flag controls the output format:
 	plain	an editor-friendly format in which every line of output
 		is of the form "pos: text", where pos is "-" if unknown.
 	json	structured data in JSON syntax.
 	xml	structured data in XML syntax.
+	dot	a graph in the DOT language of Graphviz.
+		Supported only by the 'channels' mode.
+
+The -pos flag is required in all modes except 'callgraph', 'channels'
+and 'taint'.
 
-The -pos flag is required in all modes except 'callgraph'.
+The -packages, -notests, -sort, -limit and -start flags restrict the
+results of the callers, callgraph, channels and referrers modes, which are
+otherwise dominated by the standard library.  Once -limit results have
+been reported, the output ends with a continuation token; pass it to
+-start to obtain the next page.  The -limit and -start flags imply
+-sort.  A continuation token is rejected if the results have changed,
+for example because the program has been modified.  The referrers mode
+searches just the selected packages, and the callgraph mode records
+just the calls from the selected functions, but the pointer analysis
+of the callers, callgraph and channels modes still considers the
+whole program.
 
 The mode argument determines the query to perform:
 
@@ -58,11 +95,35 @@ The mode argument determines the query to perform:
 	callers	  	show possible callers of selected function
 	callgraph 	show complete callgraph of program
 	callstack 	show path from callgraph root to selected function
+	channels  	show all channels and the operations and goroutines using them
+	definition	show declaration of selected identifier
 	describe  	describe selected syntax: definition, methods, etc
 	freevars  	show free variables of selection
-	implements	show 'implements' relation for selected package
+	implements	show 'implements' relation for selected type, method or package
 	peers     	show send/receive corresponding to selected channel op
+	pointsto  	show objects to which selected expression may point
 	referrers 	show all refs to entity denoted by selected identifier
+	taint     	show flows of untrusted data to vulnerable sinks
+	whicherrs 	show possible values of selected error expression
+	whypointsto	like pointsto, explaining how each object reaches it (slow)
+
+With the -server flag, the oracle loads the program once and then
+answers a sequence of queries about it, communicating by JSON-RPC
+(see http://json-rpc.org/wiki/specification) over stdin/stdout, or
+over the Unix domain socket specified by -socket.  The methods are:
+
+	Oracle.Query	params: [{"mode": <mode>, "pos": <pos>, "filter": <filter>}]
+			result: the query result, as for -format=json.
+			The optional filter is an object with the fields
+			"packages", "notests", "sort", "limit" and "start",
+			as for the flags of the same names.
+	Oracle.Changed	params: [{"files": [<file>, ...]}]
+			result: {"reloaded": <bool>}
+			Notifies the server that the files have changed.
+			The next query reloads the packages to which they
+			may belong, and those that import them, and then
+			analyzes the program again; "reloaded" reports
+			whether a loaded program was discarded.
 
 The user manual is available here:  http://golang.org/s/oracle-user-manual
 
@@ -110,16 +171,19 @@ func main() {
 	}
 
 	args := flag.Args()
-	if len(args) == 0 || args[0] == "" {
+	if !*serverFlag && (len(args) == 0 || args[0] == "") {
 		fmt.Fprint(os.Stderr, "Error: a mode argument is required.\n"+useHelp)
 		os.Exit(2)
 	}
 
-	mode := args[0]
-	args = args[1:]
-	if mode == "help" {
-		printHelp()
-		os.Exit(2)
+	var mode string
+	if !*serverFlag {
+		mode = args[0]
+		args = args[1:]
+		if mode == "help" {
+			printHelp()
+			os.Exit(2)
+		}
 	}
 
 	if len(args) == 0 {
@@ -132,6 +196,10 @@ func main() {
 	if *ptalogFlag != "" {
 		if f, err := os.Create(*ptalogFlag); err != nil {
 			log.Fatalf("Failed to create PTA log file: %s", err)
+		} else if *serverFlag {
+			// A server may run until it is killed, so don't buffer.
+			ptalog = f
+			defer f.Close()
 		} else {
 			buf := bufio.NewWriter(f)
 			ptalog = buf
@@ -152,17 +220,36 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
+	if *serverFlag {
+		if err := serve(args, ptalog); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s.\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// -format flag
 	switch *formatFlag {
-	case "json", "plain", "xml":
+	case "json", "plain", "xml", "dot":
 		// ok
 	default:
 		fmt.Fprintf(os.Stderr, "Error: illegal -format value: %q.\n"+useHelp, *formatFlag)
 		os.Exit(2)
 	}
 
+	// Filter flags
+	filter := &oracle.Filter{
+		NoTests: *notestsFlag,
+		Sort:    *sortFlag,
+		Limit:   *limitFlag,
+		Start:   *startFlag,
+	}
+	if *packagesFlag != "" {
+		filter.Packages = strings.Split(*packagesFlag, ",")
+	}
+
 	// Ask the oracle.
-	res, err := oracle.Query(args, mode, *posFlag, ptalog, &build.Default, *reflectFlag)
+	res, err := oracle.QueryWithFilter(args, mode, *posFlag, filter, ptalog, &build.Default, *reflectFlag)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %s.\n", err)
 		os.Exit(1)
@@ -188,5 +275,48 @@ func main() {
 
 	case "plain":
 		res.WriteTo(os.Stdout)
+
+	case "dot":
+		if err := res.WriteDOT(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s.\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// serve runs the oracle as a JSON-RPC server for the program specified
+// by args, until its input is exhausted.  ptalog is the (optional)
+// pointer-analysis log file.
+//
+func serve(args []string, ptalog io.Writer) error {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("Oracle", oracle.NewServer(args, ptalog, &build.Default, *reflectFlag)); err != nil {
+		return err
+	}
+
+	if *socketFlag == "" {
+		srv.ServeCodec(jsonrpc.NewServerCodec(stdio{}))
+		return nil
+	}
+
+	l, err := net.Listen("unix", *socketFlag)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go srv.ServeCodec(jsonrpc.NewServerCodec(conn))
 	}
 }
+
+// stdio is an io.ReadWriteCloser that reads from os.Stdin and writes
+// to os.Stdout.
+type stdio struct{}
+
+func (stdio) Read(p []byte) (int, error)  { return os.Stdin.Read(p) }
+func (stdio) Write(p []byte) (int, error) { return os.Stdout.Write(p) }
+func (stdio) Close() error                { return os.Stdin.Close() }
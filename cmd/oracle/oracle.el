@@ -39,7 +39,9 @@
 (defvar go-oracle-mode-map
   (let ((m (make-sparse-keymap)))
     (define-key m (kbd "C-c C-o d") #'go-oracle-describe)
+    (define-key m (kbd "C-c C-o j") #'go-oracle-definition)
     (define-key m (kbd "C-c C-o f") #'go-oracle-freevars)
+    (define-key m (kbd "C-c C-o c") #'go-oracle-channels)
     (define-key m (kbd "C-c C-o g") #'go-oracle-callgraph)
     (define-key m (kbd "C-c C-o i") #'go-oracle-implements)
     (define-key m (kbd "C-c C-o p") #'go-oracle-peers)
@@ -165,6 +167,12 @@ result."
   (interactive)
   (go-oracle--run "callgraph"))
 
+(defun go-oracle-channels ()
+  "Show the channels of the current program and the operations and
+goroutines that use them."
+  (interactive)
+  (go-oracle--run "channels"))
+
 (defun go-oracle-callstack ()
   "Show an arbitrary path from a root of the call graph to the
 function containing the current point."
@@ -176,9 +184,14 @@ function containing the current point."
   (interactive)
   (go-oracle--run "describe"))
 
+(defun go-oracle-definition ()
+  "Show the declaration of the identifier at the current point."
+  (interactive)
+  (go-oracle--run "definition"))
+
 (defun go-oracle-implements ()
-  "Describe the 'implements' relation for types in the package
-containing the current point."
+  "Describe the 'implements' relation for the type or method at
+the current point, or for all types in its package."
   (interactive)
   (go-oracle--run "implements"))
 
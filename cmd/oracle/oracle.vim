@@ -84,6 +84,10 @@ endfun
 command! -range=% GoOracleDescribe
   \ call s:RunOracle('describe', <count>)
 
+" Show the declaration of the identifier at the current point.
+command! -range=% GoOracleDefinition
+  \ call s:RunOracle('definition', <count>)
+
 " Show possible callees of the function call at the current point.
 command! -range=% GoOracleCallees
   \ call s:RunOracle('callees', <count>)
@@ -96,8 +100,13 @@ command! -range=% GoOracleCallers
 command! -range=% GoOracleCallgraph
   \ call s:RunOracle('callgraph', <count>)
 
-" Describe the 'implements' relation for types in the
-" package containing the current point.
+" Show the channels of the current program and the operations and
+" goroutines that use them.
+command! -range=% GoOracleChannels
+  \ call s:RunOracle('channels', <count>)
+
+" Describe the 'implements' relation for the type or method at
+" the current point, or for all types in its package.
 command! -range=% GoOracleImplements
   \ call s:RunOracle('implements', <count>)
 
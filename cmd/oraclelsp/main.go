@@ -0,0 +1,117 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// oraclelsp: an adapter that answers language server protocol
+// requests using the oracle.
+//
+// Run with -help flag for usage information.
+//
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/build"
+	"net"
+	"os"
+
+	"code.google.com/p/go.tools/importer"
+	"code.google.com/p/go.tools/oracle/lsp"
+)
+
+var socketFlag = flag.String("socket", "",
+	"Location of the Unix domain socket on which to listen, or empty for stdin/stdout.")
+
+// TODO(adonovan): eliminate or flip this flag after PTA presolver is implemented.
+var reflectFlag = flag.Bool("reflect", true, "Analyze reflection soundly (slow).")
+
+const useHelp = "Run 'oraclelsp -help' for more information.\n"
+
+const helpMessage = `oraclelsp: a language server protocol adapter for the Go oracle.
+Usage: oraclelsp [-socket=<file>] [<flag> ...] <args> ...
+
+The adapter loads the program specified by <args>, the analysis
+scope, and answers language server protocol requests about it,
+communicating by JSON-RPC over stdin/stdout, or over the Unix domain
+socket specified by -socket.  Positions are lines and UTF-16
+character offsets, as the protocol requires.
+
+Requests are answered by these oracle queries:
+
+	textDocument/definition		definition
+	textDocument/references		referrers
+	textDocument/hover		describe
+	textDocument/implementation	implements
+	textDocument/prepareCallHierarchy,
+	callHierarchy/incomingCalls,
+	callHierarchy/outgoingCalls	callgraph and callers
+
+Queries concern the saved contents of files; the program is reloaded
+after a file is saved.  Queries about a document with unsaved changes
+are refused with an error asking for it to be saved.  When a document is opened or changed, the
+adapter type-checks the package containing it, using the unsaved
+contents of open documents, and publishes its errors as diagnostics.
+
+Example:
+
+Serve requests about the oracle itself:
+% oraclelsp code.google.com/p/go.tools/cmd/oracle
+` + importer.InitialPackagesUsage
+
+func printHelp() {
+	fmt.Println(helpMessage)
+	fmt.Println("Flags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	// Don't print full help unless -help was requested.
+	// Just gently remind users that it's there.
+	flag.Usage = func() { fmt.Fprint(os.Stderr, useHelp) }
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError) // hack
+	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+		// (err has already been printed)
+		if err == flag.ErrHelp {
+			printHelp()
+		}
+		os.Exit(2)
+	}
+
+	args := flag.Args()
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, "Error: no package arguments.\n"+useHelp)
+		os.Exit(2)
+	}
+
+	if err := serve(args); err != nil {
+		fmt.Fprintf(os.Stderr, "oraclelsp: %s.\n", err)
+		os.Exit(1)
+	}
+}
+
+// serve answers requests about the program specified by args, over
+// stdin/stdout or, if -socket is set, over each accepted connection in
+// turn.
+//
+func serve(args []string) error {
+	if *socketFlag == "" {
+		return lsp.NewServer(args, &build.Default, *reflectFlag).Serve(os.Stdin, os.Stdout)
+	}
+
+	l, err := net.Listen("unix", *socketFlag)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		if err := lsp.NewServer(args, &build.Default, *reflectFlag).Serve(conn, conn); err != nil {
+			fmt.Fprintf(os.Stderr, "oraclelsp: %s.\n", err)
+		}
+		conn.Close()
+	}
+}
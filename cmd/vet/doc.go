@@ -37,6 +37,17 @@ complains about arguments that look like format descriptor strings.
 It also checks for errors such as using a Writer as the first argument of
 Printf.
 
+Functions that pass their final "format string, args ...interface{}"
+or "args ...interface{}" parameters on to one of these functions, such
+as logging helpers, are checked in the same way.  The format may be
+passed on with constant text added, as in "app: "+format.  Such
+wrappers are inferred from their source, in the package being checked
+and in the packages it imports, so they need not be listed with
+-printfuncs.  What is inferred is kept in memory for the current run
+only: it is not saved between runs, so every run of vet infers it again
+from source, and wrappers in packages whose source is not available
+are not recognized.
+
 2. Methods, flag -methods
 
 Non-standard signatures for methods with familiar names, including:
@@ -53,6 +64,75 @@ Struct tags that do not follow the format understood by reflect.StructTag.Get.
 
 Composite struct literals that do not use the field-keyed syntax.
 
+5. Copying locks, flag -copylocks
+
+Values of types that contain a lock, such as sync.Mutex, sync.RWMutex or
+sync.WaitGroup, that are copied: passed by value as function parameters
+or receivers, assigned, used as range variables or as elements of
+composite literals.  A lock is a type whose pointer, but not whose value,
+has Lock and Unlock methods.
+
+6. Unused results, flag -unusedresult
+
+Calls in statement position to functions whose results are almost always
+needed, so that discarding them is likely a mistake:
+	append bytes.TrimSpace errors.New fmt.Errorf
+	fmt.Sprint fmt.Sprintf fmt.Sprintln sort.Reverse
+	strings.Replace strings.TrimSpace (*bytes.Buffer).String
+The callee is identified using type information, not just by name.
+
+7. Unchecked errors, flag -errcheck
+
+Calls, in statement position or assigned to the blank identifier _,
+that return a value of type error that is then ignored.  Calls of
+the Print and Fprint families of package fmt and of the Write methods
+of bytes.Buffer, which rarely or never fail, and of the functions
+checked by -unusedresult, are not reported.  Deferred calls are reported,
+except for calls of methods named Close.  This check is experimental
+and is performed only if requested explicitly.
+
+8. Boolean expressions, flag -bools
+
+Chains of || or && expressions that repeat an operand, such as
+x == a || x == a; that compare an expression as unequal to distinct
+constants, such as x != 1 || x != 2, which is always true; or that
+compare it as equal to distinct constants, such as x == 1 && x == 2,
+which is always false.  Comparisons of an expression with itself,
+such as x == x, other than of floating-point values.  Operands that
+may have side effects, such as function calls, are ignored.
+
+Additional checks
+
+Each check is an Analyzer defined by package code.google.com/p/go.tools/vet.
+A vet command that performs further checks may be built by defining
+analyzers for them and passing them, along with vet.Analyzers, to vet.Main.
+Each analyzer is enabled by a flag of the same name.
+
+
+Suppressing problems
+
+A comment of the form
+
+	//vet:ignore printf,composites
+
+suppresses the problems reported by the named checks, or by all checks
+if none is named, on the line it ends, or on the next line if it is
+alone on its line.  If it is part of the doc comment of a declaration,
+it applies to the whole declaration.  Text following the list of names
+is ignored, so it may be used to give a reason.
+
+Problems may also be suppressed throughout packages or files by a
+JSON configuration file named by the -config flag:
+
+	{"Suppress": [
+		{"Checks": ["composites"], "Packages": ["example.com/legacy/..."]},
+		{"Checks": ["printf", "shadow"], "Files": ["*_test.go"]}
+	]}
+
+Package patterns are import paths in which "..." matches any string.
+File patterns are as for filepath.Match; a pattern without a slash
+matches the base name of a file.  An empty list of checks suppresses
+all of them.
 
 Usage:
 
@@ -62,6 +142,46 @@ Usage:
 The other flags are:
 	-v
 		Verbose mode
+	-json
+		Print each problem found as a JSON object, on a line of its own,
+		on standard output.  The object holds the name of the check
+		that reported it, its position (file name, line, column and
+		byte offset), its severity ("error" or "warning") and the
+		message.  Each check is named by the flag that enables it.
+	-config
+		The name of a configuration file of suppressions, as above.
+	-unusedsuppressions
+		Report //vet:ignore comments and configured suppressions
+		that suppress nothing, so that they may be removed.
+	-fix
+		Apply the fixes suggested for some problems, such as adding
+		field names to unkeyed composite literals, canonicalizing
+		malformed struct tags, and turning Println calls with
+		formatting directives into Printf calls, then rewrite the
+		affected files, formatted in the style of gofmt.
+	-d
+		With -fix, print a unified diff of each change instead of
+		rewriting the files.
+	-tags
+		A space-separated list of build tags to consider satisfied
+		when selecting the files of each package and of the packages
+		it imports.  The target operating system and architecture
+		are taken from $GOOS and $GOARCH, as for the go command.
+		Imported packages are type-checked from source, so they need
+		not be installed.
+	-unusedfuncs
+		A comma-separated list of functions whose results must be
+		used, to supplement the standard list.  Functions are named
+		by package and name, such as fmt.Sprintf, and methods by
+		receiver type and name, such as (*bytes.Buffer).String.
+	-errcheckexclude
+		A comma-separated list of functions whose errors need not be
+		checked by -errcheck, to supplement the standard list, named
+		as for -unusedfuncs.  A name ending in * stands for all the
+		names of which the rest is a prefix, as in (*bufio.Writer).Write*.
+	-errcheckdefer
+		Which deferred calls -errcheck checks: none, all, or all but
+		those of Close methods (noclose, the default).
 	-printfuncs
 		A comma-separated list of print-like functions to supplement
 		the standard list.  Each entry is in the form Name:N where N
@@ -70,7 +190,20 @@ The other flags are:
 		argument for non-formatted prints.  For example,
 		if you have Warn and Warnf functions that take an
 		io.Writer as their first argument, like Fprintf,
-			-printfuncs=Warn:1,Warnf:1
+			-unusedfuncs
+		A comma-separated list of functions whose results must be
+		used, to supplement the standard list.  Functions are named
+		by package and name, such as fmt.Sprintf, and methods by
+		receiver type and name, such as (*bytes.Buffer).String.
+	-errcheckexclude
+		A comma-separated list of functions whose errors need not be
+		checked by -errcheck, to supplement the standard list, named
+		as for -unusedfuncs.  A name ending in * stands for all the
+		names of which the rest is a prefix, as in (*bufio.Writer).Write*.
+	-errcheckdefer
+		Which deferred calls -errcheck checks: none, all, or all but
+		those of Close methods (noclose, the default).
+	-printfuncs=Warn:1,Warnf:1
 
 */
 package main
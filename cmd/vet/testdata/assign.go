@@ -16,3 +16,10 @@ func (s *ST) SetX(x int) {
 	// Another mistake
 	s.x = s.x // ERROR "self-assignment of s.x to s.x"
 }
+
+func (s *ST) SetXs(x int, xs []int, next func() int) {
+	(x) = x         // ERROR "self-assignment of x to x"
+	x, s.x = x, s.x // ERROR "self-assignment of (s.)?x to (s.)?x"
+	xs[next()] = xs[next()]
+	x, s.x = s.x, x
+}
@@ -0,0 +1,56 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains tests for the bools checker.
+
+package testdata
+
+type T int
+
+const (
+	zero T = iota
+	one
+)
+
+func RatherStupidConditions() {
+	var f, g func() int
+	if f() == 0 || f() == 0 { // OK f might have side effects
+	}
+	var x, y int
+	if x == 0 || x == 0 { // ERROR "redundant or: x == 0 \|\| x == 0"
+	}
+	if (x == 0) || y == 1 || x == 0 { // ERROR "redundant or: x == 0 \|\| x == 0"
+	}
+	if x == 0 && x == 0 { // ERROR "redundant and: x == 0 && x == 0"
+	}
+	if x != 0 || x != 1 { // ERROR "suspect or: x != 0 \|\| x != 1"
+	}
+	if x != 0 || y != 1 {
+	}
+	if x == 0 && x == 1 { // ERROR "suspect and: x == 0 && x == 1"
+	}
+	if x == 0 && 1 == x { // ERROR "suspect and: x == 0 && x == 1"
+	}
+	if x == 0 || x == 1 {
+	}
+	var t T
+	if t != zero || t != one { // ERROR "suspect or: t != zero \|\| t != one"
+	}
+	if x != 0 || x != 0 { // ERROR "redundant or: x != 0 \|\| x != 0"
+	}
+	if f() != 0 || f() != 1 || g() != 2 {
+	}
+
+	if x == x { // ERROR "suspicious self-comparison: x == x"
+	}
+	if t >= t { // ERROR "suspicious self-comparison: t >= t"
+	}
+	var z float64
+	if z != z { // OK NaN test
+	}
+	if f() == f() {
+	}
+	if len("a") == len("a") {
+	}
+}
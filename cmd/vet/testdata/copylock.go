@@ -0,0 +1,64 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains tests for the copylocks checker.
+
+package testdata
+
+import "sync"
+
+type Tlock struct {
+	once sync.Once
+}
+
+type LocksByValue struct {
+	mu sync.Mutex
+	wg sync.WaitGroup
+}
+
+func (LocksByValue) Bad()   {} // ERROR "Bad passes lock by value: testdata.LocksByValue contains sync.Mutex"
+func (*LocksByValue) Good() {}
+
+func BadParam(mu sync.Mutex)   {} // ERROR "BadParam passes lock by value: sync.Mutex"
+func GoodParam(mu *sync.Mutex) {}
+
+func BadNested(t Tlock) {} // ERROR "BadNested passes lock by value: testdata.Tlock contains sync.Once contains sync.Mutex"
+
+func CopyLocks() {
+	var x sync.Mutex
+	y := x    // ERROR "assignment copies lock value to y: sync.Mutex"
+	y = x     // ERROR "assignment copies lock value to y: sync.Mutex"
+	var z = x // ERROR "variable declaration copies lock value to z: sync.Mutex"
+	p := &x
+	w := *p // ERROR "assignment copies lock value to w: sync.Mutex"
+	_, _, _ = y, z, w
+
+	var a [2]sync.RWMutex
+	b := a // ERROR "assignment copies lock value to b: \[2\]sync.RWMutex contains sync.RWMutex"
+	_ = b
+
+	t := LocksByValue{}
+	s := struct{ l LocksByValue }{t} // ERROR "literal copies lock value from t: testdata.LocksByValue contains sync.Mutex"
+	u := []LocksByValue{t}           // ERROR "literal copies lock value from t: testdata.LocksByValue contains sync.Mutex"
+	_, _ = s, u
+
+	var m sync.Mutex
+	_ = []*sync.Mutex{&m}
+	_ = func(sync.Mutex) {} // ERROR "func passes lock by value: sync.Mutex"
+	q := new(sync.Mutex)
+	q.Lock()
+}
+
+func RangeLocks(s []sync.Mutex, m map[string]sync.Mutex) {
+	for _, mu := range s { // ERROR "range var mu copies lock: sync.Mutex"
+		_ = mu
+	}
+	for i := range s {
+		s[i].Lock()
+	}
+	var mu sync.Mutex
+	for _, mu = range m { // ERROR "range var mu copies lock: sync.Mutex"
+	}
+	_ = mu
+}
@@ -0,0 +1,49 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains tests for the errcheck checker.
+
+package testdata
+
+import (
+	"bytes"
+	"fmt"
+)
+
+type closer struct{}
+
+func (closer) Close() error { return nil }
+func (closer) Flush() error { return nil }
+
+func mayFail() error             { return nil }
+func valueOrError() (int, error) { return 0, nil }
+func noError() int               { return 0 }
+
+func ErrcheckTests() {
+	mayFail()              // ERROR "unchecked error returned by testdata.mayFail"
+	(mayFail())            // ERROR "unchecked error returned by testdata.mayFail"
+	_ = mayFail()          // ERROR "unchecked error returned by testdata.mayFail"
+	x, _ := valueOrError() // ERROR "unchecked error returned by testdata.valueOrError"
+	_, err := valueOrError()
+	_, _ = x, err
+	_, y := mayFail(), noError() // ERROR "unchecked error returned by testdata.mayFail"
+	_ = y
+	noError()
+	if err := mayFail(); err != nil {
+		return
+	}
+
+	fn := mayFail
+	fn() // ERROR "unchecked error returned by fn"
+
+	var c closer
+	c.Flush() // ERROR "unchecked error returned by \(testdata.closer\).Flush"
+	defer c.Close()
+	defer c.Flush() // ERROR "unchecked error returned by \(testdata.closer\).Flush"
+	go mayFail()
+
+	var buf bytes.Buffer
+	buf.WriteString("x")
+	fmt.Fprintf(&buf, "y")
+}
@@ -281,17 +281,17 @@ var percentSV percentSStruct
 type recursiveStringer int
 
 func (s recursiveStringer) String() string {
-	fmt.Sprintf("%d", s)
-	fmt.Sprintf("%#v", s)
-	fmt.Sprintf("%v", s)   // ERROR "arg s for printf causes recursive call to String method"
-	fmt.Sprintf("%v", &s)  // ERROR "arg &s for printf causes recursive call to String method"
-	return fmt.Sprintln(s) // ERROR "arg s for print causes recursive call to String method"
+	_ = fmt.Sprintf("%d", s)
+	_ = fmt.Sprintf("%#v", s)
+	_ = fmt.Sprintf("%v", s)  // ERROR "arg s for printf causes recursive call to String method"
+	_ = fmt.Sprintf("%v", &s) // ERROR "arg &s for printf causes recursive call to String method"
+	return fmt.Sprintln(s)    // ERROR "arg s for print causes recursive call to String method"
 }
 
 type recursivePtrStringer int
 
 func (p *recursivePtrStringer) String() string {
-	fmt.Sprintf("%v", *p)
+	_ = fmt.Sprintf("%v", *p)
 	return fmt.Sprintln(p) // ERROR "arg p for print causes recursive call to String method"
 }
 
@@ -323,3 +323,24 @@ type RecursiveStruct2 struct {
 }
 
 var recursiveStruct1V = &RecursiveStruct1{}
+
+// logf and logln are print wrappers, which vet infers from their bodies.
+func logf(format string, args ...interface{}) {
+	fmt.Printf(format, args...)
+}
+
+func logln(args ...interface{}) {
+	fmt.Println(args...)
+}
+
+// notlogf does not pass on its format.
+func notlogf(format string, args ...interface{}) {
+	fmt.Printf("%s", args...)
+}
+
+func PrintWrappers() {
+	logf("%d", 3)
+	logf("%d", "hi") // ERROR "arg .hi. for printf verb %d of wrong type"
+	logln("%d", 3)   // ERROR "possible formatting directive in logln call"
+	notlogf("%d", "hi")
+}
@@ -65,6 +65,7 @@ type Importer struct {
 	allPackages   []*PackageInfo         // all packages, including non-importable ones
 	importedMu    sync.Mutex             // guards 'imported'
 	imported      map[string]*importInfo // all imported packages (incl. failures) by import path
+	reused        int                    // number of packages reused from a previous Importer
 }
 
 // importInfo holds internal information about each import path.
@@ -120,6 +121,55 @@ func (imp *Importer) AllPackages() []*PackageInfo {
 	return append([]*PackageInfo(nil), imp.allPackages...)
 }
 
+// Reuse makes imp reuse the importable packages previously loaded by
+// old instead of loading them again, except for those for which stale
+// returns true, those that had errors, and those that import any of
+// these, directly or indirectly.  It allows a client to reload a
+// program after some of its files have changed without parsing and
+// type-checking its unaffected packages again.
+//
+// imp adopts the FileSet of old, which must have the same
+// configuration as imp.  Non-importable packages, such as those
+// created from files named on the command line, are never reused.
+//
+// Precondition: Reuse must be called before any call to Load* on imp.
+//
+func (imp *Importer) Reuse(old *Importer, stale func(info *PackageInfo) bool) {
+	imp.Fset = old.Fset
+
+	reusable := make(map[string]bool) // memo of import path to reusability
+	var visit func(path string) bool
+	visit = func(path string) bool {
+		if ok, seen := reusable[path]; seen {
+			return ok
+		}
+		reusable[path] = false // (import cycles are errors anyway)
+		ii := old.imported[path]
+		if ii == nil || ii.info == nil || ii.info.Err != nil || stale(ii.info) {
+			return false
+		}
+		for _, dep := range ii.info.Pkg.Imports() {
+			if dep != types.Unsafe && !visit(dep.Path()) {
+				return false
+			}
+		}
+		reusable[path] = true
+		return true
+	}
+
+	for _, info := range old.allPackages {
+		if !info.Importable {
+			continue
+		}
+		path := info.Pkg.Path()
+		if ii := old.imported[path]; ii != nil && ii.info == info && visit(path) {
+			imp.imported[path] = ii
+			imp.allPackages = append(imp.allPackages, info)
+		}
+	}
+	imp.reused = len(imp.allPackages)
+}
+
 func (imp *Importer) addPackage(info *PackageInfo) {
 	imp.allPackagesMu.Lock()
 	imp.allPackages = append(imp.allPackages, info)
@@ -343,7 +393,7 @@ A '--' argument terminates the list of packages.
 func (imp *Importer) LoadInitialPackages(args []string) ([]*PackageInfo, []string, error) {
 	// The "augmentation" mechanism requires that we mark all
 	// packages to be augmented before we import a single one.
-	if len(imp.allPackages) > 0 {
+	if len(imp.allPackages) > imp.reused {
 		return nil, nil, errors.New("LoadInitialPackages called on non-pristine Importer")
 	}
 
@@ -64,3 +64,42 @@ func TestLoadInitialPackages(t *testing.T) {
 		}
 	}
 }
+
+func TestReuse(t *testing.T) {
+	ctxt := build.Default // copy
+	ctxt.GOPATH = "testdata"
+	args := []string{"notest:use", "notest:other"}
+
+	old := importer.New(&importer.Config{Build: &ctxt})
+	if _, _, err := old.LoadInitialPackages(args); err != nil {
+		t.Fatalf("LoadInitialPackages(%q) failed: %s", args, err)
+	}
+	oldInfos := make(map[string]*importer.PackageInfo)
+	for _, info := range old.AllPackages() {
+		oldInfos[info.Pkg.Path()] = info
+	}
+
+	// Package dep is stale, so package use, which imports it, must
+	// be loaded again too.
+	imp := importer.New(&importer.Config{Build: &ctxt})
+	imp.Reuse(old, func(info *importer.PackageInfo) bool {
+		return info.Pkg.Path() == "dep"
+	})
+	if _, _, err := imp.LoadInitialPackages(args); err != nil {
+		t.Fatalf("LoadInitialPackages(%q) after Reuse failed: %s", args, err)
+	}
+	if imp.Fset != old.Fset {
+		t.Errorf("Reuse did not adopt the FileSet of the old Importer")
+	}
+	want := map[string]bool{"dep": false, "use": false, "other": true}
+	for _, info := range imp.AllPackages() {
+		path := info.Pkg.Path()
+		if got := info == oldInfos[path]; got != want[path] {
+			t.Errorf("Reuse: package %s reused = %t, want %t", path, got, want[path])
+		}
+		delete(want, path)
+	}
+	for path := range want {
+		t.Errorf("Reuse: package %s was not loaded", path)
+	}
+}
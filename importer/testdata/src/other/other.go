@@ -0,0 +1,3 @@
+package other
+
+const O = 2
@@ -0,0 +1,5 @@
+package use
+
+import "dep"
+
+const U = dep.D
@@ -0,0 +1,149 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ptaindex defines Index, an index of the call graph and the
+// memory accesses of a program analyzed by the pointer analysis, for
+// the clients within go.tools that search the flow of values through
+// the program: the taint analysis and the oracle's whypointsto mode.
+//
+// It is not part of the API of the pointer package.
+//
+package ptaindex
+
+import (
+	"go/token"
+
+	"code.google.com/p/go.tools/call"
+	"code.google.com/p/go.tools/go/types"
+	"code.google.com/p/go.tools/pointer"
+	"code.google.com/p/go.tools/ssa"
+)
+
+// An Index records the call graph of a program, ignoring contexts,
+// and the instructions by which the program loads from and stores to
+// memory.
+//
+// An Index is built in two phases: New adds to the analysis
+// configuration the queries and the call graph on which the index
+// depends, and Init records the result of the analysis.
+//
+type Index struct {
+	Callees map[ssa.CallInstruction][]*ssa.Function // callees of each call site, after Init
+	Callers map[*ssa.Function][]ssa.CallInstruction // call sites of each function, after Init
+	Loads   []ssa.Value                             // values loaded through their Addresses
+	Stores  []ssa.Instruction                       // instructions that store through their Addresses
+}
+
+// New returns an index of the loads and stores of all functions of
+// prog.  It adds to config a query for each pointer through which
+// they load or store, unless config already queries it, and enables
+// BuildCallGraph.
+//
+func New(prog *ssa.Program, config *pointer.Config) *Index {
+	ix := &Index{
+		Callees: make(map[ssa.CallInstruction][]*ssa.Function),
+		Callers: make(map[*ssa.Function][]ssa.CallInstruction),
+	}
+	if config.Queries == nil {
+		config.Queries = make(map[ssa.Value]pointer.Indirect)
+	}
+	for fn := range ssa.AllFunctions(prog) {
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				ptrs := Addresses(instr)
+				if ptrs == nil {
+					continue
+				}
+				for _, ptr := range ptrs {
+					if _, ok := config.Queries[ptr]; !ok && pointer.CanPoint(ptr.Type()) {
+						config.Queries[ptr] = false
+					}
+				}
+				if v, ok := instr.(ssa.Value); ok && isLoad(v) {
+					ix.Loads = append(ix.Loads, v)
+				}
+				if isStore(instr) {
+					ix.Stores = append(ix.Stores, instr)
+				}
+			}
+		}
+	}
+	config.BuildCallGraph = true
+	return ix
+}
+
+// Init records the call graph of result, ignoring contexts.
+func (ix *Index) Init(result *pointer.Result) {
+	seen := make(map[[2]interface{}]bool)
+	call.GraphVisitEdges(result.CallGraph, func(e call.Edge) error {
+		fn := e.Callee.Func()
+		if key := [2]interface{}{e.Site, fn}; e.Site != nil && !seen[key] {
+			seen[key] = true
+			ix.Callees[e.Site] = append(ix.Callees[e.Site], fn)
+			ix.Callers[fn] = append(ix.Callers[fn], e.Site)
+		}
+		return nil
+	})
+}
+
+// Addresses returns the operands of instr that are pointers (or maps
+// or channels) through which it loads or stores.
+//
+func Addresses(instr ssa.Instruction) []ssa.Value {
+	switch instr := instr.(type) {
+	case *ssa.Store:
+		return []ssa.Value{instr.Addr}
+	case *ssa.MapUpdate:
+		return []ssa.Value{instr.Map}
+	case *ssa.Send:
+		return []ssa.Value{instr.Chan}
+	case *ssa.UnOp:
+		if instr.Op == token.MUL || instr.Op == token.ARROW {
+			return []ssa.Value{instr.X}
+		}
+	case *ssa.Lookup:
+		if _, ok := instr.X.Type().Underlying().(*types.Map); ok {
+			return []ssa.Value{instr.X}
+		}
+	case *ssa.Next:
+		if _, ok := instr.Iter.(*ssa.Range).X.Type().Underlying().(*types.Map); ok {
+			return []ssa.Value{instr.Iter.(*ssa.Range).X}
+		}
+	case *ssa.Select:
+		var chans []ssa.Value
+		for _, st := range instr.States {
+			chans = append(chans, st.Chan)
+		}
+		return chans
+	case *ssa.Call:
+		if b, ok := instr.Call.Value.(*ssa.Builtin); ok && b.Object().Name() == "copy" {
+			return []ssa.Value{instr.Call.Args[0]}
+		}
+	}
+	return nil
+}
+
+// isLoad reports whether v, which has Addresses, is loaded through them.
+func isLoad(v ssa.Value) bool {
+	switch v.(type) {
+	case *ssa.UnOp, *ssa.Lookup, *ssa.Next, *ssa.Select:
+		return true
+	}
+	return false
+}
+
+// isStore reports whether instr, which has Addresses, stores through them.
+func isStore(instr ssa.Instruction) bool {
+	switch instr := instr.(type) {
+	case *ssa.Store, *ssa.MapUpdate, *ssa.Send, *ssa.Call:
+		return true
+	case *ssa.Select:
+		for _, st := range instr.States {
+			if st.Send != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
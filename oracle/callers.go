@@ -9,6 +9,7 @@ import (
 	"go/token"
 
 	"code.google.com/p/go.tools/call"
+	"code.google.com/p/go.tools/go/types"
 	"code.google.com/p/go.tools/oracle/serial"
 	"code.google.com/p/go.tools/ssa"
 )
@@ -47,10 +48,28 @@ func callers(o *Oracle, qpos *QueryPos) (queryResult, error) {
 	})
 	// TODO(adonovan): sort + dedup calls to ensure test determinism.
 
+	reachable := edges != nil
+
+	// Apply the query's filter to the calling functions.
+	kept, i, j, err := o.filterResults(len(edges),
+		func(k int) *types.Package { return funcPackage(edges[k].Caller.Func()) },
+		func(k int) token.Pos { return edgeSitePos(edges[k]) })
+	if err != nil {
+		return nil, err
+	}
+	if o.page != nil {
+		var page []call.Edge
+		for _, k := range kept[i:j] {
+			page = append(page, edges[k])
+		}
+		edges = page
+	}
+
 	return &callersResult{
 		target:    target,
 		callgraph: callgraph,
 		edges:     edges,
+		reachable: reachable,
 	}, nil
 }
 
@@ -58,11 +77,12 @@ type callersResult struct {
 	target    *ssa.Function
 	callgraph call.Graph
 	edges     []call.Edge
+	reachable bool // target has callers, though perhaps none satisfy the filter
 }
 
 func (r *callersResult) display(printf printfFunc) {
 	root := r.callgraph.Root()
-	if r.edges == nil {
+	if !r.reachable {
 		printf(r.target, "%s is not reachable in this program.", r.target)
 	} else {
 		printf(r.target, "%s is called from these %d sites:", r.target, len(r.edges))
@@ -92,3 +112,23 @@ func (r *callersResult) toSerial(res *serial.Result, fset *token.FileSet) {
 	}
 	res.Callers = callers
 }
+
+// funcPackage returns the package of function fn, or nil if it has
+// none, as for some synthetic functions.
+//
+func funcPackage(fn *ssa.Function) *types.Package {
+	if fn.Pkg == nil {
+		return nil
+	}
+	return fn.Pkg.Object
+}
+
+// edgeSitePos returns the position of the call site of edge, or
+// token.NoPos for a synthetic call from the root of the call graph.
+//
+func edgeSitePos(edge call.Edge) token.Pos {
+	if edge.Site == nil {
+		return token.NoPos
+	}
+	return edge.Site.Pos()
+}
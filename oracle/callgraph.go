@@ -6,10 +6,13 @@ package oracle
 
 import (
 	"go/token"
+	"sort"
 	"strings"
 
 	"code.google.com/p/go.tools/call"
+	"code.google.com/p/go.tools/go/types"
 	"code.google.com/p/go.tools/oracle/serial"
+	"code.google.com/p/go.tools/ssa"
 )
 
 // callgraph displays the entire callgraph of the current program.
@@ -17,8 +20,12 @@ import (
 // Nodes may be seem to appear multiple times due to (limited)
 // context sensitivity.
 //
-// TODO(adonovan): add options for restricting the display to a region
-// of interest: function, package, subgraph, dirtree, goroutine, etc.
+// If the query has a filter, only the nodes for functions that satisfy
+// it are displayed, as a list of nodes and their callees instead of a
+// spanning tree, and only their call sites are recorded in the graph.
+//
+// TODO(adonovan): add options for restricting the display to other
+// regions of interest: function, subgraph, dirtree, goroutine, etc.
 //
 // TODO(adonovan): add an option to project away context sensitivity.
 // The callgraph API should provide this feature.
@@ -30,20 +37,52 @@ import (
 func callgraph(o *Oracle, _ *QueryPos) (queryResult, error) {
 	buildSSA(o)
 
-	// Run the pointer analysis and build the complete callgraph.
+	// Run the pointer analysis and build the callgraph, recording
+	// only the calls from the functions selected by the filter.
 	o.config.BuildCallGraph = true
+	if f := o.filter; f.restrictsFuncs() {
+		o.config.CallGraphFilter = func(fn *ssa.Function) bool {
+			return f.allows(o.prog.Fset, funcPackage(fn), fn.Pos())
+		}
+	}
 	ptares := ptrAnalysis(o)
 
-	return &callgraphResult{
+	res := &callgraphResult{
 		callgraph: ptares.CallGraph,
-	}, nil
+	}
+
+	// Apply the query's filter to the nodes.
+	nodes := ptares.CallGraph.Nodes()
+	kept, i, j, err := o.filterResults(len(nodes),
+		func(k int) *types.Package { return funcPackage(nodes[k].Func()) },
+		func(k int) token.Pos { return nodes[k].Func().Pos() })
+	if err != nil {
+		return nil, err
+	}
+	if o.page != nil {
+		for _, k := range kept {
+			res.nodes = append(res.nodes, nodes[k])
+		}
+		res.start, res.end = i, j
+	}
+	return res, nil
 }
 
 type callgraphResult struct {
 	callgraph call.Graph
+
+	// If the query has a filter, nodes holds the nodes that
+	// satisfy it, in order, of which nodes[start:end] are reported.
+	nodes      []call.GraphNode
+	start, end int
 }
 
 func (r *callgraphResult) display(printf printfFunc) {
+	if r.nodes != nil {
+		r.displayNodes(printf)
+		return
+	}
+
 	printf(nil, `
 Below is a call graph of the entire program.
 The numbered nodes form a spanning tree.
@@ -72,7 +111,73 @@ Some nodes may appear multiple times due to context-sensitive
 	print(r.callgraph.Root(), 0)
 }
 
+// displayNodes displays the filtered nodes of the call graph, each
+// followed by those of its callees that satisfy the filter.
+//
+func (r *callgraphResult) displayNodes(printf printfFunc) {
+	printf(nil, `
+Below are the nodes of the call graph that satisfy the filter.
+Each is followed by its callees, with their node numbers in parentheses.
+`)
+
+	numbering := make(map[call.GraphNode]int)
+	for i, n := range r.nodes {
+		numbering[n] = i
+	}
+	for i, n := range r.nodes[r.start:r.end] {
+		fn := n.Func()
+		printf(fn, "%d\t%s", r.start+i, fn)
+		for _, callee := range r.filteredCallees(n, numbering) {
+			printf(callee.Func(), "\t    %s (%d)", callee.Func(), numbering[callee])
+		}
+	}
+}
+
+// filteredCallees returns the callees of n that satisfy the filter,
+// i.e. that are numbered, in order of their numbers.
+//
+func (r *callgraphResult) filteredCallees(n call.GraphNode, numbering map[call.GraphNode]int) []call.GraphNode {
+	var callees []call.GraphNode
+	for callee := range call.CalleesOf(n) {
+		if _, ok := numbering[callee]; ok {
+			callees = append(callees, callee)
+		}
+	}
+	sort.Sort(nodesByNumber{callees, numbering})
+	return callees
+}
+
+type nodesByNumber struct {
+	nodes     []call.GraphNode
+	numbering map[call.GraphNode]int
+}
+
+func (s nodesByNumber) Len() int           { return len(s.nodes) }
+func (s nodesByNumber) Less(i, j int) bool { return s.numbering[s.nodes[i]] < s.numbering[s.nodes[j]] }
+func (s nodesByNumber) Swap(i, j int)      { s.nodes[i], s.nodes[j] = s.nodes[j], s.nodes[i] }
+
 func (r *callgraphResult) toSerial(res *serial.Result, fset *token.FileSet) {
+	if r.nodes != nil {
+		// Node indices are relative to the entire sequence of
+		// filtered nodes, of which only a page is reported.
+		numbering := make(map[call.GraphNode]int)
+		for i, n := range r.nodes {
+			numbering[n] = i
+		}
+		cg := make([]serial.CallGraph, r.end-r.start)
+		for i, n := range r.nodes[r.start:r.end] {
+			j := &cg[i]
+			fn := n.Func()
+			j.Name = fn.String()
+			j.Pos = fset.Position(fn.Pos()).String()
+			for _, callee := range r.filteredCallees(n, numbering) {
+				j.Children = append(j.Children, numbering[callee])
+			}
+		}
+		res.Callgraph = cg
+		return
+	}
+
 	nodes := r.callgraph.Nodes()
 
 	numbering := make(map[call.GraphNode]int)
@@ -0,0 +1,387 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oracle
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"sort"
+
+	"code.google.com/p/go.tools/call"
+	"code.google.com/p/go.tools/go/types"
+	"code.google.com/p/go.tools/oracle/serial"
+	"code.google.com/p/go.tools/pointer"
+	"code.google.com/p/go.tools/ssa"
+)
+
+// channels displays the communication structure of the whole program:
+// for each make(chan) allocation site, the send, receive, close and
+// select operations that may apply to the channels it creates, and
+// the goroutines (functions started by a go statement, and the
+// functions they call) that perform them.
+//
+// It is to the whole program what peers is to a single channel op.
+//
+// TODO(adonovan): support reflect.{MakeChan,Select,Recv,Send}.
+//
+func channels(o *Oracle, _ *QueryPos) (queryResult, error) {
+	buildSSA(o)
+
+	// Find all make(chan) instructions, channel operations and
+	// go statements in the whole program.
+	var makes []*ssa.MakeChan
+	var ops []chanSite
+	var gos []*ssa.Go
+	queries := make(map[ssa.Value]pointer.Indirect)
+	for fn := range ssa.AllFunctions(o.prog) {
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				switch instr := instr.(type) {
+				case *ssa.MakeChan:
+					makes = append(makes, instr)
+				case *ssa.Go:
+					gos = append(gos, instr)
+				}
+				for _, op := range chanSites(instr) {
+					ops = append(ops, op)
+					queries[op.ch] = false
+				}
+			}
+		}
+	}
+
+	// Apply the query's filter to the allocation sites.
+	sort.Sort(makeChansByPos(makes))
+	kept, i, j, err := o.filterResults(len(makes),
+		func(k int) *types.Package { return funcPackage(makes[k].Parent()) },
+		func(k int) token.Pos { return makes[k].Pos() })
+	if err != nil {
+		return nil, err
+	}
+	if o.page != nil {
+		var page []*ssa.MakeChan
+		for _, k := range kept[i:j] {
+			page = append(page, makes[k])
+		}
+		makes = page
+	}
+
+	// Run the pointer analysis, computing the points-to sets of
+	// all channel operands and the call graph.
+	o.config.Queries = queries
+	o.config.BuildCallGraph = true
+	ptares := ptrAnalysis(o)
+
+	chans := make(map[*ssa.MakeChan]*chanInfo)
+	for _, mc := range makes {
+		chans[mc] = &chanInfo{makechan: mc}
+	}
+
+	// Attribute each operation to the allocation sites of its channel.
+	// touched records, for each function, the channels it operates upon.
+	touched := make(map[*ssa.Function]map[*chanInfo]bool)
+	for _, op := range ops {
+		for _, ch := range op.allocs(ptares, chans) {
+			ch.ops = append(ch.ops, op)
+			fn := op.instr.Parent()
+			if touched[fn] == nil {
+				touched[fn] = make(map[*chanInfo]bool)
+			}
+			touched[fn][ch] = true
+		}
+	}
+
+	// Attribute each goroutine to the channels upon which it, or any
+	// function it calls within the same goroutine, operates.
+	for _, g := range goroutines(ptares.CallGraph, gos) {
+		for ch := range g.touches(touched) {
+			ch.goroutines = append(ch.goroutines, g)
+		}
+	}
+
+	res := &channelsResult{}
+	for _, mc := range makes {
+		ch := chans[mc]
+		sort.Sort(chanSitesByPos(ch.ops))
+		sort.Sort(goroutinesByPos(ch.goroutines))
+		res.chans = append(res.chans, ch)
+	}
+	return res, nil
+}
+
+// Kinds of channel operation.
+const (
+	chanSend          = "send"
+	chanRecv          = "receive"
+	chanClose         = "close"
+	chanSelectSend    = "select send"
+	chanSelectReceive = "select receive"
+)
+
+// A chanSite is a channel operation within the program.
+type chanSite struct {
+	kind  string // one of the chan* constants
+	ch    ssa.Value
+	pos   token.Pos
+	instr ssa.Instruction
+}
+
+// chanSites returns the channel operations of an instruction,
+// including calls to close.
+//
+func chanSites(instr ssa.Instruction) []chanSite {
+	var sites []chanSite
+	for _, op := range chanOps(instr) {
+		_, isSelect := instr.(*ssa.Select)
+		var kind string
+		switch {
+		case op.dir == ast.SEND && isSelect:
+			kind = chanSelectSend
+		case op.dir == ast.SEND:
+			kind = chanSend
+		case isSelect:
+			kind = chanSelectReceive
+		default:
+			kind = chanRecv
+		}
+		sites = append(sites, chanSite{kind, op.ch, op.pos, instr})
+	}
+	if call, ok := instr.(ssa.CallInstruction); ok {
+		common := call.Common()
+		if b, ok := common.Value.(*ssa.Builtin); ok && b.Name() == "close" {
+			sites = append(sites, chanSite{chanClose, common.Args[0], call.Pos(), instr})
+		}
+	}
+	return sites
+}
+
+// allocs returns the channels, among those of interest, whose
+// allocation sites the operand of the operation may point to.
+//
+func (op *chanSite) allocs(ptares *pointer.Result, chans map[*ssa.MakeChan]*chanInfo) []*chanInfo {
+	seen := make(map[*chanInfo]bool)
+	var res []*chanInfo
+	for _, label := range pointer.PointsToCombined(ptares.Queries[op.ch]).Labels() {
+		if mc, ok := label.Value().(*ssa.MakeChan); ok {
+			if ch := chans[mc]; ch != nil && !seen[ch] {
+				seen[ch] = true
+				res = append(res, ch)
+			}
+		}
+	}
+	return res
+}
+
+// A goroutine is a function started by a go statement, together with
+// the functions it calls, excluding those started by further go
+// statements.
+//
+type goroutine struct {
+	site  *ssa.Go
+	fn    *ssa.Function
+	funcs map[*ssa.Function]bool // functions executed by the goroutine
+}
+
+// goroutines returns the goroutines started by the go statements gos,
+// using the call graph to determine their callees.
+//
+func goroutines(cg call.Graph, gos []*ssa.Go) []*goroutine {
+	isGo := make(map[ssa.CallInstruction]bool)
+	for _, g := range gos {
+		isGo[g] = true
+	}
+
+	// Find the callees of each go statement.
+	type key struct {
+		site *ssa.Go
+		fn   *ssa.Function
+	}
+	entries := make(map[key][]call.GraphNode)
+	var keys []key
+	call.GraphVisitEdges(cg, func(edge call.Edge) error {
+		if isGo[edge.Site] {
+			k := key{edge.Site.(*ssa.Go), edge.Callee.Func()}
+			if entries[k] == nil {
+				keys = append(keys, k)
+			}
+			entries[k] = append(entries[k], edge.Callee)
+		}
+		return nil
+	})
+
+	var res []*goroutine
+	for _, k := range keys {
+		g := &goroutine{site: k.site, fn: k.fn, funcs: make(map[*ssa.Function]bool)}
+		seen := make(map[call.GraphNode]bool)
+		var visit func(n call.GraphNode)
+		visit = func(n call.GraphNode) {
+			if !seen[n] {
+				seen[n] = true
+				g.funcs[n.Func()] = true
+				for _, e := range n.Edges() {
+					if !isGo[e.Site] {
+						visit(e.Callee)
+					}
+				}
+			}
+		}
+		for _, n := range entries[k] {
+			visit(n)
+		}
+		res = append(res, g)
+	}
+	return res
+}
+
+// touches returns the set of channels operated upon by g.
+func (g *goroutine) touches(touched map[*ssa.Function]map[*chanInfo]bool) map[*chanInfo]bool {
+	chans := make(map[*chanInfo]bool)
+	for fn := range g.funcs {
+		for ch := range touched[fn] {
+			chans[ch] = true
+		}
+	}
+	return chans
+}
+
+// A chanInfo describes the channels created by one make(chan)
+// allocation site.
+//
+type chanInfo struct {
+	makechan   *ssa.MakeChan
+	ops        []chanSite   // operations that may apply to its channels
+	goroutines []*goroutine // goroutines that may perform them
+}
+
+type channelsResult struct {
+	chans []*chanInfo // in order of allocation site
+}
+
+func (r *channelsResult) display(printf printfFunc) {
+	if r.chans == nil {
+		printf(nil, "No channels are allocated by this program.")
+		return
+	}
+	for _, ch := range r.chans {
+		mc := ch.makechan
+		printf(mc, "%s allocated in %s", mc.Type(), mc.Parent())
+		for _, op := range ch.ops {
+			printf(op.pos, "\t%s here in %s", op.kind, op.instr.Parent())
+		}
+		for _, g := range ch.goroutines {
+			printf(g.site, "\tused by goroutine %s started here", g.fn)
+		}
+		if ch.ops == nil {
+			printf(mc, "\tnot used by any channel operation")
+		}
+	}
+}
+
+func (r *channelsResult) toSerial(res *serial.Result, fset *token.FileSet) {
+	chans := []serial.Chan{}
+	for _, ch := range r.chans {
+		mc := ch.makechan
+		c := serial.Chan{
+			Pos:  fset.Position(mc.Pos()).String(),
+			Type: mc.Type().String(),
+			Func: mc.Parent().String(),
+		}
+		for _, op := range ch.ops {
+			c.Ops = append(c.Ops, serial.ChanOp{
+				Kind: op.kind,
+				Pos:  fset.Position(op.pos).String(),
+				Func: op.instr.Parent().String(),
+			})
+		}
+		for _, g := range ch.goroutines {
+			c.Goroutines = append(c.Goroutines, serial.ChanGoroutine{
+				Func: g.fn.String(),
+				Pos:  fset.Position(g.site.Pos()).String(),
+			})
+		}
+		chans = append(chans, c)
+	}
+	res.Channels = chans
+}
+
+// dot writes the communication graph to w in the DOT language.
+// Channels (boxes) are connected to the functions that operate upon
+// them (ellipses) by edges labelled with the kind of operation,
+// directed along the flow of data.  Dotted edges connect functions to
+// the goroutines they start.
+//
+func (r *channelsResult) dot(w io.Writer, fset *token.FileSet) {
+	fmt.Fprintln(w, "digraph channels {")
+	funcs := make(map[*ssa.Function]string) // function node names
+	funcNode := func(fn *ssa.Function) string {
+		name, ok := funcs[fn]
+		if !ok {
+			name = fmt.Sprintf("f%d", len(funcs))
+			funcs[fn] = name
+			fmt.Fprintf(w, "\t%s [label=%q];\n", name, fn.String())
+		}
+		return name
+	}
+	edges := make(map[string]bool)
+	edge := func(from, to, attrs string) {
+		e := fmt.Sprintf("\t%s -> %s [%s];\n", from, to, attrs)
+		if !edges[e] {
+			edges[e] = true
+			io.WriteString(w, e)
+		}
+	}
+	for i, ch := range r.chans {
+		mc := ch.makechan
+		name := fmt.Sprintf("c%d", i)
+		fmt.Fprintf(w, "\t%s [shape=box,label=%q];\n", name,
+			fmt.Sprintf("%s\n%s", mc.Type(), fset.Position(mc.Pos())))
+		for _, op := range ch.ops {
+			fn := funcNode(op.instr.Parent())
+			label := fmt.Sprintf("label=%q", op.kind)
+			switch op.kind {
+			case chanRecv, chanSelectReceive:
+				edge(name, fn, label)
+			case chanClose:
+				edge(fn, name, label+",style=dashed")
+			default:
+				edge(fn, name, label)
+			}
+		}
+		for _, g := range ch.goroutines {
+			edge(funcNode(g.site.Parent()), funcNode(g.fn), `label="go",style=dotted`)
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+type makeChansByPos []*ssa.MakeChan
+
+func (s makeChansByPos) Len() int           { return len(s) }
+func (s makeChansByPos) Less(i, j int) bool { return s[i].Pos() < s[j].Pos() }
+func (s makeChansByPos) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+type chanSitesByPos []chanSite
+
+func (s chanSitesByPos) Len() int      { return len(s) }
+func (s chanSitesByPos) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s chanSitesByPos) Less(i, j int) bool {
+	if s[i].pos != s[j].pos {
+		return s[i].pos < s[j].pos
+	}
+	return s[i].kind < s[j].kind
+}
+
+type goroutinesByPos []*goroutine
+
+func (s goroutinesByPos) Len() int      { return len(s) }
+func (s goroutinesByPos) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s goroutinesByPos) Less(i, j int) bool {
+	if s[i].site.Pos() != s[j].site.Pos() {
+		return s[i].site.Pos() < s[j].site.Pos()
+	}
+	return s[i].fn.String() < s[j].fn.String()
+}
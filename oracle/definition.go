@@ -0,0 +1,165 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oracle
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"code.google.com/p/go.tools/go/types"
+	"code.google.com/p/go.tools/oracle/serial"
+)
+
+// definition reports the location of the declaration of the entity
+// denoted by the selected identifier, selector expression or import
+// spec.  It requires type information only for the queried package.
+//
+// For a field or method selected through embedded fields, it also
+// reports the sequence of embedded fields.
+//
+func definition(o *Oracle, qpos *QueryPos) (queryResult, error) {
+	var obj types.Object
+	var embedded []string
+
+	path := qpos.path
+	switch n := path[0].(type) {
+	case *ast.BasicLit:
+		// The path of an import spec?
+		if _, ok := path[1].(*ast.ImportSpec); !ok {
+			return nil, fmt.Errorf("no identifier here")
+		}
+		path = path[1:]
+
+	case *ast.SelectorExpr:
+		// Descend to .Sel child.
+		path = append([]ast.Node{n.Sel}, path...)
+	}
+
+	switch n := path[0].(type) {
+	case *ast.ImportSpec:
+		importPath, _ := strconv.Unquote(n.Path.Value)
+		return packageDefinition(o, n.Path.Pos(), qpos.info.Pkg, importPath)
+
+	case *ast.Ident:
+		if sel, ok := path[1].(*ast.SelectorExpr); ok && sel.Sel == n {
+			if s := qpos.info.Selections[sel]; s != nil {
+				obj = s.Obj()
+				if s.Kind() != types.PackageObj {
+					embedded = embeddedFields(s.Recv(), s.Index())
+				}
+			}
+		}
+		if obj == nil {
+			obj = qpos.info.ObjectOf(n)
+		}
+		switch obj := obj.(type) {
+		case nil:
+			if spec, ok := path[1].(*ast.ImportSpec); ok {
+				// e.g. the name of a renaming import.
+				importPath, _ := strconv.Unquote(spec.Path.Value)
+				return packageDefinition(o, n.Pos(), qpos.info.Pkg, importPath)
+			}
+			return nil, fmt.Errorf("no object for identifier")
+
+		case *types.PkgName:
+			// A reference to an imported package.
+			return packageDefinition(o, n.Pos(), qpos.info.Pkg, obj.Pkg().Path())
+		}
+
+		if obj.Pos() == token.NoPos {
+			return nil, fmt.Errorf("%s is built in", obj.Name())
+		}
+		return &definitionResult{
+			query:    n.Pos(),
+			pos:      obj.Pos(),
+			desc:     obj.String(),
+			embedded: embedded,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no identifier here")
+}
+
+// packageDefinition returns the definition of the package of the
+// specified path, imported by package from.  The position of a
+// package is that of the start of the file containing its first
+// declaration.
+//
+func packageDefinition(o *Oracle, query token.Pos, from *types.Package, path string) (queryResult, error) {
+	var pkg *types.Package
+	if path == from.Path() {
+		pkg = from
+	} else {
+		for _, imp := range from.Imports() {
+			if imp.Path() == path {
+				pkg = imp
+				break
+			}
+		}
+	}
+	if pkg == nil {
+		// e.g. "unsafe"
+		return nil, fmt.Errorf("no source for package %q", path)
+	}
+
+	var first token.Pos
+	for _, name := range pkg.Scope().Names() {
+		if pos := pkg.Scope().Lookup(name).Pos(); pos != token.NoPos && (first == token.NoPos || pos < first) {
+			first = pos
+		}
+	}
+	if first == token.NoPos {
+		return nil, fmt.Errorf("no source for package %q", path)
+	}
+	file := o.prog.Fset.File(first)
+	return &definitionResult{
+		query: query,
+		pos:   token.Pos(file.Base()),
+		desc:  fmt.Sprintf("package %s", pkg.Path()),
+	}, nil
+}
+
+// embeddedFields returns the names of the embedded fields traversed
+// by the selection of the field or method at the specified index path
+// from type T.
+//
+func embeddedFields(T types.Type, index []int) []string {
+	var names []string
+	for _, i := range index[:len(index)-1] {
+		if p, ok := T.Underlying().(*types.Pointer); ok {
+			T = p.Elem()
+		}
+		f := T.Underlying().(*types.Struct).Field(i)
+		names = append(names, f.Name())
+		T = f.Type()
+	}
+	return names
+}
+
+type definitionResult struct {
+	query    token.Pos // location of the query reference
+	pos      token.Pos // location of the definition
+	desc     string    // description of the denoted entity
+	embedded []string  // embedded fields through which it was selected, if any
+}
+
+func (r *definitionResult) display(printf printfFunc) {
+	printf(r.pos, "defined here as %s", r.desc)
+	if r.embedded != nil {
+		printf(r.query, "selected through embedded field %s", strings.Join(r.embedded, "."))
+	}
+}
+
+func (r *definitionResult) toSerial(res *serial.Result, fset *token.FileSet) {
+	res.Definition = &serial.Definition{
+		Pos:      fset.Position(r.query).String(),
+		ObjPos:   fset.Position(r.pos).String(),
+		Desc:     r.desc,
+		Embedded: r.embedded,
+	}
+}
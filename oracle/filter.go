@@ -0,0 +1,217 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oracle
+
+// This file defines Filter, which restricts the results of the query
+// modes whose output is a long list, such as callers, callgraph and
+// referrers.  Results are typically dominated by the standard library;
+// a Filter selects the packages of interest, excludes tests, orders
+// the results, and divides them into pages.
+
+import (
+	"fmt"
+	"go/token"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+
+	"code.google.com/p/go.tools/go/types"
+	"code.google.com/p/go.tools/oracle/serial"
+	"code.google.com/p/go.tools/refactor/util"
+)
+
+// A Filter restricts the results reported by a query.
+// The zero value, like a nil *Filter, reports all results.
+//
+// Filters are honoured by the callers, callgraph, channels and
+// referrers modes, and ignored by the others.  The referrers mode
+// searches only the selected packages, and the callgraph mode records
+// only the calls from the selected functions.  The pointer analysis
+// underlying the callers, callgraph and channels modes must still
+// consider the whole program.
+//
+type Filter struct {
+	Packages []string `json:"packages,omitempty"` // import path patterns, e.g. "foo/..."; empty => all
+	NoTests  bool     `json:"notests,omitempty"`  // exclude results in _test.go files
+	Sort     bool     `json:"sort,omitempty"`     // sort results by position; implied by Limit and Start
+	Limit    int      `json:"limit,omitempty"`    // maximum number of results to report; zero => no limit
+	Start    string   `json:"start,omitempty"`    // continuation token from a previous result; "" => first page
+}
+
+// isZero reports whether f reports all results, unchanged.
+func (f *Filter) isZero() bool {
+	return f == nil || len(f.Packages) == 0 && !f.NoTests && !f.Sort && f.Limit == 0 && f.Start == ""
+}
+
+// sorted reports whether f orders results by position.
+// Pagination requires a deterministic order, so it implies sorting.
+//
+func (f *Filter) sorted() bool {
+	return f.Sort || f.Limit > 0 || f.Start != ""
+}
+
+// restrictsFuncs reports whether f excludes the results in some
+// functions, by package or by file.
+func (f *Filter) restrictsFuncs() bool {
+	return f != nil && (len(f.Packages) > 0 || f.NoTests)
+}
+
+// allowsPackage reports whether f selects the results in package pkg.
+// Results that belong to no package (e.g. synthetic functions) are
+// selected only if f specifies no package patterns.
+//
+func (f *Filter) allowsPackage(pkg *types.Package) bool {
+	if f == nil || len(f.Packages) == 0 {
+		return true
+	}
+	if pkg == nil {
+		return false
+	}
+	for _, pattern := range f.Packages {
+		if util.MatchPackage(pattern, pkg.Path()) {
+			return true
+		}
+	}
+	return false
+}
+
+// allows reports whether f selects a result at position pos in
+// package pkg.
+//
+func (f *Filter) allows(fset *token.FileSet, pkg *types.Package, pos token.Pos) bool {
+	if !f.allowsPackage(pkg) {
+		return false
+	}
+	if f != nil && f.NoTests && strings.HasSuffix(fset.Position(pos).Filename, "_test.go") {
+		return false
+	}
+	return true
+}
+
+// A page records which of the results of a query that satisfy its
+// filter are reported.
+type page struct {
+	filter *Filter
+	start  int    // index of first reported result
+	end    int    // index after last reported result
+	total  int    // number of results that satisfy the filter
+	next   string // continuation token for the following page, if any
+}
+
+// filterResults applies the query's filter to a sequence of n results,
+// where pkgOf(i) and posOf(i) are the package and position of the ith.
+// It returns the indices of the results that satisfy the filter, in
+// the order in which they should be reported, and the bounds [i:j] of
+// the portion of them on the current page.
+//
+// If the query has no filter, all results are reported, unchanged.
+//
+func (o *Oracle) filterResults(n int, pkgOf func(i int) *types.Package, posOf func(i int) token.Pos) (kept []int, i, j int, err error) {
+	f := o.filter
+	kept = make([]int, 0, n)
+	for k := 0; k < n; k++ {
+		if f.allows(o.prog.Fset, pkgOf(k), posOf(k)) {
+			kept = append(kept, k)
+		}
+	}
+	if f.isZero() {
+		return kept, 0, len(kept), nil
+	}
+
+	if f.sorted() {
+		posns := make(map[int]token.Position, len(kept))
+		for _, k := range kept {
+			posns[k] = o.prog.Fset.Position(posOf(k))
+		}
+		sort.Stable(byPosition{kept, posns})
+	}
+
+	// A continuation token holds the index of the first result of
+	// the next page and a checksum of the positions of all the
+	// results, so that it is rejected if the results have changed,
+	// for example because the program was modified and reloaded.
+	var sum string
+	if f.Limit > 0 || f.Start != "" {
+		sum = o.checksum(kept, posOf)
+	}
+	if f.Start != "" {
+		dot := strings.Index(f.Start, ".")
+		if dot < 0 {
+			return nil, 0, 0, fmt.Errorf("invalid continuation token %q", f.Start)
+		}
+		if f.Start[dot+1:] != sum {
+			return nil, 0, 0, fmt.Errorf("continuation token %q does not match the results of this query; has the program changed?", f.Start)
+		}
+		i, err = strconv.Atoi(f.Start[:dot])
+		if err != nil || i < 0 || i > len(kept) {
+			return nil, 0, 0, fmt.Errorf("invalid continuation token %q", f.Start)
+		}
+	}
+	j = len(kept)
+	var next string
+	if f.Limit > 0 && i+f.Limit < j {
+		j = i + f.Limit
+		next = fmt.Sprintf("%d.%s", j, sum)
+	}
+	o.page = &page{filter: f, start: i, end: j, total: len(kept), next: next}
+	return kept, i, j, nil
+}
+
+// checksum returns a checksum of the positions of the results whose
+// indices are kept, in order.
+//
+func (o *Oracle) checksum(kept []int, posOf func(i int) token.Pos) string {
+	h := fnv.New32a()
+	for _, k := range kept {
+		fmt.Fprintln(h, o.prog.Fset.Position(posOf(k)))
+	}
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// byPosition sorts a slice of result indices by the source positions
+// (file name, line and column) of the corresponding results.
+type byPosition struct {
+	indices []int
+	posns   map[int]token.Position
+}
+
+func (s byPosition) Len() int { return len(s.indices) }
+func (s byPosition) Less(i, j int) bool {
+	x, y := s.posns[s.indices[i]], s.posns[s.indices[j]]
+	if x.Filename != y.Filename {
+		return x.Filename < y.Filename
+	}
+	if x.Line != y.Line {
+		return x.Line < y.Line
+	}
+	return x.Column < y.Column
+}
+func (s byPosition) Swap(i, j int) { s.indices[i], s.indices[j] = s.indices[j], s.indices[i] }
+
+func (p *page) display(printf printfFunc) {
+	switch {
+	case p.total == 0:
+		printf(nil, "No results satisfy the filter.")
+	case p.start == p.end:
+		printf(nil, "No more results (%d in all).", p.total)
+	case p.next != "":
+		printf(nil, "Showing results %d-%d of %d; continue with -start=%s.", p.start+1, p.end, p.total, p.next)
+	default:
+		printf(nil, "Showing results %d-%d of %d.", p.start+1, p.end, p.total)
+	}
+}
+
+func (p *page) toSerial() *serial.Filter {
+	f := p.filter
+	return &serial.Filter{
+		Packages: f.Packages,
+		NoTests:  f.NoTests,
+		Sorted:   f.sorted(),
+		Start:    p.start,
+		Total:    p.total,
+		Next:     p.next,
+	}
+}
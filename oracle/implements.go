@@ -5,22 +5,31 @@
 package oracle
 
 import (
+	"fmt"
+	"go/ast"
 	"go/token"
+	"sort"
 
 	"code.google.com/p/go.tools/go/types"
+	"code.google.com/p/go.tools/go/types/typemap"
 	"code.google.com/p/go.tools/oracle/serial"
 )
 
-// Implements displays the 'implements" relation among all
-// package-level named types in the package containing the query
-// position.
+// Implements displays the 'implements" relation for the type or method
+// denoted by the selected identifier, relative to all package-level
+// named types in the program:
+//
+// - for a concrete type, the interfaces it implements;
+// - for an interface type, the concrete types that implement it, and
+//   the other interfaces it implements;
+// - for a concrete method, the interface methods it implements;
+// - for an interface method, the concrete methods that implement it.
+//
+// If the selection does not denote a type or method, it displays the
+// relation among all package-level named types in the package
+// containing the query position.
 //
 // TODO(adonovan): more features:
-// - should we include pairs of types belonging to
-//   different packages in the 'implements' relation?
-// - should we restrict the query to the type declaration identified
-//   by the query position, if any, and use all types in the package
-//   otherwise?
 // - should we show types that are local to functions?
 //   They can only have methods via promotion.
 // - abbreviate the set of concrete types implementing the empty
@@ -31,6 +40,96 @@ import (
 //   answer due to ChangeInterface, i.e. subtyping among interfaces.)
 //
 func implements(o *Oracle, qpos *QueryPos) (queryResult, error) {
+	switch obj := selectedTypeOrMethod(qpos).(type) {
+	case *types.TypeName:
+		return implementsType(o, obj), nil
+	case *types.Func:
+		return implementsMethod(o, obj), nil
+	}
+	return implementsPackage(o, qpos), nil
+}
+
+// selectedTypeOrMethod returns the named type or method denoted by
+// the selected identifier, if any.
+//
+func selectedTypeOrMethod(qpos *QueryPos) types.Object {
+	var id *ast.Ident
+	switch n := qpos.path[0].(type) {
+	case *ast.Ident:
+		id = n
+	case *ast.SelectorExpr:
+		id = n.Sel
+	case *ast.TypeSpec:
+		id = n.Name
+	default:
+		return nil
+	}
+	switch obj := qpos.info.ObjectOf(id).(type) {
+	case *types.TypeName:
+		if _, ok := obj.Type().(*types.Named); ok {
+			return obj
+		}
+	case *types.Func:
+		if obj.Type().(*types.Signature).Recv() != nil {
+			return obj
+		}
+	}
+	return nil
+}
+
+// allNamedTypes returns the package-level named types of all packages
+// in the program, partitioned into interfaces and concrete types,
+// sorted by name.
+//
+func allNamedTypes(o *Oracle) (interfaces, concretes []*types.Named) {
+	for _, info := range o.typeInfo {
+		scope := info.Pkg.Scope()
+		for _, name := range scope.Names() {
+			if t, ok := scope.Lookup(name).(*types.TypeName); ok {
+				nt := t.Type().(*types.Named)
+				if isInterface(nt) {
+					interfaces = append(interfaces, nt)
+				} else {
+					concretes = append(concretes, nt)
+				}
+			}
+		}
+	}
+	sort.Sort(namedByString(interfaces))
+	sort.Sort(namedByString(concretes))
+	return
+}
+
+// methodSets memoizes the method sets of types.
+type methodSets struct {
+	m typemap.M
+}
+
+func (c *methodSets) of(T types.Type) *types.MethodSet {
+	mset, _ := c.m.At(T).(*types.MethodSet)
+	if mset == nil {
+		mset = types.NewMethodSet(T)
+		c.m.Set(T, mset)
+	}
+	return mset
+}
+
+// implementor returns T or *T, whichever is the first to implement
+// iface, or nil if neither does.
+//
+func implementor(T *types.Named, iface *types.Interface) types.Type {
+	if types.Implements(T, iface, true) {
+		return T
+	}
+	if ptr := types.NewPointer(T); types.Implements(ptr, iface, true) {
+		return ptr
+	}
+	return nil
+}
+
+// ---------- Package ----------
+
+func implementsPackage(o *Oracle, qpos *QueryPos) queryResult {
 	pkg := qpos.info.Pkg
 
 	// Compute set of named interface/concrete types at package level.
@@ -40,7 +139,7 @@ func implements(o *Oracle, qpos *QueryPos) (queryResult, error) {
 		mem := scope.Lookup(name)
 		if t, ok := mem.(*types.TypeName); ok {
 			nt := t.Type().(*types.Named)
-			if _, ok := nt.Underlying().(*types.Interface); ok {
+			if isInterface(nt) {
 				interfaces = append(interfaces, nt)
 			} else {
 				concretes = append(concretes, nt)
@@ -65,7 +164,7 @@ func implements(o *Oracle, qpos *QueryPos) (queryResult, error) {
 	}
 	// TODO(adonovan): sort facts to ensure test nondeterminism.
 
-	return &implementsResult{o.prog.Fset, facts}, nil
+	return &implementsResult{o.prog.Fset, facts}
 }
 
 type implementsFact struct {
@@ -101,3 +200,184 @@ func (r *implementsResult) toSerial(res *serial.Result, fset *token.FileSet) {
 	}
 	res.Implements = facts
 }
+
+// ---------- Type ----------
+
+// implementsType computes the 'implements' relation for the named type
+// of tname.
+//
+func implementsType(o *Oracle, tname *types.TypeName) queryResult {
+	T := tname.Type().(*types.Named)
+	interfaces, concretes := allNamedTypes(o)
+
+	res := &implementsTypeResult{t: T}
+	if iface, ok := T.Underlying().(*types.Interface); ok {
+		for _, conc := range concretes {
+			if C := implementor(conc, iface); C != nil {
+				res.from = append(res.from, C)
+			}
+		}
+	}
+	for _, I := range interfaces {
+		iface := I.Underlying().(*types.Interface)
+		if I == T || iface.NumMethods() == 0 {
+			continue // trivial
+		}
+		if C := implementor(T, iface); C != nil {
+			res.to = append(res.to, implementsRel{C, I})
+		}
+	}
+	return res
+}
+
+// An implementsRel records that type T (or *T) implements interface I.
+type implementsRel struct {
+	T types.Type // Named or Pointer(Named)
+	I *types.Named
+}
+
+type implementsTypeResult struct {
+	t    *types.Named
+	to   []implementsRel // non-empty interfaces that t (or *t) implements
+	from []types.Type    // if t is an interface, concrete types C or *C that implement it
+}
+
+func (r *implementsTypeResult) display(printf printfFunc) {
+	kind := "concrete"
+	if isInterface(r.t) {
+		kind = "interface"
+	}
+	printf(r.t.Obj(), "%s type %s", kind, r.t)
+	for _, C := range r.from {
+		printf(deref(C).(*types.Named).Obj(), "\tis implemented by %s", C)
+	}
+	for _, rel := range r.to {
+		printf(rel.I.Obj(), "\t%s implements %s", rel.T, rel.I)
+	}
+	if r.from == nil && r.to == nil {
+		printf(r.t.Obj(), "\timplements no non-empty interfaces, and is implemented by no types")
+	}
+}
+
+func (r *implementsTypeResult) toSerial(res *serial.Result, fset *token.FileSet) {
+	of := &serial.ImplementsOf{
+		T: serial.ImplementsType{
+			Name: r.t.String(),
+			Pos:  fset.Position(r.t.Obj().Pos()).String(),
+		},
+	}
+	for _, C := range r.from {
+		of.From = append(of.From, serial.ImplementsType{
+			Name: C.String(),
+			Pos:  fset.Position(deref(C).(*types.Named).Obj().Pos()).String(),
+		})
+	}
+	for _, rel := range r.to {
+		of.To = append(of.To, serial.ImplementsType{
+			Name: rel.I.String(),
+			Pos:  fset.Position(rel.I.Obj().Pos()).String(),
+			Via:  rel.T.String(),
+		})
+	}
+	res.ImplementsOf = of
+}
+
+// ---------- Method ----------
+
+// implementsMethod computes the 'implements' relation for a method:
+// the interface methods that a concrete method implements, or the
+// concrete methods that implement an interface method.
+//
+func implementsMethod(o *Oracle, method *types.Func) queryResult {
+	recv := method.Type().(*types.Signature).Recv().Type()
+	interfaces, concretes := allNamedTypes(o)
+	var msets methodSets
+
+	res := &implementsMethodResult{method: method}
+	if I, ok := deref(recv).(*types.Named); ok && isInterface(I) {
+		// Abstract method: find the concrete methods of each
+		// type that implements its interface.
+		iface := I.Underlying().(*types.Interface)
+		for _, conc := range concretes {
+			if C := implementor(conc, iface); C != nil {
+				sel := msets.of(C).Lookup(method.Pkg(), method.Name())
+				res.from = append(res.from, sel.Obj().(*types.Func))
+			}
+		}
+	} else if T, ok := deref(recv).(*types.Named); ok {
+		// Concrete method: find the corresponding method of
+		// each interface that its type implements.
+		for _, I := range interfaces {
+			iface := I.Underlying().(*types.Interface)
+			if msets.of(I).Lookup(method.Pkg(), method.Name()) == nil {
+				continue // interface has no method of that name
+			}
+			if C := implementor(T, iface); C != nil {
+				if sel := msets.of(C).Lookup(method.Pkg(), method.Name()); sel != nil && sel.Obj() == method {
+					res.to = append(res.to, implementsRel{C, I})
+				}
+			}
+		}
+	}
+	return res
+}
+
+type implementsMethodResult struct {
+	method *types.Func
+	to     []implementsRel // interfaces whose method the concrete method implements
+	from   []*types.Func   // concrete methods that implement the abstract method
+}
+
+func (r *implementsMethodResult) display(printf printfFunc) {
+	recv := r.method.Type().(*types.Signature).Recv().Type()
+	kind := "concrete"
+	if isInterface(deref(recv)) {
+		kind = "abstract"
+	}
+	printf(r.method, "%s method %s", kind, r.method)
+	for _, m := range r.from {
+		printf(m, "\tis implemented by %s", m.FullName())
+	}
+	for _, rel := range r.to {
+		printf(rel.I.Obj(), "\timplements (%s).%s", rel.I, r.method.Name())
+	}
+	if r.from == nil && r.to == nil {
+		printf(r.method, "\timplements no interface methods, and is implemented by no methods")
+	}
+}
+
+func (r *implementsMethodResult) toSerial(res *serial.Result, fset *token.FileSet) {
+	of := &serial.ImplementsOf{
+		T: serial.ImplementsType{
+			Name: r.method.FullName(),
+			Pos:  fset.Position(r.method.Pos()).String(),
+		},
+	}
+	for _, m := range r.from {
+		of.From = append(of.From, serial.ImplementsType{
+			Name: m.FullName(),
+			Pos:  fset.Position(m.Pos()).String(),
+		})
+	}
+	for _, rel := range r.to {
+		of.To = append(of.To, serial.ImplementsType{
+			Name: fmt.Sprintf("(%s).%s", rel.I, r.method.Name()),
+			Pos:  fset.Position(rel.I.Obj().Pos()).String(),
+			Via:  rel.T.String(),
+		})
+	}
+	res.ImplementsOf = of
+}
+
+// -------- utils --------
+
+func isInterface(T types.Type) bool {
+	_, ok := T.Underlying().(*types.Interface)
+	return ok
+}
+
+type namedByString []*types.Named
+
+func (a namedByString) Len() int           { return len(a) }
+func (a namedByString) Less(i, j int) bool { return a[i].String() < a[j].String() }
+func (a namedByString) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
@@ -0,0 +1,170 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+// This file maps call hierarchy requests onto the oracle's callgraph
+// and callers queries.  A call hierarchy item denotes a function by
+// its full name, which is that of its nodes in the call graph.
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"code.google.com/p/go.tools/oracle"
+	"code.google.com/p/go.tools/oracle/serial"
+)
+
+// callgraph returns the nodes of the program's call graph, computed
+// by the oracle.  (The oracle caches the call graph.)
+//
+func (s *Server) callgraph() ([]serial.CallGraph, error) {
+	var res serial.Result
+	if err := s.oracle.Query(&oracle.QueryArgs{Mode: "callgraph"}, &res); err != nil {
+		return nil, err
+	}
+	return res.Callgraph, nil
+}
+
+// item returns the call hierarchy item for a call graph node, if it
+// has a position.
+//
+func (l *locations) item(node *serial.CallGraph) (CallHierarchyItem, bool) {
+	loc, ok := l.location(node.Pos)
+	if !ok {
+		return CallHierarchyItem{}, false // synthetic function
+	}
+	kind := SymbolKindFunction
+	if strings.HasPrefix(node.Name, "(") {
+		kind = SymbolKindMethod
+	}
+	return CallHierarchyItem{
+		Name:           node.Name,
+		Kind:           kind,
+		URI:            loc.URI,
+		Range:          loc.Range,
+		SelectionRange: loc.Range,
+	}, true
+}
+
+// prepareCallHierarchy returns the item for the function whose
+// declaration is denoted by the selected identifier.
+//
+func (s *Server) prepareCallHierarchy(params *json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	res, err := s.query("definition", &p)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := s.callgraph()
+	if err != nil {
+		return nil, err
+	}
+	locs := newLocations()
+	for i := range nodes {
+		if nodes[i].Pos == res.Definition.ObjPos {
+			if item, ok := locs.item(&nodes[i]); ok {
+				return []CallHierarchyItem{item}, nil
+			}
+		}
+	}
+	return nil, nil // not a function, or not reachable
+}
+
+// incomingCalls returns the functions that call the item's function,
+// with the locations of their call sites.
+//
+func (s *Server) incomingCalls(params *json.RawMessage) (interface{}, error) {
+	var p CallHierarchyCallsParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	res, err := s.query("callers", &TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: p.Item.URI},
+		Position:     p.Item.SelectionRange.Start,
+	})
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := s.callgraph()
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*serial.CallGraph)
+	for i := range nodes {
+		byName[nodes[i].Name] = &nodes[i]
+	}
+
+	// Group the call sites by calling function.
+	locs := newLocations()
+	calls := make(map[string]*CallHierarchyIncomingCall)
+	var names []string
+	for _, caller := range res.Callers {
+		site, ok := locs.location(caller.Pos)
+		node := byName[caller.Caller]
+		if !ok || node == nil {
+			continue // synthetic call
+		}
+		call := calls[caller.Caller]
+		if call == nil {
+			from, ok := locs.item(node)
+			if !ok {
+				continue
+			}
+			call = &CallHierarchyIncomingCall{From: from, FromRanges: []Range{}}
+			calls[caller.Caller] = call
+			names = append(names, caller.Caller)
+		}
+		call.FromRanges = append(call.FromRanges, site.Range)
+	}
+	sort.Strings(names)
+	result := []CallHierarchyIncomingCall{}
+	for _, name := range names {
+		result = append(result, *calls[name])
+	}
+	return result, nil
+}
+
+// outgoingCalls returns the functions called by the item's function.
+// The call graph does not record call sites, so the ranges of the
+// calls are not reported.
+//
+func (s *Server) outgoingCalls(params *json.RawMessage) (interface{}, error) {
+	var p CallHierarchyCallsParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	nodes, err := s.callgraph()
+	if err != nil {
+		return nil, err
+	}
+
+	// The function may have several nodes, due to context sensitivity.
+	callees := make(map[string]int)
+	for _, node := range nodes {
+		if node.Name == p.Item.Name {
+			for _, child := range node.Children {
+				callees[nodes[child].Name] = child
+			}
+		}
+	}
+	var names []string
+	for name := range callees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	locs := newLocations()
+	result := []CallHierarchyOutgoingCall{}
+	for _, name := range names {
+		if to, ok := locs.item(&nodes[callees[name]]); ok {
+			result = append(result, CallHierarchyOutgoingCall{To: to, FromRanges: []Range{}})
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+// This file defines the framing of JSON-RPC messages on the wire:
+// each message is preceded by a header, terminated by a blank line,
+// that gives the length of its content in bytes:
+//
+//	Content-Length: 44\r\n
+//	\r\n
+//	{"jsonrpc":"2.0","id":1,"method":"shutdown"}
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A conn reads and writes framed JSON-RPC messages.
+type conn struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// read reads the content of the next message.
+// It returns io.EOF at the end of the input.
+//
+func (c *conn) read() ([]byte, error) {
+	length := -1
+	for {
+		line, err := c.in.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line == "" && length < 0 {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("reading header: %s", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break // end of header
+		}
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			return nil, fmt.Errorf("invalid header line %q", line)
+		}
+		name, value := line[:colon], strings.TrimSpace(line[colon+1:])
+		if strings.EqualFold(name, "Content-Length") {
+			if length, err = strconv.Atoi(value); err != nil || length < 0 {
+				return nil, fmt.Errorf("invalid Content-Length %q", value)
+			}
+		}
+		// Other headers (e.g. Content-Type) are ignored.
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(c.in, content); err != nil {
+		return nil, fmt.Errorf("reading content: %s", err)
+	}
+	return content, nil
+}
+
+// write writes msg, encoded as JSON.
+func (c *conn) write(msg interface{}) error {
+	content, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.out, "Content-Length: %d\r\n\r\n", len(content)); err != nil {
+		return err
+	}
+	_, err = c.out.Write(content)
+	return err
+}
@@ -0,0 +1,159 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+// This file computes the diagnostics of open documents by parsing
+// and type-checking the package containing each one, using the
+// unsaved contents of all open documents of that package.
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"code.google.com/p/go.tools/importer"
+)
+
+// publishDiagnostics sends the diagnostics of the named file.
+func (s *Server) publishDiagnostics(file string) error {
+	return s.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+		URI:         pathToURI(file),
+		Diagnostics: s.diagnostics(file),
+	})
+}
+
+// diagnostics returns the parse and type errors of the named file,
+// which are found by checking the package to which it belongs.
+//
+func (s *Server) diagnostics(file string) []Diagnostic {
+	content := s.content(file)
+	diags := []Diagnostic{}
+	report := func(line, col int, msg string) {
+		start := lineColOffset(content, line, col)
+		diags = append(diags, Diagnostic{
+			Range:    Range{positionOf(content, start), positionOf(content, identEnd(content, start))},
+			Severity: SeverityError,
+			Source:   "go/types",
+			Message:  msg,
+		})
+	}
+
+	// Parse the files of the package.
+	fset := token.NewFileSet()
+	var files []*ast.File
+	var parseErrors bool
+	for _, name := range s.packageFiles(file) {
+		f, err := parser.ParseFile(fset, name, s.content(name), parser.AllErrors)
+		if err != nil {
+			parseErrors = true
+			if list, ok := err.(scanner.ErrorList); ok {
+				for _, e := range list {
+					if e.Pos.Filename == file {
+						report(e.Pos.Line, e.Pos.Column, e.Msg)
+					}
+				}
+			}
+		}
+		if f != nil {
+			files = append(files, f)
+		}
+	}
+	if parseErrors || len(files) == 0 {
+		return diags // type-checking an incomplete AST is unhelpful
+	}
+
+	// Type-check the package, importing dependencies from source.
+	if s.imp == nil {
+		s.impConfig = &importer.Config{Build: s.buildContext}
+		s.imp = importer.New(s.impConfig)
+	}
+	tc := s.impConfig.TypeChecker // (importer.New has set its Import function)
+	tc.Error = func(err error) {
+		if name, line, col, msg, ok := splitError(err.Error()); ok && name == file {
+			report(line, col, msg)
+		}
+	}
+	tc.FakeImportC = true
+	tc.Check(files[0].Name.Name, fset, files, nil)
+	return diags
+}
+
+// content returns the contents of the named file, or of the open
+// document of that name, if any.
+//
+func (s *Server) content(file string) []byte {
+	if content, ok := s.docs[file]; ok {
+		return content
+	}
+	content, _ := ioutil.ReadFile(file) // (errors are reported by the parser)
+	return content
+}
+
+// packageFiles returns the names of the files of the package to which
+// the named file belongs: the files of its directory that satisfy the
+// build constraints, including test files only if it is one.
+// If the file does not itself satisfy the constraints, it is checked
+// alone.
+//
+func (s *Server) packageFiles(file string) []string {
+	dir := filepath.Dir(file)
+	bp, err := s.buildContext.ImportDir(dir, 0)
+	if err != nil {
+		return []string{file}
+	}
+	base := filepath.Base(file)
+	var names []string
+	switch {
+	case contains(bp.GoFiles, base), contains(bp.CgoFiles, base):
+		names = concat(bp.GoFiles, bp.CgoFiles)
+	case contains(bp.TestGoFiles, base):
+		names = concat(bp.GoFiles, bp.CgoFiles, bp.TestGoFiles)
+	case contains(bp.XTestGoFiles, base):
+		names = bp.XTestGoFiles
+	default:
+		return []string{file}
+	}
+	var files []string
+	for _, name := range names {
+		files = append(files, filepath.Join(dir, name))
+	}
+	return files
+}
+
+// errorRx matches a type error of the form "file:line:col: message".
+var errorRx = regexp.MustCompile(`(?s)^(.*?):(\d+):(\d+): (.*)$`)
+
+// splitError splits a type error into its position and message.
+func splitError(err string) (file string, line, col int, msg string, ok bool) {
+	m := errorRx.FindStringSubmatch(err)
+	if m == nil {
+		return
+	}
+	line, _ = strconv.Atoi(m[2])
+	col, _ = strconv.Atoi(m[3])
+	return m[1], line, col, m[4], true
+}
+
+func contains(list []string, x string) bool {
+	for _, y := range list {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
+
+func concat(lists ...[]string) []string {
+	var res []string
+	for _, list := range lists {
+		res = append(res, list...)
+	}
+	return res
+}
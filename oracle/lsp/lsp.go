@@ -0,0 +1,432 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package lsp adapts the oracle to the language server protocol, so
+// that it may be used from any editor that speaks the protocol, not
+// just those (Emacs, Vim) for which the oracle provides integration.
+//
+// The adapter maps protocol requests onto oracle queries:
+//
+//	textDocument/definition         definition
+//	textDocument/references         referrers
+//	textDocument/hover              describe
+//	textDocument/implementation     implements
+//	textDocument/prepareCallHierarchy,
+//	callHierarchy/incomingCalls,
+//	callHierarchy/outgoingCalls     callgraph, callers
+//
+// and publishes the parse and type errors of each open document,
+// found by go/types, as diagnostics.
+//
+// Queries are answered about the saved contents of files; the
+// analyzed program is reloaded after a file is saved.  A query about a
+// document with unsaved changes is refused, since its position may
+// denote something else in the saved file.  Diagnostics reflect the
+// unsaved contents of open documents.
+//
+// The protocol is described at
+// https://microsoft.github.io/language-server-protocol/.
+//
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+
+	"code.google.com/p/go.tools/importer"
+	"code.google.com/p/go.tools/oracle"
+	"code.google.com/p/go.tools/oracle/serial"
+)
+
+// A Server answers language server protocol requests about a fixed
+// analysis scope using an oracle.Server.
+type Server struct {
+	oracle       *oracle.Server
+	buildContext *build.Context
+	conn         *conn
+	docs         map[string][]byte  // contents of open documents, keyed by file name
+	impConfig    *importer.Config   // importer configuration for diagnostics
+	imp          *importer.Importer // importer for diagnostics; nil => not yet created
+}
+
+// NewServer returns a server for requests about the program specified
+// by args, in importer.CreatePackageFromArgs syntax.
+//
+// buildContext is the go/build configuration for locating packages.
+// reflection determines whether to model reflection soundly (currently slow).
+//
+func NewServer(args []string, buildContext *build.Context, reflection bool) *Server {
+	return &Server{
+		oracle:       oracle.NewServer(args, nil, buildContext, reflection),
+		buildContext: buildContext,
+		docs:         make(map[string][]byte),
+	}
+}
+
+// Serve reads a sequence of requests and notifications from in and
+// writes the responses and notifications to out, until it receives
+// an exit notification or its input is exhausted.
+//
+// Requests are answered one at a time, in order.
+//
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.conn = &conn{in: bufio.NewReader(in), out: out}
+	for {
+		content, err := s.conn.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(content, &req); err != nil {
+			if err := s.conn.write(&errorResponse{
+				JSONRPC: "2.0",
+				Error:   &rpcError{codeParseError, err.Error()},
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "" {
+			continue // a response from the client; we send no requests
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+
+		result, err := s.handle(&req)
+		if req.ID == nil {
+			// A notification has no response, but the client
+			// may log its failure.
+			if err != nil {
+				err = s.notify("window/logMessage", &logMessageParams{Type: 1, Message: err.Error()})
+			}
+		} else if err != nil {
+			rerr, ok := err.(*rpcError)
+			if !ok {
+				rerr = &rpcError{codeRequestFailed, err.Error()}
+			}
+			err = s.conn.write(&errorResponse{JSONRPC: "2.0", ID: req.ID, Error: rerr})
+		} else {
+			err = s.conn.write(&response{JSONRPC: "2.0", ID: req.ID, Result: result})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+type logMessageParams struct {
+	Type    int    `json:"type"` // 1 => error
+	Message string `json:"message"`
+}
+
+// notify sends a notification to the client.
+func (s *Server) notify(method string, params interface{}) error {
+	return s.conn.write(&notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+type handlerFunc func(s *Server, params *json.RawMessage) (interface{}, error)
+
+var handlers = map[string]handlerFunc{
+	"initialize":                        (*Server).initialize,
+	"initialized":                       nop,
+	"shutdown":                          nop,
+	"textDocument/didOpen":              (*Server).didOpen,
+	"textDocument/didChange":            (*Server).didChange,
+	"textDocument/didSave":              (*Server).didSave,
+	"textDocument/didClose":             (*Server).didClose,
+	"textDocument/definition":           (*Server).definition,
+	"textDocument/references":           (*Server).references,
+	"textDocument/hover":                (*Server).hover,
+	"textDocument/implementation":       (*Server).implementation,
+	"textDocument/prepareCallHierarchy": (*Server).prepareCallHierarchy,
+	"callHierarchy/incomingCalls":       (*Server).incomingCalls,
+	"callHierarchy/outgoingCalls":       (*Server).outgoingCalls,
+}
+
+func nop(*Server, *json.RawMessage) (interface{}, error) { return nil, nil }
+
+// handle handles a request or notification and returns its result.
+func (s *Server) handle(req *request) (interface{}, error) {
+	h := handlers[req.Method]
+	if h == nil {
+		if req.ID == nil {
+			return nil, nil // unknown notifications are ignored
+		}
+		return nil, &rpcError{codeMethodNotFound, fmt.Sprintf("method not found: %s", req.Method)}
+	}
+	return h(s, req.Params)
+}
+
+// decode decodes the parameters of a request into v.
+func decode(params *json.RawMessage, v interface{}) error {
+	if params == nil {
+		return &rpcError{codeInvalidParams, "missing params"}
+	}
+	if err := json.Unmarshal(*params, v); err != nil {
+		return &rpcError{codeInvalidParams, err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) initialize(*json.RawMessage) (interface{}, error) {
+	var res InitializeResult
+	res.Capabilities = ServerCapabilities{
+		PositionEncoding:       "utf-16",
+		TextDocumentSync:       syncFull,
+		DefinitionProvider:     true,
+		ReferencesProvider:     true,
+		HoverProvider:          true,
+		ImplementationProvider: true,
+		CallHierarchyProvider:  true,
+	}
+	res.ServerInfo.Name = "oracle"
+	return &res, nil
+}
+
+// ---------- Document synchronization ----------
+
+func (s *Server) didOpen(params *json.RawMessage) (interface{}, error) {
+	var p DidOpenTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	file, err := uriToPath(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	s.docs[file] = []byte(p.TextDocument.Text)
+	return nil, s.publishDiagnostics(file)
+}
+
+func (s *Server) didChange(params *json.RawMessage) (interface{}, error) {
+	var p DidChangeTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	file, err := uriToPath(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	if n := len(p.ContentChanges); n > 0 {
+		// With full synchronization, the last change holds
+		// the entire content.
+		s.docs[file] = []byte(p.ContentChanges[n-1].Text)
+	}
+	return nil, s.publishDiagnostics(file)
+}
+
+func (s *Server) didSave(params *json.RawMessage) (interface{}, error) {
+	var p DidSaveTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	file, err := uriToPath(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	var reply oracle.ChangedReply
+	if err := s.oracle.Changed(&oracle.ChangedArgs{Files: []string{file}}, &reply); err != nil {
+		return nil, err
+	}
+	s.imp = nil // the file may be a dependency of other documents
+	return nil, s.publishDiagnostics(file)
+}
+
+func (s *Server) didClose(params *json.RawMessage) (interface{}, error) {
+	var p DidCloseTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	file, err := uriToPath(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	delete(s.docs, file)
+	// Clear the document's diagnostics.
+	return nil, s.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+		URI:         p.TextDocument.URI,
+		Diagnostics: []Diagnostic{},
+	})
+}
+
+// ---------- Queries ----------
+
+// query runs an oracle query of the specified mode at the position
+// given by params, and returns its result.  It fails if the document
+// has been changed but not saved.
+//
+func (s *Server) query(mode string, params *TextDocumentPositionParams) (*serial.Result, error) {
+	file, err := uriToPath(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if doc, ok := s.docs[file]; ok && !bytes.Equal(doc, content) {
+		// The oracle analyzes the files on disk, in which the
+		// position may denote something else.
+		return nil, fmt.Errorf("%s has unsaved changes; save it before querying", filepath.Base(file))
+	}
+	offset, err := offsetOf(content, params.Position)
+	if err != nil {
+		return nil, err
+	}
+	var res serial.Result
+	args := &oracle.QueryArgs{Mode: mode, Pos: fmt.Sprintf("%s:#%d", file, offset)}
+	if err := s.oracle.Query(args, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *Server) definition(params *json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	res, err := s.query("definition", &p)
+	if err != nil {
+		return nil, err
+	}
+	return newLocations().add(res.Definition.ObjPos), nil
+}
+
+func (s *Server) references(params *json.RawMessage) (interface{}, error) {
+	var p ReferenceParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	res, err := s.query("referrers", &p.TextDocumentPositionParams)
+	if err != nil {
+		return nil, err
+	}
+	locs := newLocations()
+	if p.Context.IncludeDeclaration {
+		locs.add(res.Referrers.ObjPos)
+	}
+	for _, ref := range res.Referrers.Refs {
+		locs.add(ref)
+	}
+	return locs, nil
+}
+
+func (s *Server) hover(params *json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	res, err := s.query("describe", &p)
+	if err != nil {
+		return nil, err
+	}
+	return &Hover{Contents: MarkupContent{Kind: "plaintext", Value: hoverText(res.Describe)}}, nil
+}
+
+// hoverText returns the text of a hover describing d.
+func hoverText(d *serial.Describe) string {
+	text := d.Desc
+	switch {
+	case d.Package != nil:
+		text += fmt.Sprintf("\npackage %q", d.Package.Path)
+	case d.Type != nil:
+		text += "\ntype " + d.Type.Type
+		if d.Type.NameDef != "" {
+			text += " " + d.Type.NameDef
+		}
+		for _, m := range d.Type.Methods {
+			text += "\n\t" + m.Name
+		}
+	case d.Value != nil:
+		text += "\ntype " + d.Value.Type
+		if d.Value.Value != "" {
+			text += " = " + d.Value.Value
+		}
+	}
+	return text
+}
+
+// implementation returns the locations of the types (or methods)
+// that implement the selected interface (or interface method), or
+// else those of the interfaces implemented by the selected concrete
+// type (or method).
+//
+func (s *Server) implementation(params *json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	res, err := s.query("implements", &p)
+	if err != nil {
+		return nil, err
+	}
+	locs := newLocations()
+	if of := res.ImplementsOf; of != nil {
+		impls := of.From
+		if impls == nil {
+			impls = of.To
+		}
+		for _, t := range impls {
+			locs.add(t.Pos)
+		}
+	}
+	return locs, nil
+}
+
+// A locations accumulates the protocol locations of a set of oracle
+// positions, caching the contents of the files to which they refer.
+//
+type locations struct {
+	list     []Location
+	contents map[string][]byte
+}
+
+func newLocations() *locations {
+	return &locations{list: []Location{}, contents: make(map[string][]byte)}
+}
+
+// add appends the location of the identifier at oracle position pos,
+// if valid.  Positions without a location (e.g. "-") are ignored.
+//
+func (l *locations) add(pos string) *locations {
+	if loc, ok := l.location(pos); ok {
+		l.list = append(l.list, loc)
+	}
+	return l
+}
+
+// location returns the location of the identifier at oracle
+// position pos.
+//
+func (l *locations) location(pos string) (Location, bool) {
+	file, line, col, err := parseOraclePos(pos)
+	if err != nil {
+		return Location{}, false
+	}
+	content, ok := l.contents[file]
+	if !ok {
+		content, _ = ioutil.ReadFile(file) // (on error, positions are approximate)
+		l.contents[file] = content
+	}
+	start := lineColOffset(content, line, col)
+	end := identEnd(content, start)
+	return Location{
+		URI:   pathToURI(file),
+		Range: Range{positionOf(content, start), positionOf(content, end)},
+	}, true
+}
+
+func (l *locations) MarshalJSON() ([]byte, error) { return json.Marshal(l.list) }
@@ -0,0 +1,175 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"go/build"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPositions(t *testing.T) {
+	content := []byte("a\n\ts := \"☺𝄞\" + x\nb")
+	offset := bytes.IndexByte(content, 'x')
+	want := Position{Line: 1, Character: 14} // 𝄞 is two UTF-16 code units
+	if got := positionOf(content, offset); got != want {
+		t.Errorf("positionOf(%d) = %+v, want %+v", offset, got, want)
+	}
+	if got, err := offsetOf(content, want); err != nil || got != offset {
+		t.Errorf("offsetOf(%+v) = %d, %v, want %d", want, got, err, offset)
+	}
+	if _, err := offsetOf(content, Position{Line: 3}); err == nil {
+		t.Errorf("offsetOf(line 3) succeeded, want error")
+	}
+
+	for _, path := range []string{"/home/me/a b.go", "/tmp/x.go"} {
+		uri := pathToURI(path)
+		if got, err := uriToPath(uri); err != nil || got != filepath.FromSlash(path) {
+			t.Errorf("uriToPath(%q) = %q, %v, want %q", uri, got, err, path)
+		}
+	}
+}
+
+// TestServer runs a session of requests against the program
+// testdata/src/lsp/main.go and checks the responses.
+//
+func TestServer(t *testing.T) {
+	filename, err := filepath.Abs("testdata/src/lsp/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri := pathToURI(filename)
+
+	// line returns the zero-based number of the line containing substr.
+	line := func(substr string) int {
+		return bytes.Count(content[:bytes.Index(content, []byte(substr))], []byte("\n"))
+	}
+	at := func(substr string, char int) TextDocumentPositionParams {
+		return TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: line(substr), Character: char},
+		}
+	}
+	var refs ReferenceParams
+	refs.TextDocumentPositionParams = at("func f", 5)
+	refs.Context.IncludeDeclaration = true
+	mainItem := CallHierarchyItem{
+		Name:           "main.main",
+		URI:            uri,
+		SelectionRange: Range{Start: Position{Line: line("func main"), Character: 5}},
+	}
+	bad := strings.Replace(string(content), "_ = y", "_ = y\n\t_ = z", 1)
+
+	var in bytes.Buffer
+	c := &conn{out: &in}
+	for i, req := range []struct {
+		method string
+		params interface{}
+	}{
+		{"initialize", struct{}{}},
+		{"textDocument/definition", at("f(1)", 16)}, // (byte offset 20)
+		{"textDocument/references", refs},
+		{"textDocument/hover", at("i.m()", 1)},
+		{"textDocument/implementation", at("type I", 5)},
+		{"textDocument/prepareCallHierarchy", at("func f", 5)},
+		{"callHierarchy/incomingCalls", CallHierarchyCallsParams{Item: CallHierarchyItem{
+			Name:           "main.f",
+			URI:            uri,
+			SelectionRange: Range{Start: Position{Line: line("func f"), Character: 5}},
+		}}},
+		{"callHierarchy/outgoingCalls", CallHierarchyCallsParams{Item: mainItem}},
+		{"nonesuch", nil},
+	} {
+		c.write(map[string]interface{}{"jsonrpc": "2.0", "id": i, "method": req.method, "params": req.params})
+	}
+	c.write(map[string]interface{}{"jsonrpc": "2.0", "method": "textDocument/didOpen",
+		"params": DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uri, Text: bad}}})
+	// Queries about an unsaved document are refused.
+	c.write(map[string]interface{}{"jsonrpc": "2.0", "id": 9, "method": "textDocument/definition", "params": at("f(1)", 16)})
+	c.write(map[string]interface{}{"jsonrpc": "2.0", "method": "exit"})
+
+	buildContext := build.Default
+	buildContext.GOPATH = "testdata"
+	var out bytes.Buffer
+	if err := NewServer([]string{filename}, &buildContext, true).Serve(&in, &out); err != nil {
+		t.Fatalf("Serve failed: %s", err)
+	}
+
+	// Decode the responses and notifications.
+	results := make(map[int]string) // JSON result of each request
+	errors := make(map[int]string)  // error message of each request
+	var diags PublishDiagnosticsParams
+	c = &conn{in: bufio.NewReader(&out)}
+	for {
+		msg, err := c.read()
+		if err != nil {
+			break
+		}
+		var resp struct {
+			ID     *int
+			Method string
+			Params json.RawMessage
+			Result json.RawMessage
+			Error  *rpcError
+		}
+		if err := json.Unmarshal(msg, &resp); err != nil {
+			t.Fatalf("invalid message %s: %s", msg, err)
+		}
+		switch {
+		case resp.Method == "textDocument/publishDiagnostics":
+			json.Unmarshal(resp.Params, &diags)
+		case resp.ID != nil && resp.Error != nil:
+			errors[*resp.ID] = resp.Error.Message
+		case resp.ID != nil:
+			results[*resp.ID] = string(resp.Result)
+		}
+	}
+
+	loc := func(substr string, char, length int) string {
+		l := line(substr)
+		return `{"uri":"` + uri + `","range":{"start":{"line":` + strconv.Itoa(l) + `,"character":` + strconv.Itoa(char) +
+			`},"end":{"line":` + strconv.Itoa(l) + `,"character":` + strconv.Itoa(char+length) + `}}}`
+	}
+	for id, want := range []string{
+		0: `"definitionProvider":true`,
+		1: loc("func f", 5, 1),
+		2: "[" + loc("func f", 5, 1) + "," + loc("f(1)", 16, 1) + "]",
+		3: `main.I`,
+		4: loc("type T", 5, 1),
+		5: `"name":"main.f","kind":12`,
+		6: `"from":{"name":"main.main"`,
+		7: `"to":{"name":"main.f"`,
+	} {
+		if got := results[id]; !strings.Contains(got, want) {
+			t.Errorf("request %d: got result %s (error %q), want %s", id, got, errors[id], want)
+		}
+	}
+	if got := errors[8]; !strings.Contains(got, "method not found") {
+		t.Errorf("request 8: got error %q, want method not found", got)
+	}
+	if got := errors[9]; !strings.Contains(got, "main.go has unsaved changes") {
+		t.Errorf("request 9: got result %s (error %q), want unsaved changes error", results[9], got)
+	}
+
+	if diags.URI != uri || len(diags.Diagnostics) != 1 {
+		t.Fatalf("got diagnostics %+v, want one for %s", diags, uri)
+	}
+	d := diags.Diagnostics[0]
+	l := line("_ = y") + 1
+	want := Range{Position{l, 5}, Position{l, 6}}
+	if d.Range != want || !strings.Contains(d.Message, "z") {
+		t.Errorf("got diagnostic %+v, want undeclared z at %+v", d, want)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+// This file defines conversions between the protocol's document URIs
+// and line/character positions, and the oracle's file names and byte
+// offsets.  Protocol positions count UTF-16 code units within a line;
+// the oracle counts bytes.
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// uriToPath returns the file name denoted by a "file:" URI.
+func uriToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported URI scheme %q", u.Scheme)
+	}
+	path := u.Path
+	// "file:///C:/foo" denotes the Windows file C:\foo.
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path), nil
+}
+
+// pathToURI returns the "file:" URI of the named file.
+func pathToURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // Windows
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// offsetOf returns the byte offset within content of position pos.
+// A character offset beyond the end of its line denotes the end of
+// the line.
+//
+func offsetOf(content []byte, pos Position) (int, error) {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		nl := bytes.IndexByte(content[offset:], '\n')
+		if nl < 0 {
+			return 0, fmt.Errorf("line %d is beyond end of file", pos.Line+1)
+		}
+		offset += nl + 1
+	}
+	for units := 0; units < pos.Character && offset < len(content); {
+		r, size := utf8.DecodeRune(content[offset:])
+		if r == '\n' {
+			break
+		}
+		units += utf16Len(r)
+		offset += size
+	}
+	return offset, nil
+}
+
+// positionOf returns the protocol position of the byte offset within
+// content.
+//
+func positionOf(content []byte, offset int) Position {
+	if offset > len(content) {
+		offset = len(content)
+	}
+	var pos Position
+	start := 0 // offset of start of line
+	for i, b := range content[:offset] {
+		if b == '\n' {
+			pos.Line++
+			start = i + 1
+		}
+	}
+	for _, r := range string(content[start:offset]) {
+		pos.Character += utf16Len(r)
+	}
+	return pos
+}
+
+// utf16Len returns the number of UTF-16 code units needed to encode r.
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2 // surrogate pair
+	}
+	return 1
+}
+
+// lineColOffset returns the byte offset within content of the 1-based
+// line and column col, which counts bytes, as in the oracle's output.
+//
+func lineColOffset(content []byte, line, col int) int {
+	offset := 0
+	for ; line > 1; line-- {
+		nl := bytes.IndexByte(content[offset:], '\n')
+		if nl < 0 {
+			return len(content)
+		}
+		offset += nl + 1
+	}
+	offset += col - 1
+	if offset > len(content) {
+		offset = len(content)
+	}
+	return offset
+}
+
+// identEnd returns the offset of the end of the identifier that
+// begins at offset within content, or offset itself if there is none.
+//
+func identEnd(content []byte, offset int) int {
+	for offset < len(content) {
+		r, size := utf8.DecodeRune(content[offset:])
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		offset += size
+	}
+	return offset
+}
+
+// parseOraclePos parses a position of the form "file:line:col" in the
+// oracle's serialized output.
+//
+func parseOraclePos(pos string) (file string, line, col int, err error) {
+	// The file name may itself contain colons, so parse from the end.
+	if i := strings.LastIndex(pos, ":"); i >= 0 {
+		if j := strings.LastIndex(pos[:i], ":"); j >= 0 {
+			line, err1 := strconv.Atoi(pos[j+1 : i])
+			col, err2 := strconv.Atoi(pos[i+1:])
+			if err1 == nil && err2 == nil {
+				return pos[:j], line, col, nil
+			}
+		}
+	}
+	return "", 0, 0, fmt.Errorf("invalid oracle position %q", pos)
+}
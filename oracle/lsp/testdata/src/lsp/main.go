@@ -0,0 +1,24 @@
+package main
+
+// Test data for the language server protocol adapter.
+// See lsp_test.go for the requests made.
+
+type I interface {
+	m()
+}
+
+type T int
+
+func (T) m() {}
+
+func f(x int) int {
+	return x + 1
+}
+
+func main() {
+	var i I = T(0)
+	i.m()
+	s, y := "☺𝄞", f(1) // (𝄞 is two UTF-16 code units)
+	_ = s
+	_ = y
+}
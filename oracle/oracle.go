@@ -24,9 +24,6 @@ import (
 	"go/printer"
 	"go/token"
 	"io"
-	"os"
-	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
@@ -34,6 +31,7 @@ import (
 	"code.google.com/p/go.tools/importer"
 	"code.google.com/p/go.tools/oracle/serial"
 	"code.google.com/p/go.tools/pointer"
+	"code.google.com/p/go.tools/refactor/util"
 	"code.google.com/p/go.tools/ssa"
 )
 
@@ -46,6 +44,15 @@ type Oracle struct {
 	// need&AllTypeInfo
 	typeInfo map[*types.Package]*importer.PackageInfo // type info for all ASTs in the program
 
+	// callGraphResult caches the result of a pointer analysis
+	// that computes only the call graph, which is independent of
+	// the query selection.
+	callGraphResult *pointer.Result
+
+	// Per-query state, reset after each query.
+	filter *Filter // restricts the results of the query; may be nil
+	page   *page   // the portion of the filtered results reported
+
 	timers map[string]time.Duration // phase timing information
 }
 
@@ -75,11 +82,17 @@ var modes = []*modeInfo{
 	{"callers", needPTA | needPos, callers},
 	{"callgraph", needPTA, callgraph},
 	{"callstack", needPTA | needPos, callstack},
+	{"channels", needPTA, channels},
+	{"definition", needPos, definition},
 	{"describe", needPTA | needSSADebug | needExactPos, describe},
 	{"freevars", needPos, freevars},
-	{"implements", needPos, implements},
+	{"implements", needAllTypeInfo | needPos, implements},
 	{"peers", needPTA | needSSADebug | needPos, peers},
+	{"pointsto", needPTA | needSSADebug | needExactPos, pointsto},
 	{"referrers", needAllTypeInfo | needPos, referrers},
+	{"taint", needPTA, taintFlows},
+	{"whicherrs", needPTA | needSSADebug | needExactPos, whicherrs},
+	{"whypointsto", needPTA | needSSADebug | needExactPos, whypointsto},
 }
 
 func findMode(mode string) *modeInfo {
@@ -118,6 +131,7 @@ type Result struct {
 	q        queryResult       // the query-specific result
 	mode     string            // query mode
 	warnings []pointer.Warning // pointer analysis warnings
+	page     *page             // the portion of the results reported, if filtered
 }
 
 // Serial returns an instance of serial.Result, which implements the
@@ -133,13 +147,19 @@ func (res *Result) Serial() *serial.Result {
 			Message: w.Message,
 		})
 	}
+	if res.page != nil {
+		resj.Filter = res.page.toSerial()
+	}
 	return resj
 }
 
 // Query runs a single oracle query.
 //
 // args specify the main package in importer.CreatePackageFromArgs syntax.
+// A package with neither a main function nor tests is analyzed as a
+// library, treating its exported functions and methods as entry points.
 // mode is the query mode ("callers", etc).
+// pos is the query position, e.g. "foo.go:#123,#456".
 // ptalog is the (optional) pointer-analysis log file.
 // buildContext is the go/build configuration for locating packages.
 // reflection determines whether to model reflection soundly (currently slow).
@@ -164,6 +184,13 @@ func (res *Result) Serial() *serial.Result {
 // depends on the query mode; how should we expose this?
 //
 func Query(args []string, mode, pos string, ptalog io.Writer, buildContext *build.Context, reflection bool) (*Result, error) {
+	return QueryWithFilter(args, mode, pos, nil, ptalog, buildContext, reflection)
+}
+
+// QueryWithFilter is like Query, but filter (optional) restricts the
+// results of the query, as described at Filter.
+//
+func QueryWithFilter(args []string, mode, pos string, filter *Filter, ptalog io.Writer, buildContext *build.Context, reflection bool) (*Result, error) {
 	minfo := findMode(mode)
 	if minfo == nil {
 		return nil, fmt.Errorf("invalid mode type: %q", mode)
@@ -199,7 +226,7 @@ func Query(args []string, mode, pos string, ptalog io.Writer, buildContext *buil
 	// Release the other ASTs and type info to the GC.
 	imp = nil
 
-	return o.query(minfo, qpos)
+	return o.query(minfo, qpos, filter)
 }
 
 // New constructs a new Oracle that can be used for a sequence of queries.
@@ -208,6 +235,8 @@ func Query(args []string, mode, pos string, ptalog io.Writer, buildContext *buil
 // It must not yet have loaded any packages.
 //
 // args specify the main package in importer.CreatePackageFromArgs syntax.
+// A package with neither a main function nor tests is analyzed as a
+// library, treating its exported functions and methods as entry points.
 //
 // ptalog is the (optional) pointer-analysis log file.
 // reflection determines whether to model reflection soundly (currently slow).
@@ -263,7 +292,10 @@ func newOracle(imp *importer.Importer, args []string, ptalog io.Writer, needs in
 				// should build a single synthetic testmain package,
 				// not synthetic main functions to many packages.
 				if initialPkg.CreateTestMainFunction() == nil {
-					return nil, fmt.Errorf("analysis scope has no main() entry points")
+					// No main() and no tests: analyze
+					// the package as a library.
+					o.config.Libraries = append(o.config.Libraries, initialPkg)
+					continue
 				}
 			}
 			o.config.Mains = append(o.config.Mains, initialPkg)
@@ -283,21 +315,38 @@ func newOracle(imp *importer.Importer, args []string, ptalog io.Writer, needs in
 
 // Query runs the query of the specified mode and selection.
 func (o *Oracle) Query(mode string, qpos *QueryPos) (*Result, error) {
+	return o.QueryWithFilter(mode, qpos, nil)
+}
+
+// QueryWithFilter is like Query, but filter (optional) restricts the
+// results of the query, as described at Filter.
+//
+func (o *Oracle) QueryWithFilter(mode string, qpos *QueryPos, filter *Filter) (*Result, error) {
 	minfo := findMode(mode)
 	if minfo == nil {
 		return nil, fmt.Errorf("invalid mode type: %q", mode)
 	}
-	return o.query(minfo, qpos)
+	return o.query(minfo, qpos, filter)
 }
 
-func (o *Oracle) query(minfo *modeInfo, qpos *QueryPos) (*Result, error) {
+func (o *Oracle) query(minfo *modeInfo, qpos *QueryPos, filter *Filter) (*Result, error) {
 	res := &Result{
 		mode:    minfo.name,
 		fset:    o.prog.Fset,
 		fprintf: o.fprintf, // captures o.prog, o.{start,end}Pos for later printing
 	}
+	o.filter = filter
 	var err error
 	res.q, err = minfo.impl(o, qpos)
+	res.page = o.page
+
+	// Reset the per-query state.
+	o.config.BuildCallGraph = false
+	o.config.CallGraphFilter = nil
+	o.config.Queries = nil
+	o.filter = nil
+	o.page = nil
+
 	if err != nil {
 		return nil, err
 	}
@@ -329,6 +378,11 @@ func (res *Result) WriteTo(out io.Writer) {
 	}
 	res.q.display(printf)
 
+	// Describe the page of filtered results.
+	if res.page != nil {
+		res.page.display(printf)
+	}
+
 	// Print warnings after the main output.
 	if res.warnings != nil {
 		fmt.Fprintln(out, "\nPointer analysis warnings:")
@@ -338,6 +392,24 @@ func (res *Result) WriteTo(out io.Writer) {
 	}
 }
 
+// A dotResult is a query result that can be displayed as a graph.
+type dotResult interface {
+	dot(w io.Writer, fset *token.FileSet)
+}
+
+// WriteDOT writes the oracle query result res to out as a graph in
+// the DOT language of Graphviz.  Only some query modes support this
+// format.
+//
+func (res *Result) WriteDOT(out io.Writer) error {
+	d, ok := res.q.(dotResult)
+	if !ok {
+		return fmt.Errorf("%s query results cannot be displayed as a graph", res.mode)
+	}
+	d.dot(out, res.fset)
+	return nil
+}
+
 // ---------- Utilities ----------
 
 // buildSSA constructs the SSA representation of Go-source function bodies.
@@ -350,22 +422,21 @@ func buildSSA(o *Oracle) {
 }
 
 // ptrAnalysis runs the pointer analysis and returns its result.
+// A result that consists only of the call graph is computed only once
+// per Oracle.
+//
 func ptrAnalysis(o *Oracle) *pointer.Result {
+	cacheable := o.config.BuildCallGraph && o.config.CallGraphFilter == nil && o.config.Queries == nil
+	if cacheable && o.callGraphResult != nil {
+		return o.callGraphResult
+	}
 	start := time.Now()
 	result := pointer.Analyze(&o.config)
 	o.timers["pointer analysis"] = time.Since(start)
-	return result
-}
-
-// parseOctothorpDecimal returns the numeric value if s matches "#%d",
-// otherwise -1.
-func parseOctothorpDecimal(s string) int {
-	if s != "" && s[0] == '#' {
-		if s, err := strconv.ParseInt(s[1:], 10, 32); err == nil {
-			return int(s)
-		}
+	if cacheable {
+		o.callGraphResult = result
 	}
-	return -1
+	return result
 }
 
 // parseQueryPos parses a string of the form "file:pos" or
@@ -391,24 +462,25 @@ func parseQueryPos(fset *token.FileSet, queryPos string) (start, end token.Pos,
 	endOffset := -1
 	if hyphen := strings.Index(offset, ","); hyphen < 0 {
 		// e.g. "foo.go:#123"
-		startOffset = parseOctothorpDecimal(offset)
+		startOffset = util.ParseOctothorpDecimal(offset)
 		endOffset = startOffset
 	} else {
 		// e.g. "foo.go:#123,#456"
-		startOffset = parseOctothorpDecimal(offset[:hyphen])
-		endOffset = parseOctothorpDecimal(offset[hyphen+1:])
+		startOffset = util.ParseOctothorpDecimal(offset[:hyphen])
+		endOffset = util.ParseOctothorpDecimal(offset[hyphen+1:])
 	}
 	if startOffset < 0 || endOffset < 0 {
 		err = fmt.Errorf("invalid -pos offset %q", offset)
 		return
 	}
 
+	// If the file was parsed more than once, as happens when a
+	// Server reloads a changed package, use the latest version.
 	var file *token.File
 	fset.Iterate(func(f *token.File) bool {
-		if sameFile(filename, f.Name()) {
+		if util.SameFile(filename, f.Name()) {
 			// (f.Name() is absolute)
 			file = f
-			return false // done
 		}
 		return true // continue
 	})
@@ -436,20 +508,6 @@ func parseQueryPos(fset *token.FileSet, queryPos string) (start, end token.Pos,
 	return
 }
 
-// sameFile returns true if x and y have the same basename and denote
-// the same file.
-//
-func sameFile(x, y string) bool {
-	if filepath.Base(x) == filepath.Base(y) { // (optimisation)
-		if xi, err := os.Stat(x); err == nil {
-			if yi, err := os.Stat(y); err == nil {
-				return os.SameFile(xi, yi)
-			}
-		}
-	}
-	return false
-}
-
 // unparen returns e with any enclosing parentheses stripped.
 func unparen(e ast.Expr) ast.Expr {
 	for {
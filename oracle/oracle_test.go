@@ -36,6 +36,9 @@ import (
 	"go/token"
 	"io"
 	"io/ioutil"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
 	"os"
 	"os/exec"
 	"regexp"
@@ -46,6 +49,7 @@ import (
 
 	"code.google.com/p/go.tools/importer"
 	"code.google.com/p/go.tools/oracle"
+	"code.google.com/p/go.tools/oracle/serial"
 )
 
 var updateFlag = flag.Bool("update", false, "Update the golden files.")
@@ -201,16 +205,26 @@ func TestOracle(t *testing.T) {
 		"testdata/src/main/calls.go",
 		"testdata/src/main/callgraph.go",
 		"testdata/src/main/callgraph2.go",
+		"testdata/src/main/channels.go",
+		"testdata/src/main/definition.go",
 		"testdata/src/main/describe.go",
 		"testdata/src/main/freevars.go",
 		"testdata/src/main/implements.go",
 		"testdata/src/main/imports.go",
+		"testdata/src/main/library.go",
 		"testdata/src/main/peers.go",
+		"testdata/src/main/pointsto.go",
 		"testdata/src/main/reflection.go",
+		"testdata/src/main/taint.go",
+		"testdata/src/main/whicherrs.go",
 		// JSON:
 		"testdata/src/main/callgraph-json.go",
 		"testdata/src/main/calls-json.go",
+		"testdata/src/main/channels-json.go",
+		"testdata/src/main/definition-json.go",
+		"testdata/src/main/implements-json.go",
 		"testdata/src/main/peers-json.go",
+		"testdata/src/main/pointsto-json.go",
 		"testdata/src/main/describe-json.go",
 		"testdata/src/main/referrers-json.go",
 	} {
@@ -294,3 +308,153 @@ var x int
 		t.Errorf("Query output differs; want <<%s>>, got <<%s>>\n", want, got)
 	}
 }
+
+func TestServer(t *testing.T) {
+	var buildContext = build.Default
+	buildContext.GOPATH = "testdata"
+	filename := "testdata/src/main/multi.go"
+
+	// Serve JSON-RPC requests over an in-memory connection.
+	srv := rpc.NewServer()
+	srv.RegisterName("Oracle", oracle.NewServer([]string{filename}, nil, &buildContext, true))
+	clientConn, serverConn := net.Pipe()
+	go srv.ServeCodec(jsonrpc.NewServerCodec(serverConn))
+	client := jsonrpc.NewClient(clientConn)
+	defer client.Close()
+
+	pos := filename + ":#54,#58"
+	for _, mode := range [...]string{"callers", "freevars", "callers"} {
+		var res serial.Result
+		if err := client.Call("Oracle.Query", &oracle.QueryArgs{Mode: mode, Pos: pos}, &res); err != nil {
+			t.Fatalf("Oracle.Query(%q) failed: %s", mode, err)
+		}
+		if res.Mode != mode {
+			t.Errorf("Oracle.Query(%q): got result of mode %q", mode, res.Mode)
+		}
+	}
+
+	var res serial.Result
+	if err := client.Call("Oracle.Query", &oracle.QueryArgs{Mode: "nonesuch"}, &res); err == nil {
+		t.Errorf("Oracle.Query with invalid mode succeeded")
+	}
+
+	for _, test := range []struct {
+		file     string
+		reloaded bool
+	}{
+		{"testdata/src/lib/lib.go", false}, // not in the program
+		{filename, true},
+		{filename, false}, // already discarded
+	} {
+		var reply oracle.ChangedReply
+		if err := client.Call("Oracle.Changed", &oracle.ChangedArgs{Files: []string{test.file}}, &reply); err != nil {
+			t.Fatalf("Oracle.Changed failed: %s", err)
+		}
+		if reply.Reloaded != test.reloaded {
+			t.Errorf("Oracle.Changed(%s): got reloaded=%t, want %t", test.file, reply.Reloaded, test.reloaded)
+		}
+	}
+
+	// The next query rebuilds the program, reloading the changed package.
+	if err := client.Call("Oracle.Query", &oracle.QueryArgs{Mode: "callers", Pos: pos}, &res); err != nil {
+		t.Fatalf("Oracle.Query after Oracle.Changed failed: %s", err)
+	}
+	var reply oracle.ChangedReply
+	if err := client.Call("Oracle.Changed", &oracle.ChangedArgs{Files: []string{filename}}, &reply); err != nil {
+		t.Fatalf("Oracle.Changed failed: %s", err)
+	}
+	if !reply.Reloaded {
+		t.Errorf("Oracle.Changed(%s) after reload: got reloaded=false, want true", filename)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	var buildContext = build.Default
+	buildContext.GOPATH = "testdata"
+
+	// Query the two references to lib.Type.Method.
+	filename := "testdata/src/main/referrers-json.go"
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := bytes.Index(content, []byte("Method"))
+	pos := fmt.Sprintf("%s:#%d,#%d", filename, offset, offset+len("Method"))
+
+	// Each test whose filter has Start "next" continues from the
+	// continuation token of the previous one.
+	var next string
+	for _, test := range []struct {
+		filter    oracle.Filter
+		refs      int  // number of refs reported
+		start     int  // serial.Filter.Start
+		total     int  // serial.Filter.Total
+		more      bool // serial.Filter.Next is non-empty
+		wantError string
+	}{
+		{filter: oracle.Filter{Limit: 1}, refs: 1, start: 0, total: 2, more: true},
+		{filter: oracle.Filter{Limit: 1, Start: "next"}, refs: 1, start: 1, total: 2},
+		{filter: oracle.Filter{Limit: 1}, refs: 1, start: 0, total: 2, more: true},
+		{filter: oracle.Filter{Packages: []string{"main"}, Start: "next"}, wantError: "does not match the results"},
+		{filter: oracle.Filter{Packages: []string{"lib/..."}}, refs: 0, total: 0},
+		{filter: oracle.Filter{Packages: []string{"lib", "referrers"}}, refs: 2, total: 2},
+		{filter: oracle.Filter{Start: "3"}, wantError: `invalid continuation token "3"`},
+		{filter: oracle.Filter{Start: "bad"}, wantError: `invalid continuation token "bad"`},
+	} {
+		filter := test.filter
+		if filter.Start == "next" {
+			filter.Start = next
+		}
+		res, err := oracle.QueryWithFilter([]string{filename}, "referrers", pos, &filter, nil, &buildContext, true)
+		if test.wantError != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantError) {
+				t.Errorf("QueryWithFilter(%+v): got error %v, want %q", filter, err, test.wantError)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("QueryWithFilter(%+v) failed: %s", filter, err)
+			continue
+		}
+		sres := res.Serial()
+		if got := len(sres.Referrers.Refs); got != test.refs {
+			t.Errorf("QueryWithFilter(%+v): got %d refs, want %d", filter, got, test.refs)
+		}
+		f := sres.Filter
+		if f == nil {
+			t.Errorf("QueryWithFilter(%+v): no filter in result", filter)
+			continue
+		}
+		if f.Start != test.start || f.Total != test.total || (f.Next != "") != test.more {
+			t.Errorf("QueryWithFilter(%+v): got start=%d total=%d next=%q, want start=%d total=%d more=%t",
+				filter, f.Start, f.Total, f.Next, test.start, test.total, test.more)
+		}
+		next = f.Next
+	}
+}
+
+func TestChannelsDOT(t *testing.T) {
+	var buildContext = build.Default
+	buildContext.GOPATH = "testdata"
+	filename := "testdata/src/main/channels-json.go"
+	res, err := oracle.Query([]string{filename}, "channels", "", nil, &buildContext, true)
+	if err != nil {
+		t.Fatalf("oracle.Query failed: %s", err)
+	}
+	out := new(bytes.Buffer)
+	if err := res.WriteDOT(out); err != nil {
+		t.Fatalf("WriteDOT failed: %s", err)
+	}
+	want := `digraph channels {
+	c0 [shape=box,label="chan int\ntestdata/src/main/channels-json.go:8:12"];
+	f0 [label="func@9.5"];
+	f0 -> c0 [label="send"];
+	f1 [label="main.main"];
+	c0 -> f1 [label="receive"];
+	f1 -> f0 [label="go",style=dotted];
+}
+`
+	if got := out.String(); got != want {
+		t.Errorf("WriteDOT output differs; want <<%s>>, got <<%s>>", want, got)
+	}
+}
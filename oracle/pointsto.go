@@ -0,0 +1,603 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oracle
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+
+	"code.google.com/p/go.tools/go/types"
+	"code.google.com/p/go.tools/importer"
+	"code.google.com/p/go.tools/internal/ptaindex"
+	"code.google.com/p/go.tools/oracle/serial"
+	"code.google.com/p/go.tools/pointer"
+	"code.google.com/p/go.tools/ssa"
+)
+
+// pointsto runs the pointer analysis on the selected expression,
+// and reports its points-to set (for a pointer-like expression)
+// or its dynamic types (for an interface, reflect.Value, or
+// reflect.Type expression) and their points-to sets.
+//
+// All printed sets are sorted to ensure determinism.
+//
+func pointsto(o *Oracle, qpos *QueryPos) (queryResult, error) {
+	return doPointsto(o, qpos, false)
+}
+
+// whypointsto is like pointsto, but additionally explains each label
+// by a chain of assignments from its allocation site to the selected
+// expression.  It is much more expensive, since it computes the
+// points-to set of every pointer-like value in the program.
+//
+func whypointsto(o *Oracle, qpos *QueryPos) (queryResult, error) {
+	return doPointsto(o, qpos, true)
+}
+
+func doPointsto(o *Oracle, qpos *QueryPos, why bool) (queryResult, error) {
+	path := qpos.path
+	expr, _ := path[0].(ast.Expr)
+	if expr == nil {
+		return nil, fmt.Errorf("pointer analysis wants an expression; got %s",
+			importer.NodeDescription(path[0]))
+	}
+
+	var obj types.Object
+	if id, ok := expr.(*ast.Ident); ok {
+		obj = qpos.info.ObjectOf(id)
+		if _, ok := obj.(*types.Var); !ok {
+			return nil, fmt.Errorf("pointer analysis wants an expression; got %s",
+				importer.NodeDescription(id))
+		}
+	}
+
+	typ := qpos.info.TypeOf(expr)
+	if !pointer.CanPoint(typ) {
+		return nil, fmt.Errorf("pointer analysis wants an expression of reference type; got %s", typ)
+	}
+
+	// Determine the ssa.Value for the expression.
+	var value ssa.Value
+	var err error
+	if obj != nil {
+		// def/ref of var object
+		value, err = ssaValueForIdent(o.prog, qpos.info, obj, path)
+	} else {
+		value, err = ssaValueForExpr(o.prog, qpos.info, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, fmt.Errorf("no SSA value for expression")
+	}
+	indirect := types.IsIdentical(types.NewPointer(typ), value.Type())
+
+	buildSSA(o)
+	o.config.Queries = map[ssa.Value]pointer.Indirect{value: pointer.Indirect(indirect)}
+	var e *explainer
+	if why {
+		e = newExplainer(o)
+	}
+	ptares := ptrAnalysis(o)
+
+	pointers := ptares.Queries[value]
+	if pointers == nil {
+		return nil, fmt.Errorf("pointer analysis did not encounter this expression (dead code?)")
+	}
+	pts := pointer.PointsToCombined(pointers)
+	if e != nil {
+		e.init(ptares)
+	}
+
+	var ptrs []pointstoEntry
+	if pointer.CanHaveDynamicTypes(typ) {
+		// Show concrete types for interface/reflect.Value expression.
+		concs := pts.DynamicTypes()
+		concs.Iterate(func(conc types.Type, pta interface{}) {
+			labels := pointer.PointsToCombined(pta.([]pointer.Pointer)).Labels()
+			sort.Sort(byPosAndString(labels)) // to ensure determinism
+			entry := pointstoEntry{typ: conc, labels: labels}
+			if e != nil {
+				// Explain the origin of the dynamic type
+				// by its first tagged object.
+				for _, l := range pts.Labels() {
+					if mi, ok := l.Value().(*ssa.MakeInterface); ok && types.IsIdentical(mi.X.Type(), conc) {
+						entry.why = e.explain(value, indirect, mi)
+						break
+					}
+				}
+			}
+			ptrs = append(ptrs, entry)
+		})
+	} else {
+		labels := pts.Labels()
+		sort.Sort(byPosAndString(labels)) // to ensure determinism
+		entry := pointstoEntry{typ: value.Type(), labels: labels}
+		if indirect {
+			entry.typ = typ
+		}
+		if e != nil {
+			for _, l := range labels {
+				entry.labelWhy = append(entry.labelWhy, e.explain(value, indirect, l.Value()))
+			}
+		}
+		ptrs = append(ptrs, entry)
+	}
+	sort.Sort(byEntryTypeString(ptrs)) // to ensure determinism
+
+	return &pointstoResult{
+		qpos: qpos,
+		typ:  typ,
+		ptrs: ptrs,
+	}, nil
+}
+
+// A pointstoEntry describes the points-to set of the selected
+// expression, or of one of its dynamic types.
+type pointstoEntry struct {
+	typ      types.Type       // type of the pointer (always concrete)
+	labels   []*pointer.Label // pointed-to objects
+	why      []whyStep        // origin of the dynamic type, if requested
+	labelWhy [][]whyStep      // origin of each label, if requested
+}
+
+type byEntryTypeString []pointstoEntry
+
+func (a byEntryTypeString) Len() int           { return len(a) }
+func (a byEntryTypeString) Less(i, j int) bool { return a[i].typ.String() < a[j].typ.String() }
+func (a byEntryTypeString) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+type pointstoResult struct {
+	qpos *QueryPos
+	typ  types.Type      // type of expression
+	ptrs []pointstoEntry // pointer info (typ is concrete => len==1)
+}
+
+func (r *pointstoResult) display(printf printfFunc) {
+	if pointer.CanHaveDynamicTypes(r.typ) {
+		// Show concrete types for interface, reflect.Type or
+		// reflect.Value expression.
+		if len(r.ptrs) > 0 {
+			printf(r.qpos, "this %s may contain these dynamic types:", r.typ)
+			for _, ptr := range r.ptrs {
+				var obj types.Object
+				if nt, ok := deref(ptr.typ).(*types.Named); ok {
+					obj = nt.Obj()
+				}
+				if len(ptr.labels) > 0 {
+					printf(obj, "\t%s, may point to:", ptr.typ)
+					printPointstoLabels(printf, ptr.labels, nil, "\t\t")
+				} else {
+					printf(obj, "\t%s", ptr.typ)
+				}
+				printWhy(printf, ptr.why, "\t\t")
+			}
+		} else {
+			printf(r.qpos, "this %s cannot contain any dynamic types.", r.typ)
+		}
+	} else {
+		// Show labels for other expressions.
+		if ptr := r.ptrs[0]; len(ptr.labels) > 0 {
+			printf(r.qpos, "this %s may point to these objects:", r.typ)
+			printPointstoLabels(printf, ptr.labels, ptr.labelWhy, "\t")
+		} else {
+			printf(r.qpos, "this %s may not point to anything.", r.typ)
+		}
+	}
+}
+
+// printPointstoLabels prints each label with its context and access
+// path, followed by its explanation, if any.
+func printPointstoLabels(printf printfFunc, labels []*pointer.Label, why [][]whyStep, prefix string) {
+	for i, l := range labels {
+		var ctx string
+		if cgn := l.Context(); cgn != nil {
+			ctx = fmt.Sprintf(" (in context %s)", cgn.Func())
+		}
+		printf(l, "%s%s%s", prefix, l, ctx)
+		if why != nil {
+			printWhy(printf, why[i], prefix+"\t")
+		}
+	}
+}
+
+func printWhy(printf printfFunc, why []whyStep, prefix string) {
+	if why == nil {
+		return
+	}
+	printf(nil, "%sbecause:", prefix)
+	for _, step := range why {
+		printf(step.pos, "%s\t%s", prefix, step.desc)
+	}
+}
+
+func (r *pointstoResult) toSerial(res *serial.Result, fset *token.FileSet) {
+	var pts []serial.PointsTo
+	for _, ptr := range r.ptrs {
+		var namePos string
+		if nt, ok := deref(ptr.typ).(*types.Named); ok {
+			namePos = fset.Position(nt.Obj().Pos()).String()
+		}
+		var labels []serial.PointsToLabel
+		for i, l := range ptr.labels {
+			j := serial.PointsToLabel{
+				Pos:  fset.Position(l.Pos()).String(),
+				Desc: l.String(),
+				Path: l.Path(),
+			}
+			if cgn := l.Context(); cgn != nil {
+				j.Context = cgn.Func().String()
+			}
+			if ptr.labelWhy != nil {
+				j.Why = whyToSerial(ptr.labelWhy[i], fset)
+			}
+			labels = append(labels, j)
+		}
+		pts = append(pts, serial.PointsTo{
+			Type:    ptr.typ.String(),
+			NamePos: namePos,
+			Labels:  labels,
+			Why:     whyToSerial(ptr.why, fset),
+		})
+	}
+	res.PointsTo = pts
+}
+
+func whyToSerial(why []whyStep, fset *token.FileSet) []serial.WhyStep {
+	var steps []serial.WhyStep
+	for _, step := range why {
+		steps = append(steps, serial.WhyStep{
+			Pos:  fset.Position(step.pos).String(),
+			Desc: step.desc,
+		})
+	}
+	return steps
+}
+
+// ---- Explanation of points-to facts -----------------------------------
+
+// A whyStep is one step in the chain of assignments by which an
+// object flows from its allocation site to a pointer.
+type whyStep struct {
+	pos  token.Pos
+	desc string
+}
+
+// An explainer finds chains of assignments from allocation sites to
+// pointers using the points-to sets of all pointer-like values of the
+// program, by searching backwards through the SSA value flow from the
+// pointer to the allocation site.
+//
+type explainer struct {
+	ptrs      map[ssa.Value][]pointer.Pointer
+	index     *ptaindex.Index // call graph and stores
+	closures  map[*ssa.Function][]*ssa.MakeClosure
+	returns   map[*ssa.Function][]*ssa.Return
+	pointsTos map[ssa.Value]pointer.PointsToSet // memoization of pointsTo
+}
+
+// newExplainer returns an explainer for the program of o, adding
+// Queries for all pointer-like values to the pointer analysis
+// configuration.
+//
+func newExplainer(o *Oracle) *explainer {
+	e := &explainer{
+		index:     ptaindex.New(o.prog, &o.config),
+		closures:  make(map[*ssa.Function][]*ssa.MakeClosure),
+		returns:   make(map[*ssa.Function][]*ssa.Return),
+		pointsTos: make(map[ssa.Value]pointer.PointsToSet),
+	}
+	query := func(v ssa.Value) {
+		if _, ok := o.config.Queries[v]; !ok && pointer.CanPoint(v.Type()) {
+			o.config.Queries[v] = false
+		}
+	}
+	for fn := range ssa.AllFunctions(o.prog) {
+		for _, p := range fn.Params {
+			query(p)
+		}
+		for _, fv := range fn.FreeVars {
+			query(fv)
+		}
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				switch instr := instr.(type) {
+				case *ssa.MakeClosure:
+					fn := instr.Fn.(*ssa.Function)
+					e.closures[fn] = append(e.closures[fn], instr)
+				case *ssa.Return:
+					e.returns[fn] = append(e.returns[fn], instr)
+				}
+				if v, ok := instr.(ssa.Value); ok {
+					query(v)
+				}
+			}
+		}
+	}
+	return e
+}
+
+// init records the results of the pointer analysis.
+func (e *explainer) init(result *pointer.Result) {
+	e.ptrs = result.Queries
+	e.index.Init(result)
+}
+
+// pointsTo returns the combined points-to set of v, or nil if unknown.
+func (e *explainer) pointsTo(v ssa.Value) pointer.PointsToSet {
+	pts, ok := e.pointsTos[v]
+	if !ok {
+		if ptrs := e.ptrs[v]; ptrs != nil {
+			pts = pointer.PointsToCombined(ptrs)
+		}
+		e.pointsTos[v] = pts
+	}
+	return pts
+}
+
+// mayPointTo reports whether v may point to an object allocated by
+// alloc, or v is not pointer-like.
+//
+func (e *explainer) mayPointTo(v, alloc ssa.Value) bool {
+	if !pointer.CanPoint(v.Type()) {
+		return true // e.g. a tuple
+	}
+	pts := e.pointsTo(v)
+	if pts == nil {
+		return false
+	}
+	for _, l := range pts.Labels() {
+		if l.Value() == alloc {
+			return true
+		}
+	}
+	return false
+}
+
+// mayAlias reports whether pointers x and y may alias.
+func (e *explainer) mayAlias(x, y ssa.Value) bool {
+	px, py := e.pointsTo(x), e.pointsTo(y)
+	return px != nil && py != nil && px.Intersects(py)
+}
+
+// A whyEdge records the successor of a value along the chain from
+// the allocation site to the queried pointer.
+type whyEdge struct {
+	succ ssa.Value       // successor value, or nil for the queried pointer
+	via  ssa.Instruction // instruction by which the object flows to succ, if not succ itself
+}
+
+// explain returns a chain of assignments by which the object allocated
+// by alloc flows to v (or to *v, if indirect), or nil if none was found.
+//
+func (e *explainer) explain(v ssa.Value, indirect bool, alloc ssa.Value) []whyStep {
+	if alloc == nil {
+		return nil
+	}
+
+	// Breadth-first search backwards from v.
+	edges := make(map[ssa.Value]whyEdge)
+	var queue []ssa.Value
+	visit := func(pred ssa.Value, succ ssa.Value, via ssa.Instruction) {
+		if _, ok := edges[pred]; !ok && e.mayPointTo(pred, alloc) {
+			edges[pred] = whyEdge{succ, via}
+			queue = append(queue, pred)
+		}
+	}
+	if indirect {
+		// *v is loaded from the location v.
+		for _, pred := range e.storedInto(v) {
+			visit(pred.val, nil, pred.instr)
+		}
+	} else {
+		visit(v, nil, nil)
+	}
+
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		if u == alloc {
+			// Found: follow the chain forwards.
+			var steps []whyStep
+			// Steps without positions are omitted.
+			for ; u != nil; u = edges[u].succ {
+				if pos := u.Pos(); pos != token.NoPos || u == alloc {
+					steps = append(steps, whyStep{pos, describeFlow(u, false)})
+				}
+				if via := edges[u].via; via != nil && via.Pos() != token.NoPos {
+					steps = append(steps, whyStep{via.Pos(), describeFlow(via, true)})
+				}
+			}
+			return steps
+		}
+		for _, pred := range e.preds(u) {
+			visit(pred.val, u, pred.instr)
+		}
+	}
+	return nil
+}
+
+// A flowPred is a value that flows to another, possibly via an
+// instruction such as a store or call.
+type flowPred struct {
+	val   ssa.Value
+	instr ssa.Instruction // may be nil
+}
+
+// preds returns the values from which the value u may be derived.
+func (e *explainer) preds(u ssa.Value) []flowPred {
+	var preds []flowPred
+	add := func(v ssa.Value, instr ssa.Instruction) {
+		preds = append(preds, flowPred{v, instr})
+	}
+	switch u := u.(type) {
+	case *ssa.Phi:
+		for _, edge := range u.Edges {
+			add(edge, nil)
+		}
+	case *ssa.ChangeType:
+		add(u.X, nil)
+	case *ssa.Convert:
+		add(u.X, nil)
+	case *ssa.ChangeInterface:
+		add(u.X, nil)
+	case *ssa.MakeInterface:
+		add(u.X, nil)
+	case *ssa.TypeAssert:
+		add(u.X, nil)
+	case *ssa.Slice:
+		add(u.X, nil)
+	case *ssa.FieldAddr:
+		add(u.X, nil)
+	case *ssa.IndexAddr:
+		add(u.X, nil)
+	case *ssa.Field:
+		add(u.X, nil)
+	case *ssa.Index:
+		add(u.X, nil)
+	case *ssa.Extract:
+		add(u.Tuple, nil)
+	case *ssa.UnOp:
+		if u.Op == token.MUL || u.Op == token.ARROW {
+			preds = e.storedInto(u.X)
+		}
+	case *ssa.Lookup:
+		if _, ok := u.X.Type().Underlying().(*types.Map); ok {
+			preds = e.storedInto(u.X)
+		}
+	case *ssa.Call:
+		if b, ok := u.Call.Value.(*ssa.Builtin); ok {
+			if b.Object().Name() == "append" {
+				for _, arg := range u.Call.Args {
+					add(arg, nil)
+				}
+			}
+			break
+		}
+		for _, fn := range e.index.Callees[u] {
+			for _, ret := range e.returns[fn] {
+				for _, res := range ret.Results {
+					add(res, ret)
+				}
+			}
+		}
+	case *ssa.Parameter:
+		fn := u.Parent()
+		var index int
+		for i, p := range fn.Params {
+			if p == u {
+				index = i
+			}
+		}
+		for _, site := range e.index.Callers[fn] {
+			common := site.Common()
+			args := common.Args
+			if common.IsInvoke() {
+				args = append([]ssa.Value{common.Value}, args...)
+			}
+			if index < len(args) {
+				add(args[index], site)
+			}
+		}
+	case *ssa.Capture:
+		fn := u.Parent()
+		for i, fv := range fn.FreeVars {
+			if fv == u {
+				for _, mc := range e.closures[fn] {
+					add(mc.Bindings[i], mc)
+				}
+			}
+		}
+	}
+	return preds
+}
+
+// storedInto returns the values that may be stored in the location
+// to which ptr points (or sent on the channel, or placed in the map).
+//
+func (e *explainer) storedInto(ptr ssa.Value) []flowPred {
+	var preds []flowPred
+	for _, instr := range e.index.Stores {
+		switch instr := instr.(type) {
+		case *ssa.Store:
+			if e.mayAlias(instr.Addr, ptr) {
+				preds = append(preds, flowPred{instr.Val, instr})
+			}
+		case *ssa.MapUpdate:
+			if e.mayAlias(instr.Map, ptr) {
+				preds = append(preds, flowPred{instr.Value, instr}, flowPred{instr.Key, instr})
+			}
+		case *ssa.Send:
+			if e.mayAlias(instr.Chan, ptr) {
+				preds = append(preds, flowPred{instr.X, instr})
+			}
+		case *ssa.Select:
+			for _, st := range instr.States {
+				if st.Send != nil && e.mayAlias(st.Chan, ptr) {
+					preds = append(preds, flowPred{st.Send, instr})
+				}
+			}
+		}
+	}
+	return preds
+}
+
+// describeFlow returns a description of the step by which an object
+// flows through the value or instruction x.  If via, x is the
+// instruction by which the object flows from one value to another.
+//
+func describeFlow(x interface{}, via bool) string {
+	switch x := x.(type) {
+	case *ssa.Alloc:
+		if x.Heap {
+			return "allocation"
+		}
+		return "local allocation"
+	case *ssa.MakeMap, *ssa.MakeChan, *ssa.MakeSlice:
+		return "make"
+	case *ssa.MakeClosure:
+		return "closure creation"
+	case *ssa.MakeInterface:
+		return "conversion to interface"
+	case *ssa.Global:
+		return "global " + x.Name()
+	case *ssa.Function:
+		return "function " + x.String()
+	case *ssa.Parameter:
+		return fmt.Sprintf("parameter %s of %s", x.Name(), x.Parent())
+	case *ssa.Capture:
+		return "free variable " + x.Name()
+	case *ssa.Phi:
+		return "merge of control flow"
+	case *ssa.FieldAddr, *ssa.IndexAddr:
+		return "address of element"
+	case *ssa.Field, *ssa.Index, *ssa.Extract:
+		return "element"
+	case *ssa.UnOp:
+		if x.Op == token.ARROW {
+			return "receive"
+		}
+		return "load"
+	case *ssa.Lookup:
+		return "map lookup"
+	case *ssa.Store:
+		return "store"
+	case *ssa.MapUpdate:
+		return "map update"
+	case *ssa.Send:
+		return "send"
+	case *ssa.Return:
+		return "return from " + x.Parent().String()
+	case ssa.CallInstruction:
+		if via {
+			return "argument of " + x.Common().Description()
+		}
+		return "result of " + x.Common().Description()
+	}
+	return "conversion"
+}
@@ -29,20 +29,41 @@ func referrers(o *Oracle, qpos *QueryPos) (queryResult, error) {
 		return nil, fmt.Errorf("no object for identifier")
 	}
 
-	// Iterate over all go/types' resolver facts for the entire program.
+	// Iterate over all go/types' resolver facts for the entire
+	// program, or just the packages selected by the filter.
 	var refs []token.Pos
+	pkgs := make(map[token.Pos]*types.Package) // package of each ref
 	for _, info := range o.typeInfo {
+		if !o.filter.allowsPackage(info.Pkg) {
+			continue
+		}
 		for id2, obj2 := range info.Objects {
 			if sameObj(obj, obj2) {
 				if id2.NamePos == obj.Pos() {
 					continue // skip defining ident
 				}
 				refs = append(refs, id2.NamePos)
+				pkgs[id2.NamePos] = info.Pkg
 			}
 		}
 	}
 	sort.Sort(byPos(refs))
 
+	// Apply the query's filter.
+	kept, i, j, err := o.filterResults(len(refs),
+		func(k int) *types.Package { return pkgs[refs[k]] },
+		func(k int) token.Pos { return refs[k] })
+	if err != nil {
+		return nil, err
+	}
+	if o.page != nil {
+		var page []token.Pos
+		for _, k := range kept[i:j] {
+			page = append(page, refs[k])
+		}
+		refs = page
+	}
+
 	return &referrersResult{
 		query: id.NamePos,
 		obj:   obj,
@@ -14,6 +14,14 @@ package serial
 // TODO(adonovan): consider richer encodings of types, functions,
 // methods, etc.
 
+// A Definition is the result of a 'definition' query.
+type Definition struct {
+	Pos      string   `json:"pos"`                // location of the query reference
+	ObjPos   string   `json:"objpos"`             // location of the definition
+	Desc     string   `json:"desc"`               // description of the denoted entity
+	Embedded []string `json:"embedded,omitempty"` // embedded fields traversed by a selection, if any
+}
+
 // A Peers is the result of a 'peers' query.
 // If Allocs is empty, the selected channel can't point to anything.
 type Peers struct {
@@ -64,6 +72,11 @@ type Caller struct {
 // Multiple nodes may have the same Name due to context-sensitive
 // treatment of some functions.
 //
+// If the result is filtered, the slice contains only the nodes that
+// satisfy the filter, and Children omits the others.  Indices are
+// relative to the start of the entire sequence of filtered nodes,
+// not to the start of the page reported by the Result.
+//
 // TODO(adonovan): perhaps include edge labels (i.e. callsites).
 type CallGraph struct {
 	Name     string `json:"name"`               // full name of function
@@ -104,6 +117,22 @@ type Implements struct {
 	CPos string `json:"cpos"` // location of its definition
 }
 
+// An ImplementsType is a type or method in the result of an
+// 'implements' query about a selected type or method.
+type ImplementsType struct {
+	Name string `json:"name"`          // full name of the type or method
+	Pos  string `json:"pos"`           // location of its definition
+	Via  string `json:"via,omitempty"` // for To, the type (T or *T) that implements it
+}
+
+// An ImplementsOf is the result of an 'implements' query about a
+// selected type or method.
+type ImplementsOf struct {
+	T    ImplementsType   `json:"type"`           // the selected type or method
+	To   []ImplementsType `json:"to,omitempty"`   // interfaces (or their methods) implemented by T
+	From []ImplementsType `json:"from,omitempty"` // concrete types (or methods) implementing T
+}
+
 // A DescribePTALabel describes a pointer analysis label.
 //
 // A "label" is an object that may be pointed to by a pointer, map,
@@ -192,11 +221,113 @@ type Describe struct {
 	Value   *DescribeValue   `json:"value,omitempty"`
 }
 
+// A TaintStep is one step along the path of a Taint.
+type TaintStep struct {
+	Pos  string `json:"pos"`  // location of the step
+	Desc string `json:"desc"` // description of the step, e.g. "store"
+}
+
+// A Taint is one element of the slice returned by a 'taint' query.
+// Each one indicates a flow of untrusted data from a source to a sink.
+type Taint struct {
+	Source string      `json:"source"` // description of the source
+	Sink   string      `json:"sink"`   // description of the sink
+	Path   []TaintStep `json:"path"`   // steps from source to sink
+}
+
+// A WhyStep is one step in the chain of assignments by which an
+// object flows from its allocation site to a pointer.
+type WhyStep struct {
+	Pos  string `json:"pos"`  // location of the step
+	Desc string `json:"desc"` // description of the step, e.g. "store"
+}
+
+// A PointsToLabel describes an object to which a pointer may point.
+type PointsToLabel struct {
+	Pos     string    `json:"pos"`               // location of syntax that allocated the object
+	Desc    string    `json:"desc"`              // description of the label
+	Context string    `json:"context,omitempty"` // function whose context allocated the object, if any
+	Path    string    `json:"path,omitempty"`    // access path to a subelement, e.g. ".x[*].y"
+	Why     []WhyStep `json:"why,omitempty"`     // flow from allocation to pointer ('whypointsto' only)
+}
+
+// A PointsTo is one element of the result of a 'pointsto' or
+// 'whypointsto' query.
+//
+// If the selected expression is an interface, there is one element
+// for each concrete type that it may contain, and Why (if requested)
+// explains the origin of a value of that type.  For each concrete
+// type that is a pointer, Labels describes the objects it may point
+// to.  Otherwise there is a single element describing the labels of
+// the selected pointer.
+//
+type PointsTo struct {
+	Type    string          `json:"type"`              // (concrete) type of the pointer
+	NamePos string          `json:"namepos,omitempty"` // location of type defn, if Named
+	Labels  []PointsToLabel `json:"labels,omitempty"`  // pointed-to objects
+	Why     []WhyStep       `json:"why,omitempty"`     // origin of the dynamic type ('whypointsto' only)
+}
+
+// A WhichErrsType is one of the dynamic types of a 'whicherrs' result.
+type WhichErrsType struct {
+	Type    string `json:"type"`              // the dynamic type
+	NamePos string `json:"namepos,omitempty"` // location of type defn, if Named
+}
+
+// A WhichErrs is the result of a 'whicherrs' query.
+// It contains the package-level variables of type error whose values
+// may flow to the selected error expression, and the dynamic types
+// that it may contain.
+type WhichErrs struct {
+	ErrPos  string          `json:"errpos"`            // location of the queried error expression
+	Globals []string        `json:"globals,omitempty"` // names of the error variables
+	Types   []WhichErrsType `json:"types,omitempty"`   // dynamic types of the error
+}
+
 type PTAWarning struct {
 	Pos     string `json:"pos"`     // location associated with warning
 	Message string `json:"message"` // warning message
 }
 
+// A ChanOp is an operation upon a channel in the result of a
+// 'channels' query.
+type ChanOp struct {
+	Kind string `json:"kind"` // "send", "receive", "close", "select send" or "select receive"
+	Pos  string `json:"pos"`  // location of the operation
+	Func string `json:"func"` // full name of the function containing it
+}
+
+// A ChanGoroutine is a goroutine, started by a go statement, that may
+// operate upon a channel.  The goroutine comprises the started
+// function and those it calls.
+type ChanGoroutine struct {
+	Func string `json:"func"` // full name of the started function
+	Pos  string `json:"pos"`  // location of the go statement
+}
+
+// A Chan is one element of the slice returned by a 'channels' query.
+// It describes the channels created by one make(chan) allocation site.
+type Chan struct {
+	Pos        string          `json:"pos"`                  // location of the make(chan)
+	Type       string          `json:"type"`                 // type of the channels
+	Func       string          `json:"func"`                 // full name of the function containing it
+	Ops        []ChanOp        `json:"ops,omitempty"`        // operations that may apply to the channels
+	Goroutines []ChanGoroutine `json:"goroutines,omitempty"` // goroutines that may perform them
+}
+
+// A Filter describes how the results of a query were restricted by
+// the oracle's filter options, and which page of them is reported.
+// The continuation token Next, if non-empty, may be supplied as the
+// starting point of a subsequent query to obtain the following page.
+type Filter struct {
+	Packages []string `json:"packages,omitempty"` // import path patterns of the packages reported
+	NoTests  bool     `json:"notests,omitempty"`  // results in _test.go files were excluded
+	Sorted   bool     `json:"sorted,omitempty"`   // results are sorted by position
+	Start    int      `json:"start"`              // index of the first reported result
+	Total    int      `json:"total"`              // number of results that satisfy the filter
+	Next     string   `json:"next,omitempty"`     // continuation token for the next page, if any
+}
+
 // A Result is the common result of any oracle query.
 // It contains a query-specific result element.
 //
@@ -207,15 +338,22 @@ type Result struct {
 
 	// Exactly one of the following fields is populated:
 	// the one specified by 'mode'.
-	Callees    *Callees      `json:"callees,omitempty"`
-	Callers    []Caller      `json:"callers,omitempty"`
-	Callgraph  []CallGraph   `json:"callgraph,omitempty"`
-	Callstack  *CallStack    `json:"callstack,omitempty"`
-	Describe   *Describe     `json:"describe,omitempty"`
-	Freevars   []*FreeVar    `json:"freevars,omitempty"`
-	Implements []*Implements `json:"implements,omitempty"`
-	Peers      *Peers        `json:"peers,omitempty"`
-	Referrers  *Referrers    `json:"referrers,omitempty"`
+	Callees      *Callees      `json:"callees,omitempty"`
+	Callers      []Caller      `json:"callers,omitempty"`
+	Callgraph    []CallGraph   `json:"callgraph,omitempty"`
+	Callstack    *CallStack    `json:"callstack,omitempty"`
+	Channels     []Chan        `json:"channels,omitempty"`
+	Definition   *Definition   `json:"definition,omitempty"`
+	Describe     *Describe     `json:"describe,omitempty"`
+	Freevars     []*FreeVar    `json:"freevars,omitempty"`
+	Implements   []*Implements `json:"implements,omitempty"`
+	ImplementsOf *ImplementsOf `json:"implementsof,omitempty"`
+	Peers        *Peers        `json:"peers,omitempty"`
+	PointsTo     []PointsTo    `json:"pointsto,omitempty"`
+	Referrers    *Referrers    `json:"referrers,omitempty"`
+	Taint        []*Taint      `json:"taint,omitempty"`
+	WhichErrs    *WhichErrs    `json:"whicherrs,omitempty"`
 
+	Filter   *Filter      `json:"filter,omitempty"`   // how the results were filtered, if at all
 	Warnings []PTAWarning `json:"warnings,omitempty"` // warnings from pointer analysis
 }
@@ -0,0 +1,233 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oracle
+
+// This file defines Server, which keeps an Oracle resident so that a
+// sequence of queries against the same program can be answered
+// without reloading it.  Its methods follow the net/rpc conventions,
+// so it can be served over any connection using JSON-RPC:
+//
+//	rpc.Register(oracle.NewServer(args, nil, &build.Default, true))
+//	jsonrpc.ServeConn(conn)
+//
+// Example request and response on the wire:
+//
+//	{"method": "Oracle.Query", "params": [{"mode": "callers", "pos": "foo.go:#123"}], "id": 1}
+//	{"id": 1, "result": {"mode": "callers", "callers": [...]}, "error": null}
+//
+//	{"method": "Oracle.Changed", "params": [{"files": ["/home/me/foo.go"]}], "id": 2}
+//	{"id": 2, "result": {"reloaded": true}, "error": null}
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"io"
+	"path/filepath"
+	"sync"
+
+	"code.google.com/p/go.tools/importer"
+	"code.google.com/p/go.tools/oracle/serial"
+	"code.google.com/p/go.tools/refactor/util"
+)
+
+// A Server answers oracle queries about a fixed analysis scope,
+// retaining the parsed, type-checked and SSA form of the program (and
+// the call graph, once computed) between queries.  When files change,
+// only the packages they affect are parsed and type-checked again.
+//
+// The methods of a Server may be called concurrently; queries are
+// answered one at a time.
+//
+type Server struct {
+	args         []string
+	ptalog       io.Writer
+	buildContext *build.Context
+	reflection   bool
+
+	mu     sync.Mutex
+	imp    *importer.Importer // importer of the last loaded program; nil => never loaded
+	oracle *Oracle            // oracle for the loaded program; nil => not loaded
+	files  []string           // absolute names of the source files of the last loaded program
+	stale  map[string]bool    // directories of the packages changed since the last load
+}
+
+// NewServer returns a server for queries about the program specified
+// by args, in importer.CreatePackageFromArgs syntax.  The program is
+// loaded upon the first query.
+//
+// ptalog is the (optional) pointer-analysis log file.
+// buildContext is the go/build configuration for locating packages.
+// reflection determines whether to model reflection soundly (currently slow).
+//
+func NewServer(args []string, ptalog io.Writer, buildContext *build.Context, reflection bool) *Server {
+	return &Server{
+		args:         args,
+		ptalog:       ptalog,
+		buildContext: buildContext,
+		reflection:   reflection,
+	}
+}
+
+// QueryArgs holds the arguments of a Server.Query request.
+type QueryArgs struct {
+	Mode   string  `json:"mode"`             // query mode ("callers", etc)
+	Pos    string  `json:"pos"`              // query position, e.g. "foo.go:#123,#456"
+	Filter *Filter `json:"filter,omitempty"` // restricts the results of the query; optional
+}
+
+// Query runs the query specified by args and sets *reply to its
+// result, in the form used by the oracle's -format=json output.
+//
+func (s *Server) Query(args *QueryArgs, reply *serial.Result) error {
+	minfo := findMode(args.Mode)
+	if minfo == nil {
+		return fmt.Errorf("invalid mode type: %q", args.Mode)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	var qpos *QueryPos
+	if minfo.needs&(needPos|needExactPos) != 0 {
+		var err error
+		qpos, err = ParseQueryPos(s.imp, args.Pos, minfo.needs&needExactPos != 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	res, err := s.oracle.query(minfo, qpos, args.Filter)
+	if err != nil {
+		return err
+	}
+	*reply = *res.Serial()
+	return nil
+}
+
+// ChangedArgs holds the arguments of a Server.Changed request.
+type ChangedArgs struct {
+	Files []string `json:"files"` // names of the changed files
+}
+
+// ChangedReply holds the result of a Server.Changed request.
+type ChangedReply struct {
+	Reloaded bool `json:"reloaded"` // the loaded program was discarded; the next query rebuilds it
+}
+
+// Changed notifies the server that the specified files have been
+// modified, created or deleted.  If any of them belongs to a package
+// of the loaded program, or is a new file in the directory of one,
+// that package is marked stale, and the SSA form and analyses of the
+// program are discarded.  The next query reloads only the stale
+// packages and those that import them, directly or indirectly,
+// reusing the parsed and type-checked form of the rest.
+//
+// Reloaded is set only if a loaded program was discarded; if none is
+// loaded, there is nothing to discard.
+//
+func (s *Server) Changed(args *ChangedArgs, reply *ChangedReply) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reply.Reloaded = false
+	if s.imp == nil {
+		return nil // never loaded
+	}
+	for _, file := range args.Files {
+		dirs, err := s.affected(file)
+		if err == nil && dirs == nil {
+			continue // not in the program
+		}
+		if s.oracle != nil {
+			s.oracle = nil
+			reply.Reloaded = true
+		}
+		if err != nil {
+			// Conservatively discard the whole program.
+			s.imp = nil
+			s.files = nil
+			s.stale = nil
+			break
+		}
+		for _, dir := range dirs {
+			s.stale[dir] = true
+		}
+	}
+	return nil
+}
+
+// affected returns the directories of the packages of the last loaded
+// program that a change to the named file may affect: those of which
+// it is a source file, or in whose directory it is a new file.
+//
+func (s *Server) affected(file string) ([]string, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(abs)
+	var dirs []string
+	for _, f := range s.files {
+		if f == abs || filepath.Dir(f) == dir || util.SameFile(f, abs) {
+			dirs = append(dirs, filepath.Dir(f))
+		}
+	}
+	return dirs, nil
+}
+
+// isStale reports whether the package info has been marked stale by
+// a call to Changed since the last load.
+// Precondition: s.mu is held.
+func (s *Server) isStale(info *importer.PackageInfo) bool {
+	for _, f := range info.Files {
+		if s.stale[filepath.Dir(s.filename(f))] {
+			return true
+		}
+	}
+	return false
+}
+
+// filename returns the absolute name of the file f of the last loaded
+// program, if it can be determined.
+// Precondition: s.mu is held.
+func (s *Server) filename(f *ast.File) string {
+	name := s.imp.Fset.File(f.Pos()).Name()
+	if abs, err := filepath.Abs(name); err == nil {
+		name = abs
+	}
+	return name
+}
+
+// load loads the program, if it is not already loaded.
+// Precondition: s.mu is held.
+func (s *Server) load() error {
+	if s.oracle != nil {
+		return nil
+	}
+	imp := importer.New(&importer.Config{Build: s.buildContext})
+	if s.imp != nil {
+		imp.Reuse(s.imp, s.isStale)
+	}
+	o, err := New(imp, s.args, s.ptalog, s.reflection)
+	if err != nil {
+		return err // (retain s.imp and s.stale for the next attempt)
+	}
+
+	s.imp = imp
+	s.oracle = o
+	s.stale = make(map[string]bool)
+	s.files = nil
+	for _, info := range imp.AllPackages() {
+		for _, f := range info.Files {
+			s.files = append(s.files, s.filename(f))
+		}
+	}
+	return nil
+}
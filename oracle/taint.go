@@ -0,0 +1,66 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oracle
+
+import (
+	"go/token"
+	"time"
+
+	"code.google.com/p/go.tools/oracle/serial"
+	"code.google.com/p/go.tools/taint"
+)
+
+// taintFlows reports the flows of untrusted data to vulnerable sinks
+// within the entire program, using the default specification of
+// sources, sinks and sanitizers (taint.DefaultSpec).
+//
+// TODO(adonovan): allow the user to provide a specification.
+//
+func taintFlows(o *Oracle, _ *QueryPos) (queryResult, error) {
+	buildSSA(o)
+
+	start := time.Now()
+	flows := taint.Analyze(&o.config, taint.DefaultSpec)
+	o.timers["taint analysis"] = time.Since(start)
+
+	return &taintResult{flows}, nil
+}
+
+type taintResult struct {
+	flows []*taint.Flow
+}
+
+func (r *taintResult) display(printf printfFunc) {
+	if len(r.flows) == 0 {
+		printf(nil, "No flows of untrusted data found.")
+		return
+	}
+	printf(nil, "%d flow(s) of untrusted data found.", len(r.flows))
+	for _, f := range r.flows {
+		sink := f.Path[len(f.Path)-1]
+		printf(sink.Pos, "%s", f)
+		for _, step := range f.Path {
+			printf(step.Pos, "\t%s", step.Desc)
+		}
+	}
+}
+
+func (r *taintResult) toSerial(res *serial.Result, fset *token.FileSet) {
+	var flows []*serial.Taint
+	for _, f := range r.flows {
+		j := &serial.Taint{
+			Source: f.Source.String(),
+			Sink:   f.Sink.String(),
+		}
+		for _, step := range f.Path {
+			j.Path = append(j.Path, serial.TaintStep{
+				Pos:  fset.Position(step.Pos).String(),
+				Desc: step.Desc,
+			})
+		}
+		flows = append(flows, j)
+	}
+	res.Taint = flows
+}
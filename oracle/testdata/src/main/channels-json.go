@@ -0,0 +1,15 @@
+package main
+
+// Tests of 'channels' queries, -format=json.
+// See go.tools/oracle/oracle_test.go for explanation.
+// See channels-json.golden for expected query results.
+
+func main() {
+	ch := make(chan int, 1)
+	go func() {
+		ch <- 1
+	}()
+	<-ch
+}
+
+// @channels channels-json "^"
@@ -0,0 +1,39 @@
+package main
+
+// Tests of 'channels' queries.
+// See go.tools/oracle/oracle_test.go for explanation.
+// See channels.golden for expected query results.
+
+func produce(out chan<- int) {
+	for i := 0; i < 3; i++ {
+		out <- i
+	}
+	close(out)
+}
+
+func consume(in <-chan int, done chan bool) {
+	for _ = range in {
+	}
+	signal(done)
+}
+
+func signal(done chan bool) {
+	done <- true
+}
+
+func main() {
+	data := make(chan int)
+	done := make(chan bool)
+	unused := make(chan string)
+	_ = unused
+
+	go produce(data)
+	go consume(data, done)
+
+	select {
+	case <-done:
+	case data <- 0:
+	}
+}
+
+// @channels channels "^"
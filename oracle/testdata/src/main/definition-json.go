@@ -0,0 +1,17 @@
+package definition
+
+// Tests of 'definition' queries, -format=json.
+// See go.tools/oracle/oracle_test.go for explanation.
+// See definition-json.golden for expected query results.
+
+import "lib"
+
+type T struct{ *lib.Type }
+
+type U struct{ T }
+
+func main() {
+	var u U
+	u.Method(nil) // @definition def-json-promoted "Method"
+	_ = u         // @definition def-json-local "u"
+}
@@ -0,0 +1,41 @@
+package definition
+
+// Tests of 'definition' queries.
+// See go.tools/oracle/oracle_test.go for explanation.
+// See definition.golden for expected query results.
+
+import (
+	"lib"      // @definition def-import "lib"
+	lib2 "lib" // @definition def-import-renamed "lib2"
+)
+
+type T struct {
+	E
+	*P
+	f int
+}
+
+type E struct{ g int }
+
+func (E) m() {}
+
+type P struct{}
+
+func (*P) n() {}
+
+func main() {
+	var t T
+	_ = t.f        // @definition def-field "f"
+	_ = t.g        // @definition def-promoted-field "g"
+	t.m()          // @definition def-promoted-method "m"
+	t.n()          // @definition def-promoted-ptr-method "n"
+	_ = t.E        // @definition def-embedded "E"
+	lib.Func()     // @definition def-pkg "lib"
+	lib.Func()     // @definition def-qualified "Func"
+	var x lib.Type // @definition def-lib-type "Type"
+	x.Method(nil)  // @definition def-lib-method "Method"
+	lib2.Var++     // @definition def-renamed-qualified "Var"
+	_ = T.m        // @definition def-method-expr "m"
+	print(x)       // @definition def-builtin "print"
+	_ = 1 + 2      // @definition def-none "[+]"
+}
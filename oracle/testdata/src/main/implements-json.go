@@ -0,0 +1,16 @@
+package main
+
+// Tests of 'implements' query, -output=json.
+// See go.tools/oracle/oracle_test.go for explanation.
+// See implements-json.golden for expected query results.
+
+func main() {
+}
+
+type I interface { // @implements I "I"
+	f()
+}
+
+type T struct{}
+
+func (*T) f() {} // @implements T.f "\\bf\\b"
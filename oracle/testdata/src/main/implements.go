@@ -4,6 +4,8 @@ package main
 // See go.tools/oracle/oracle_test.go for explanation.
 // See implements.golden for expected query results.
 
+import "lib"
+
 // @implements impl ""
 
 func main() {
@@ -11,19 +13,26 @@ func main() {
 
 type E interface{}
 
-type F interface {
-	f()
+type F interface { // @implements F "F"
+	f() // @implements F.f "f"
 }
 
-type FG interface {
+type FG interface { // @implements FG "FG"
 	f()
 	g() int
 }
 
-type C int
+type C int // @implements C "C"
 type D struct{}
 
 func (c *C) f() {}
-func (d D) f()  {}
+func (d D) f()  {} // @implements D.f "\\bf\\b"
 
 func (d *D) g() int { return 0 }
+
+// An interface implemented by a type of another package.
+type I interface { // @implements I "I"
+	Method(*int) *int
+}
+
+var _ lib.Type
@@ -0,0 +1,36 @@
+package library
+
+// Tests of queries within a library package, i.e. one with no main
+// function, whose exported functions and methods are the entry points.
+// See go.tools/oracle/oracle_test.go for explanation.
+// See library.golden for expected query results.
+
+type T struct {
+	next *T
+}
+
+func (t *T) Next() *T {
+	return t.next // @describe describe-next "t.next"
+}
+
+func NewT() *T {
+	return &T{}
+}
+
+func Link(tail *T) *T {
+	u := NewT()
+	u.next = tail // @describe describe-tail "tail"
+	return helper(u)
+}
+
+func helper(t *T) *T { // @callers callers-helper "helper"
+	return t
+}
+
+func Send(ch chan *T, t *T) {
+	ch <- t // @peers peers-send "<-"
+}
+
+func Recv(ch chan *T) *T {
+	return <-ch // @peers peers-recv "<-"
+}
@@ -0,0 +1,13 @@
+package pointsto
+
+// Tests of 'pointsto' and 'whypointsto' queries, -format=json.
+// See go.tools/oracle/oracle_test.go for explanation.
+// See pointsto-json.golden for expected query results.
+
+func main() {
+	x := new(int)
+	ch := make(chan *int, 1)
+	ch <- x
+	y := <-ch
+	_ = y // @whypointsto why-json "y"
+}
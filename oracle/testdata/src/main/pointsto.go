@@ -0,0 +1,51 @@
+package pointsto
+
+// Tests of 'pointsto' and 'whypointsto' queries.
+// See go.tools/oracle/oracle_test.go for explanation.
+// See pointsto.golden for expected query results.
+
+type I interface {
+	f()
+}
+
+type C int
+
+func (C) f() {}
+
+type D struct{ ptr *int }
+
+func (D) f() {}
+
+type S struct{ x, y int }
+
+var global = new(int)
+
+func id(p *int) *int { return p }
+
+func main() {
+	a := new(int)
+	b := id(a)
+	_ = b // @pointsto ptsto-b "b"
+
+	var s S
+	_ = &s.y // @pointsto ptsto-field "&s.y"
+
+	d := D{id(global)}
+	var i I = d
+	if a != nil {
+		i = C(0)
+	}
+	_ = i // @pointsto ptsto-iface "i"
+
+	m := make(map[string]*int)
+	m["a"] = b
+	c := m["b"]
+	_ = c // @whypointsto why-c "c"
+
+	var j I = d
+	_ = j // @whypointsto why-iface "j"
+
+	_ = global // @pointsto ptsto-global "global"
+
+	_ = s.x // @pointsto ptsto-int "s.x"
+}
@@ -0,0 +1,24 @@
+package main
+
+// Tests of 'taint' queries.
+// See go.tools/oracle/oracle_test.go for explanation.
+// See taint.golden for expected query results.
+
+import (
+	"net/http"
+	"os"
+	"os/exec"
+)
+
+func handler(w http.ResponseWriter, r *http.Request) {
+	exec.Command("sh", "-c", r.URL.RawQuery).Run() // flow
+	exec.Command("date").Run()                     // no flow
+}
+
+func main() {
+	http.HandleFunc("/", handler)
+	exec.Command(os.Args[1]).Run() // flow
+	http.ListenAndServe(":8080", nil)
+}
+
+// @taint taint "^"
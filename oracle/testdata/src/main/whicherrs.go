@@ -0,0 +1,48 @@
+package whicherrs
+
+// Tests of 'whicherrs' queries.
+// See go.tools/oracle/oracle_test.go for explanation.
+// See whicherrs.golden for expected query results.
+
+type errorString struct{ s string }
+
+func (e *errorString) Error() string { return e.s }
+
+func newError(s string) error { return &errorString{s} }
+
+var (
+	EOF      = newError("EOF")
+	ErrShort = newError("short")
+	ErrOther = newError("other")
+)
+
+type MyError struct{}
+
+func (MyError) Error() string { return "my error" }
+
+func read(n int) error {
+	switch n {
+	case 0:
+		return EOF
+	case 1:
+		return ErrShort
+	case 2:
+		return MyError{}
+	}
+	return nil
+}
+
+func main() {
+	err := read(0)
+	if err != nil { // @whicherrs whicherrs-read "err"
+		print(err.Error())
+	}
+
+	var other error = ErrOther
+	_ = other // @whicherrs whicherrs-other "other"
+
+	var none error
+	_ = none // @whicherrs whicherrs-none "none"
+
+	_ = err.Error() // @whicherrs whicherrs-notanerror "err.Error.."
+}
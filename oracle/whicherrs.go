@@ -0,0 +1,161 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oracle
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+
+	"code.google.com/p/go.tools/go/types"
+	"code.google.com/p/go.tools/oracle/serial"
+	"code.google.com/p/go.tools/pointer"
+	"code.google.com/p/go.tools/ssa"
+)
+
+var builtinErrorType = types.Universe.Lookup("error").Type()
+
+// whicherrs takes a position of an expression of type error, and
+// reports the package-level variables of type error (e.g. io.EOF)
+// whose values may flow to it, and the dynamic types it may contain.
+//
+// TODO(adonovan): report constants of named error types too.
+//
+func whicherrs(o *Oracle, qpos *QueryPos) (queryResult, error) {
+	path := qpos.path
+	expr, _ := path[0].(ast.Expr)
+	if expr == nil {
+		return nil, fmt.Errorf("whicherrs wants an expression of type error")
+	}
+	if id, ok := expr.(*ast.Ident); ok {
+		if _, ok := qpos.info.ObjectOf(id).(*types.Var); !ok {
+			return nil, fmt.Errorf("whicherrs wants an expression of type error")
+		}
+	}
+	typ := qpos.info.TypeOf(expr)
+	if !types.IsIdentical(typ, builtinErrorType) {
+		return nil, fmt.Errorf("whicherrs wants an expression of type error; got %s", typ)
+	}
+
+	var value ssa.Value
+	var err error
+	if id, ok := expr.(*ast.Ident); ok {
+		value, err = ssaValueForIdent(o.prog, qpos.info, qpos.info.ObjectOf(id), path)
+	} else {
+		value, err = ssaValueForExpr(o.prog, qpos.info, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		// The variable's value here is a constant, and the
+		// only constant of type error is nil.
+		return &whicherrsResult{qpos: qpos}, nil
+	}
+	indirect := types.IsIdentical(types.NewPointer(typ), value.Type())
+
+	buildSSA(o)
+
+	// Query the error value and each package-level
+	// variable of type error, e.g. io.EOF.
+	o.config.Queries = map[ssa.Value]pointer.Indirect{value: pointer.Indirect(indirect)}
+	var globals []*ssa.Global
+	for _, pkg := range o.prog.AllPackages() {
+		for _, mem := range pkg.Members {
+			if g, ok := mem.(*ssa.Global); ok && types.IsIdentical(deref(g.Type()), builtinErrorType) {
+				globals = append(globals, g)
+				o.config.Queries[g] = true
+			}
+		}
+	}
+	ptares := ptrAnalysis(o)
+
+	pointers := ptares.Queries[value]
+	if pointers == nil {
+		return nil, fmt.Errorf("pointer analysis did not encounter this expression (dead code?)")
+	}
+	pts := pointer.PointsToCombined(pointers)
+
+	// A global may flow to the error value if their
+	// points-to sets (of tagged objects) intersect.
+	res := &whicherrsResult{qpos: qpos}
+	for _, g := range globals {
+		if ptrs := ptares.Queries[g]; ptrs != nil && pointer.PointsToCombined(ptrs).Intersects(pts) {
+			res.globals = append(res.globals, g)
+		}
+	}
+	sort.Sort(globalsByPos(res.globals))
+
+	pts.DynamicTypes().Iterate(func(conc types.Type, _ interface{}) {
+		res.types = append(res.types, conc)
+	})
+	sort.Sort(typesByString(res.types))
+
+	return res, nil
+}
+
+type whicherrsResult struct {
+	qpos    *QueryPos
+	globals []*ssa.Global // package-level variables whose values may flow here
+	types   []types.Type  // dynamic types of the error value
+}
+
+func (r *whicherrsResult) display(printf printfFunc) {
+	if len(r.globals) > 0 {
+		printf(r.qpos, "this error may point to these globals:")
+		for _, g := range r.globals {
+			printf(g, "\t%s", g)
+		}
+	}
+	if len(r.types) > 0 {
+		printf(r.qpos, "this error may contain these dynamic types:")
+		for _, t := range r.types {
+			var obj types.Object
+			if nt, ok := deref(t).(*types.Named); ok {
+				obj = nt.Obj()
+			}
+			printf(obj, "\t%s", t)
+		}
+	}
+	if len(r.globals) == 0 && len(r.types) == 0 {
+		printf(r.qpos, "this error cannot contain any values.")
+	}
+}
+
+func (r *whicherrsResult) toSerial(res *serial.Result, fset *token.FileSet) {
+	we := &serial.WhichErrs{
+		ErrPos: fset.Position(r.qpos.start).String(),
+	}
+	for _, g := range r.globals {
+		we.Globals = append(we.Globals, g.String())
+	}
+	for _, t := range r.types {
+		var namePos string
+		if nt, ok := deref(t).(*types.Named); ok {
+			namePos = fset.Position(nt.Obj().Pos()).String()
+		}
+		we.Types = append(we.Types, serial.WhichErrsType{
+			Type:    t.String(),
+			NamePos: namePos,
+		})
+	}
+	res.WhichErrs = we
+}
+
+type globalsByPos []*ssa.Global
+
+func (a globalsByPos) Len() int { return len(a) }
+func (a globalsByPos) Less(i, j int) bool {
+	cmp := a[i].Pos() - a[j].Pos()
+	return cmp < 0 || (cmp == 0 && a[i].String() < a[j].String())
+}
+func (a globalsByPos) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+
+type typesByString []types.Type
+
+func (a typesByString) Len() int           { return len(a) }
+func (a typesByString) Less(i, j int) bool { return a[i].String() < a[j].String() }
+func (a typesByString) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
@@ -12,6 +12,7 @@ import (
 	"io"
 	"os"
 	"reflect"
+	"time"
 
 	"code.google.com/p/go.tools/go/types"
 	"code.google.com/p/go.tools/go/types/typemap"
@@ -83,6 +84,13 @@ type node struct {
 	// an object of aggregate type (struct, tuple, array) this is.
 	subelement *fieldInfo // e.g. ".a.b[*].c"
 
+	// rep is the representative of the equivalence class of nodes
+	// to which this node belongs; see analysis.find.  The
+	// optimisations merge nodes that must have equal points-to
+	// sets, whereupon the remaining fields of this struct are
+	// meaningful only for the representative.
+	rep nodeid
+
 	// Points-to sets.
 	pts     nodeset // points-to set of this node
 	prevPts nodeset // pts(n) in previous iteration (for difference propagation)
@@ -106,9 +114,16 @@ type constraint interface {
 	// to which it is attached.
 	ptr() nodeid
 
+	// presolve is called by the HVN pre-solver to inform it of
+	// the effect of the constraint; typically, it marks as
+	// indirect the nodes to which the solver will add edges or
+	// labels.
+	presolve(h *hvn)
+
 	// solve is called for complex constraints when the pts for
-	// the node to which they are attached has changed.
-	solve(a *analysis, n *node, delta nodeset)
+	// the node to which they are attached has changed.  delta
+	// holds the new labels, in ascending order.
+	solve(a *analysis, n *node, delta []nodeid)
 }
 
 // dst = &src
@@ -183,7 +198,14 @@ type analysis struct {
 	globalobj   map[ssa.Value]nodeid        // maps v to sole member of pts(v), if singleton
 	localval    map[ssa.Value]nodeid        // node for each local ssa.Value
 	localobj    map[ssa.Value]nodeid        // maps v to sole member of pts(v), if singleton
+	contours    map[contourKey]nodeid       // context-sensitive function objects
+	callStrings map[callString]*callString  // interned call strings
+	unknowns    typemap.M                   // nodeids of canonical objects of unknown origin for pointerlike type T
 	work        worklist                    // solver's worklist
+	hcd         map[nodeid]nodeid           // HCD: maps pointer p to node unified with all of pts(p)
+	deltaSpace  []nodeid                    // working space for solveConstraints
+	lcdChecked  map[[2]nodeid]bool          // LCD: copy edges already checked for cycles
+	stats       stats                       // statistics, for benchmarking
 	result      *Result                     // results of the analysis
 
 	// Reflection:
@@ -225,21 +247,40 @@ func (a *analysis) warnf(pos token.Pos, format string, args ...interface{}) {
 	a.result.Warnings = append(a.result.Warnings, Warning{pos, fmt.Sprintf(format, args...)})
 }
 
+// stats records the size of the constraint system and the effect of
+// the optimisations upon it.
+type stats struct {
+	nodes        int           // number of nodes after constraint generation
+	constraints  int           // number of constraints generated
+	presolved    int           // number of constraints remaining after pre-solving
+	hvnMerged    int           // number of nodes merged by HVN
+	hcdMerged    int           // number of nodes merged by HCD
+	lcdMerged    int           // number of nodes merged by LCD
+	presolveTime time.Duration // time spent in the pre-solver
+	solveTime    time.Duration // time spent in the solver
+}
+
 // Analyze runs the pointer analysis with the scope and options
 // specified by config, and returns the (synthetic) root of the callgraph.
 //
 func Analyze(config *Config) *Result {
+	return analyze(config).result
+}
+
+func analyze(config *Config) *analysis {
 	a := &analysis{
 		config:      config,
 		log:         config.Log,
 		prog:        config.prog(),
 		globalval:   make(map[ssa.Value]nodeid),
 		globalobj:   make(map[ssa.Value]nodeid),
+		contours:    make(map[contourKey]nodeid),
+		callStrings: make(map[callString]*callString),
 		flattenMemo: make(map[types.Type][]*fieldInfo),
 		hasher:      typemap.MakeHasher(),
 		intrinsics:  make(map[*ssa.Function]intrinsic),
 		probes:      make(map[*ssa.CallCommon]nodeid),
-		work:        makeMapWorklist(),
+		work:        makeWorklist(config.Worklist),
 		result: &Result{
 			Queries: make(map[ssa.Value][]Pointer),
 		},
@@ -267,6 +308,8 @@ func Analyze(config *Config) *Result {
 		a.reflectZeros.SetHasher(a.hasher)
 	}
 
+	a.unknowns.SetHasher(a.hasher)
+
 	root := a.generate()
 
 	if a.log != nil {
@@ -282,26 +325,38 @@ func Analyze(config *Config) *Result {
 		fmt.Fprintf(a.log, "# nodes:\t%d\n", len(a.nodes))
 	}
 
-	//a.optimize()
+	a.stats.nodes = len(a.nodes)
+	a.stats.constraints = len(a.constraints)
+
+	start := time.Now()
+	a.optimize()
+	a.stats.presolved = len(a.constraints)
+	a.stats.presolveTime = time.Since(start)
 
+	start = time.Now()
 	a.solve()
+	a.stats.solveTime = time.Since(start)
 
 	if a.log != nil {
 		// Dump solution.
 		for i, n := range a.nodes {
-			if n.pts != nil {
-				fmt.Fprintf(a.log, "pts(n%d) = %s : %s\n", i, n.pts, n.typ)
+			if pts := a.nodes[a.find(nodeid(i))].pts; !pts.isEmpty() {
+				fmt.Fprintf(a.log, "pts(n%d) = %s : %s\n", i, pts, n.typ)
 			}
 		}
+		fmt.Fprintf(a.log, "# nodes merged:\tHVN %d, HCD %d, LCD %d\n",
+			a.stats.hvnMerged, a.stats.hcdMerged, a.stats.lcdMerged)
 	}
 
 	// Visit discovered call graph.
 	for _, caller := range a.cgnodes {
+		record := a.config.BuildCallGraph &&
+			(a.config.CallGraphFilter == nil || a.config.CallGraphFilter(caller.fn))
 		for _, site := range caller.sites {
-			for nid := range a.nodes[site.targets].pts {
+			for _, nid := range a.nodes[a.find(site.targets)].pts.appendTo(nil) {
 				callee := a.nodes[nid].obj.cgn
 
-				if a.config.BuildCallGraph {
+				if record {
 					site.callees = append(site.callees, callee)
 				}
 
@@ -319,5 +374,5 @@ func Analyze(config *Config) *Result {
 		a.result.CallGraph = &cgraph{root, a.cgnodes}
 	}
 
-	return a.result
+	return a
 }
@@ -18,20 +18,42 @@ import (
 type Config struct {
 	// Mains contains the set of 'main' packages to analyze
 	// Clients must provide the analysis with at least one
-	// package defining a main() function.
+	// package defining a main() function, or at least one
+	// library package (see Libraries).
 	Mains []*ssa.Package
 
+	// Libraries contains a set of packages to analyze as
+	// libraries, i.e. without a main() function.  The analysis
+	// treats the initializer and all exported functions and
+	// methods of each library package as entry points, called
+	// from the root of the callgraph by an unknown caller.
+	//
+	// Each pointer passed to an entry point (including its
+	// receiver) points to a canonical object of unknown origin
+	// for its type, whose pointers in turn point to such objects.
+	// A value of non-empty interface type may hold an object of
+	// unknown origin of each type of the program that implements
+	// it.  Functions and empty interface values of unknown origin
+	// are not modelled, and are reported by a warning.
+	//
+	Libraries []*ssa.Package
+
 	// Reflection determines whether to handle reflection
 	// operators soundly, which is currently rather slow since it
 	// causes constraint to be generated during solving
-	// proportional to the number of constraint variables, which
-	// has not yet been reduced by presolver optimisation.
+	// proportional to the number of constraint variables.
 	Reflection bool
 
 	// BuildCallGraph determines whether to construct a callgraph.
 	// If enabled, the graph will be available in Result.CallGraph.
 	BuildCallGraph bool
 
+	// CallGraphFilter, if non-nil, restricts the callgraph to the
+	// call sites within the functions for which it returns true;
+	// no edges are recorded for the call sites of other functions.
+	// The analysis itself must still consider the whole program.
+	CallGraphFilter func(fn *ssa.Function) bool
+
 	// Print is invoked during the analysis for each discovered
 	// call to the built-in print(x), providing a convenient way
 	// to identify arbitrary expressions of interest in the tests.
@@ -66,14 +88,105 @@ type Config struct {
 	// If Log is non-nil, log messages are written to it.
 	// Logging is extremely verbose.
 	Log io.Writer
+
+	// DisableOpts is the set of solver optimisations to disable,
+	// which is useful for benchmarking and debugging.  The zero
+	// value enables all of them.  The optimisations affect only
+	// the running time and space of the analysis, not its result.
+	DisableOpts Opt
+
+	// Worklist determines the order in which the solver visits
+	// nodes whose points-to sets have changed.  Like DisableOpts,
+	// it affects only the running time of the analysis.
+	Worklist WorklistOrder
+
+	// Context determines which calls are analysed
+	// context-sensitively, trading running time for precision.
+	// The zero value selects the default policy.
+	Context ContextPolicy
+}
+
+// A ContextPolicy determines the context sensitivity of the analysis.
+//
+// Each statically dispatched call to a function for which Sensitive
+// holds is analysed in a context (a distinct call graph node, or
+// "contour") determined by K and Objects; all other calls to the
+// function share a single context.  Dynamic calls and calls through
+// interfaces always use the shared context.
+//
+// The context of a Pointer or Label is available from its Context
+// method.
+//
+type ContextPolicy struct {
+	// Sensitive reports whether static calls to fn should be
+	// analysed context-sensitively.  If nil, the default policy
+	// selects synthetic wrappers and short, call-free functions
+	// such as accessor methods.
+	//
+	// Intrinsics (functions with built-in summaries) are always
+	// treated context-sensitively.
+	Sensitive func(fn *ssa.Function) bool
+
+	// K is the number of call sites in the call string that
+	// identifies each context (k-call-site sensitivity): calls
+	// whose last K call sites are the same share a context.
+	//
+	// If K is zero and Sensitive is nil, each call site within
+	// each context has its own context, with no limit on the
+	// length of the call string; this terminates only because
+	// the default policy rejects functions that make calls.
+	// If K is zero and Sensitive is non-nil, K is taken to be 1.
+	K int
+
+	// Objects enables object sensitivity for method calls: calls
+	// to a method whose receiver points to a single, statically
+	// known object allocated outside any context (e.g. a global,
+	// or a local of a shared context) share a context for that
+	// object.  Other method calls fall back to K.
+	Objects bool
 }
 
+// An Opt is a set of optional optimisations of the constraint solver.
+type Opt uint
+
+const (
+	// OptHVN enables offline variable substitution by hash-based
+	// value numbering (HVN, with the HRU refinement): nodes
+	// proven pointer-equivalent before solving are merged.
+	OptHVN Opt = 1 << iota
+
+	// OptHCD enables hybrid cycle detection: cycles through
+	// dereferences found offline are collapsed during solving.
+	OptHCD
+
+	// OptLCD enables lazy cycle detection: cycles of copy edges
+	// are detected and collapsed during solving.
+	OptLCD
+
+	OptAll = OptHVN | OptHCD | OptLCD
+)
+
+// A WorklistOrder determines the order in which the solver visits
+// the nodes on its worklist.
+type WorklistOrder int
+
+const (
+	MapWorklist    WorklistOrder = iota // unspecified order, using a map (the default)
+	FIFOWorklist                        // insertion order
+	LIFOWorklist                        // reverse insertion order
+	NodeIDWorklist                      // ascending node id order
+	LRFWorklist                         // least recently fired node first
+)
+
 type Indirect bool // map[ssa.Value]Indirect is not a set
 
 func (c *Config) prog() *ssa.Program {
 	for _, main := range c.Mains {
 		return main.Prog
 	}
+	for _, lib := range c.Libraries {
+		return lib.Prog
+	}
 	panic("empty scope")
 }
 
@@ -165,7 +278,7 @@ type ptset struct {
 
 func (s ptset) Labels() []*Label {
 	var labels []*Label
-	for l := range s.pts {
+	for _, l := range s.pts.appendTo(nil) {
 		labels = append(labels, s.a.labelFor(l))
 	}
 	return labels
@@ -174,7 +287,7 @@ func (s ptset) Labels() []*Label {
 func (s ptset) DynamicTypes() *typemap.M {
 	var tmap typemap.M
 	tmap.SetHasher(s.a.hasher)
-	for ifaceObjId := range s.pts {
+	for _, ifaceObjId := range s.pts.appendTo(nil) {
 		tDyn, v, indirect := s.a.taggedValue(ifaceObjId)
 		if tDyn == nil {
 			continue // !CanHaveDynamicTypes(tDyn)
@@ -190,12 +303,7 @@ func (s ptset) DynamicTypes() *typemap.M {
 
 func (x ptset) Intersects(y_ PointsToSet) bool {
 	y := y_.(ptset)
-	for l := range x.pts {
-		if _, ok := y.pts[l]; ok {
-			return true
-		}
-	}
-	return false
+	return x.pts.intersects(y.pts)
 }
 
 // ---- Pointer public interface
@@ -212,11 +320,14 @@ func (p ptr) String() string {
 }
 
 func (p ptr) Context() call.GraphNode {
+	if p.cgn == nil {
+		return nil // (not a nil *cgnode)
+	}
 	return p.cgn
 }
 
 func (p ptr) PointsTo() PointsToSet {
-	return ptset{p.a, p.a.nodes[p.n].pts}
+	return ptset{p.a, p.a.nodes[p.a.find(p.n)].pts}
 }
 
 func (p ptr) MayAlias(q Pointer) bool {
@@ -38,6 +38,14 @@ type cgnode struct {
 	obj        nodeid      // start of this contour's object block
 	sites      []*callsite // ordered list of callsites within this function
 	callersite *callsite   // where called from, if known; nil for shared contours
+	context    *callString // call string of this contour, under k-call-site sensitivity
+	recvObj    nodeid      // receiver object of this contour, under object sensitivity
+}
+
+// shared reports whether n is the shared contour for its function,
+// i.e. the one used in all calls not treated context-sensitively.
+func (n *cgnode) shared() bool {
+	return n.callersite == nil && n.recvObj == 0
 }
 
 func (n *cgnode) Func() *ssa.Function {
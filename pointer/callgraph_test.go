@@ -0,0 +1,48 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pointer
+
+// This file tests Config.CallGraphFilter using testdata/contextpolicy.go.
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"code.google.com/p/go.tools/call"
+	"code.google.com/p/go.tools/ssa"
+)
+
+func TestCallGraphFilter(t *testing.T) {
+	mains, err := loadMains([]string{contextInput})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := Analyze(&Config{
+		Mains:          mains,
+		Reflection:     true,
+		BuildCallGraph: true,
+		CallGraphFilter: func(fn *ssa.Function) bool {
+			return fn.Name() == "id2" || fn.Name() == "rec"
+		},
+	})
+
+	// Only the calls from id2 and rec are recorded.
+	seen := make(map[string]bool)
+	var edges []string
+	call.GraphVisitEdges(result.CallGraph, func(edge call.Edge) error {
+		e := edge.Caller.Func().String() + " -> " + edge.Callee.Func().String()
+		if !seen[e] {
+			seen[e] = true
+			edges = append(edges, e)
+		}
+		return nil
+	})
+	sort.Strings(edges)
+	const want = "main.id2 -> main.id, main.rec -> main.rec"
+	if got := strings.Join(edges, ", "); got != want {
+		t.Errorf("call graph edges: got %s, want %s", got, want)
+	}
+}
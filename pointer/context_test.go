@@ -0,0 +1,233 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pointer
+
+// This file tests the context-sensitivity policies of Config.Context
+// using testdata/contextpolicy.go.
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"code.google.com/p/go.tools/ssa"
+)
+
+const contextInput = "testdata/contextpolicy.go"
+
+// contextResults analyzes contextInput using the specified policy
+// and returns, for each print(x) call tagged by a @line comment, a
+// string describing the labels of the combined points-to set of x,
+// and the number of contexts in which that call was analyzed.
+//
+func contextResults(t *testing.T, policy ContextPolicy) map[string]string {
+	mains, err := loadMains([]string{contextInput})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, probes := taggedProbes(t, contextInput, &Config{
+		Mains:      mains,
+		Reflection: true,
+		Context:    policy,
+	})
+
+	results := make(map[string]string)
+	for tag, ptrs := range probes {
+		// Distinct labels may have the same string, so count them.
+		count := make(map[string]int)
+		for _, l := range PointsToCombined(ptrs).Labels() {
+			count[l.String()]++
+		}
+		var labels []string
+		for l, n := range count {
+			if n > 1 {
+				l = fmt.Sprintf("%s x%d", l, n)
+			}
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+		results[tag] = fmt.Sprintf("%s (%d contexts)", strings.Join(labels, " | "), len(ptrs))
+	}
+	return results
+}
+
+// taggedProbes runs the analysis specified by config, whose program
+// was loaded from filename, and returns its result and the pointers
+// for the operand x of each print(x) call, one per context in which
+// the call was analyzed, keyed by the @line tag of the line of the
+// call.
+//
+// (Config.Print cannot be used since it reports a single pointer
+// for each call, combining all contexts.)
+//
+func taggedProbes(t *testing.T, filename string, config *Config) (*Result, map[string][]Pointer) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags := make(map[int]string)
+	re := regexp.MustCompile("// *@line *(.*)$")
+	for i, line := range strings.Split(string(data), "\n") {
+		if m := re.FindStringSubmatch(line); m != nil {
+			tags[i+1] = m[1]
+		}
+	}
+
+	var prog *ssa.Program
+	for _, pkg := range append(config.Mains, config.Libraries...) {
+		prog = pkg.Prog
+	}
+	operands := make(map[ssa.Value]string) // maps each print operand to its tag
+	config.Queries = make(map[ssa.Value]Indirect)
+	for fn := range ssa.AllFunctions(prog) {
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				call, ok := instr.(*ssa.Call)
+				if !ok {
+					continue
+				}
+				if b, ok := call.Call.Value.(*ssa.Builtin); ok && b.Object().Name() == "print" {
+					x := call.Call.Args[0]
+					operands[x] = tags[prog.Fset.Position(call.Pos()).Line]
+					config.Queries[x] = false
+				}
+			}
+		}
+	}
+	result := Analyze(config)
+
+	probes := make(map[string][]Pointer)
+	for x, tag := range operands {
+		probes[tag] = append(probes[tag], result.Queries[x]...)
+	}
+	return result, probes
+}
+
+func TestContextPolicy(t *testing.T) {
+	all := func(fn *ssa.Function) bool { return true }
+	none := func(fn *ssa.Function) bool { return false }
+	for _, test := range []struct {
+		descr  string
+		policy ContextPolicy
+		want   map[string]string
+	}{
+		{
+			"default policy",
+			ContextPolicy{},
+			map[string]string{
+				"id2a": "main.a | main.b (1 contexts)",
+				"id2b": "main.a | main.b (1 contexts)",
+				"reca": "main.a (1 contexts)",
+				"up":   "new x2 (1 contexts)",
+				"show": "main.a | main.b (2 contexts)",
+			},
+		},
+		{
+			"insensitive",
+			ContextPolicy{Sensitive: none},
+			map[string]string{
+				"id2a": "main.a | main.b (1 contexts)",
+				"reca": "main.a (1 contexts)",
+				"up":   "new (1 contexts)",
+				"show": "main.a | main.b (1 contexts)",
+			},
+		},
+		{
+			"1-call-site",
+			ContextPolicy{Sensitive: all},
+			map[string]string{
+				"id2a": "main.a | main.b (1 contexts)",
+				"id2b": "main.a | main.b (1 contexts)",
+				"reca": "main.a (1 contexts)",
+				"up":   "new x2 (1 contexts)",
+				"show": "main.a | main.b (2 contexts)",
+			},
+		},
+		{
+			"2-call-site",
+			ContextPolicy{Sensitive: all, K: 2},
+			map[string]string{
+				"id2a": "main.a (1 contexts)",
+				"id2b": "main.b (1 contexts)",
+				"reca": "main.a (1 contexts)",
+				"up":   "new x2 (1 contexts)",
+				"show": "main.a | main.b (2 contexts)",
+			},
+		},
+		{
+			"object sensitivity",
+			ContextPolicy{Objects: true},
+			map[string]string{
+				"id2a": "main.a | main.b (1 contexts)",
+				"id2b": "main.a | main.b (1 contexts)",
+				"reca": "main.a (1 contexts)",
+				"up":   "new (1 contexts)",
+				"show": "main.a | main.b (2 contexts)",
+			},
+		},
+	} {
+		got := contextResults(t, test.policy)
+		for tag, want := range test.want {
+			if got[tag] != want {
+				t.Errorf("%s: @%s: got %s, want %s", test.descr, tag, got[tag], want)
+			}
+		}
+	}
+}
+
+// TestContextPolicyContexts checks that contours created by the
+// policy are exposed through Pointer.Context and Label.Context.
+func TestContextPolicyContexts(t *testing.T) {
+	mains, err := loadMains([]string{contextInput})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := mains[0].Func("id")
+	config := &Config{
+		Mains:   mains,
+		Context: ContextPolicy{Sensitive: func(*ssa.Function) bool { return true }, K: 2},
+		Queries: map[ssa.Value]Indirect{id.Params[0]: false},
+	}
+	ptrs := Analyze(config).Queries[id.Params[0]]
+	if len(ptrs) != 2 {
+		t.Fatalf("got %d pointers for parameter of id, want 2", len(ptrs))
+	}
+	seen := make(map[interface{}]bool)
+	for _, p := range ptrs {
+		cgn := p.Context()
+		if cgn == nil || cgn.Func() != id {
+			t.Errorf("Context() = %v, want a context of %s", cgn, id)
+		}
+		seen[cgn] = true
+	}
+	if len(seen) != 2 {
+		t.Errorf("got %d distinct contexts for id, want 2", len(seen))
+	}
+
+	// The objects allocated by (*U).Init under object sensitivity
+	// are labelled with the sole context for the receiver.
+	var probes []Pointer
+	config = &Config{
+		Mains:   mains,
+		Context: ContextPolicy{Objects: true},
+		Print: func(site *ssa.CallCommon, p Pointer) {
+			probes = append(probes, p)
+		},
+	}
+	Analyze(config)
+	labels := PointsToCombined(probes).Labels()
+	var found bool
+	for _, l := range labels {
+		if cgn := l.Context(); cgn != nil && cgn.Func().Name() == "Init" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no label allocated in a context of (*U).Init: %v", labels)
+	}
+}
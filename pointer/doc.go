@@ -18,10 +18,10 @@ operations.)  This improves the traction of presolver optimisations,
 but imposes certain restrictions, e.g. potential context sensitivity
 is limited since all variants must be created a priori.
 
-We intend to add various presolving optimisations such as Pointer and
-Location Equivalence from (Hardekopf & Lin, SAS '07) and solver
-optimisatisions such as Hybrid- and Lazy- Cycle Detection from
-(Hardekopf & Lin, PLDI'07),
+The pre-solver detects Pointer Equivalence using hash-based value
+numbering (HVN/HRU) from (Hardekopf & Lin, SAS '07), and the solver
+uses Hybrid- and Lazy- Cycle Detection from (Hardekopf & Lin,
+PLDI'07).  We intend to add Location Equivalence too.
 
 
 CLASSIFICATION
@@ -40,8 +40,9 @@ fields, such as x and y in struct { x, y *int }.
 
 It is mostly CONTEXT-INSENSITIVE: most functions are analyzed once,
 so values can flow in at one call to the function and return out at
-another.  Only some smaller functions are analyzed with consideration
-to their calling context.
+another.  By default, only some smaller functions are analyzed with
+consideration to their calling context; Config.Context selects a
+different policy.
 
 It has a CONTEXT-SENSITIVE HEAP: objects are named by both allocation
 site and context, so the objects returned by two distinct calls to f:
@@ -49,7 +50,10 @@ site and context, so the objects returned by two distinct calls to f:
 are distinguished up to the limits of the calling context.
 
 It is a WHOLE PROGRAM analysis: it requires SSA-form IR for the
-complete Go program and summaries for native code.
+complete Go program and summaries for native code.  A library may be
+analyzed in the absence of a main function by treating its exported
+functions and methods as entry points called with arguments of
+unknown origin; see Config.Libraries.
 
 See the (Hind, PASTE'01) survey paper for an explanation of these terms.
 
@@ -73,6 +77,9 @@ ordering enables a very cheap way to group related nodes together.
 (For example, passing n parameters consists of generating n parallel
 constraints from caller+i to callee+i for 0<=i<n.)
 
+Points-to sets and copy edges are represented as sparse bitvectors
+(see nodeset).
+
 The zero nodeid means "not a pointer".  Currently it's only used for
 struct{} or ().  We generate all flow constraints, even for non-pointer
 types, with the expectations that (a) presolver optimisations will
@@ -403,12 +410,19 @@ FUNCTION CALLS
 
       Static calls (alone) may be treated context sensitively,
       i.e. each callsite may cause a distinct re-analysis of the
-      callee, improving precision.  Our current context-sensitivity
+      callee, improving precision.  Our default context-sensitivity
       policy treats all intrinsics and getter/setter methods in this
       manner since such functions are small and seem like an obvious
       source of spurious confluences, though this has not yet been
       evaluated.
 
+      Config.Context allows clients to choose which functions are
+      treated context sensitively, and how their contexts are
+      distinguished: by the last k call sites (k-call-site
+      sensitivity), or by the receiver object of a method call
+      (object sensitivity).  Contexts are memoized, so that calls
+      with the same call string or receiver share a contour.
+
   Dynamic function calls
 
     Dynamic calls work in a similar manner except that the creation of
@@ -444,9 +458,19 @@ FUNCTION CALLS
 
 SOLVER
 
-The solver is currently a very naive Andersen-style implementation,
-although it does use difference propagation (Pearce et al, SQC'04).
-There is much to do.
+The solver is an Andersen-style implementation using difference
+propagation (Pearce et al, SQC'04).  Before solving, nodes proven to
+be pointer-equivalent by HVN are merged, and constraints rendered
+redundant are discarded.  During solving, nodes found to lie on a
+cycle, either by HCD (using cycles found offline through dereference
+nodes) or by LCD (triggered by equal points-to sets across a copy
+edge), are merged too.  Merging uses a union-find structure; labels
+are never merged, so the optimisations do not affect the solution.
+
+The order in which the solver visits nodes is determined by
+Config.Worklist, and each optimisation may be disabled by
+Config.DisableOpts, which is useful for benchmarking; see the
+Benchmark functions in the tests.
 
 
 FURTHER READING.
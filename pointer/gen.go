@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"go/ast"
 	"go/token"
+	"sort"
 
 	"code.google.com/p/go.tools/go/types"
 	"code.google.com/p/go.tools/ssa"
@@ -59,7 +60,7 @@ func (a *analysis) addNodes(typ types.Type, comment string) nodeid {
 //
 func (a *analysis) addOneNode(typ types.Type, comment string, subelement *fieldInfo) nodeid {
 	id := a.nextNode()
-	a.nodes = append(a.nodes, &node{typ: typ, subelement: subelement})
+	a.nodes = append(a.nodes, &node{typ: typ, subelement: subelement, rep: id})
 	if a.log != nil {
 		fmt.Fprintf(a.log, "\tcreate n%d %s for %s%s\n",
 			id, typ, comment, subelement.path())
@@ -120,7 +121,8 @@ func (a *analysis) endObject(obj nodeid, cgn *cgnode, data interface{}) *object
 // enqueues fn for subsequent constraint generation.
 //
 // For a context-sensitive contour, callersite identifies the sole
-// callsite; for shared contours, caller is nil.
+// callsite (or, under k-call-site sensitivity, the most recent
+// callsite); for shared and object-sensitive contours, it is nil.
 //
 func (a *analysis) makeFunctionObject(fn *ssa.Function, callersite *callsite) nodeid {
 	if a.log != nil {
@@ -536,18 +538,27 @@ func (a *analysis) genBuiltinCall(instr ssa.CallInstruction, cgn *cgnode) {
 	}
 }
 
-// shouldUseContext defines the context-sensitivity policy.  It
-// returns true if we should analyse all static calls to fn anew.
-//
-// Obviously this interface rather limits how much freedom we have to
-// choose a policy.  The current policy, rather arbitrarily, is true
-// for intrinsics and accessor methods (actually: short, single-block,
-// call-free functions).  This is just a starting point.
-//
+// shouldUseContext reports whether static calls to fn should be
+// analysed context-sensitively, according to Config.Context.
 func (a *analysis) shouldUseContext(fn *ssa.Function) bool {
 	if a.findIntrinsic(fn) != nil {
 		return true // treat intrinsics context-sensitively
 	}
+	if sensitive := a.config.Context.Sensitive; sensitive != nil {
+		return sensitive(fn)
+	}
+	return defaultShouldUseContext(fn)
+}
+
+// defaultShouldUseContext defines the default context-sensitivity
+// policy.  It returns true if we should analyse all static calls to
+// fn anew.
+//
+// The current policy, rather arbitrarily, is true for accessor
+// methods (actually: short, single-block, call-free functions).
+// This is just a starting point.
+//
+func defaultShouldUseContext(fn *ssa.Function) bool {
 	if len(fn.Blocks) != 1 {
 		return false // too expensive
 	}
@@ -574,13 +585,7 @@ func (a *analysis) shouldUseContext(fn *ssa.Function) bool {
 // genStaticCall generates constraints for a statically dispatched function call.
 func (a *analysis) genStaticCall(caller *cgnode, site *callsite, call *ssa.CallCommon, result nodeid) {
 	// Ascertain the context (contour/CGNode) for a particular call.
-	var obj nodeid
-	fn := call.StaticCallee()
-	if a.shouldUseContext(fn) {
-		obj = a.makeFunctionObject(fn, site) // new contour
-	} else {
-		obj = a.objectNode(nil, fn) // shared contour
-	}
+	obj := a.contour(caller, site, call)
 
 	sig := call.Signature()
 	targets := a.addOneNode(sig, "call.targets", nil)
@@ -613,6 +618,110 @@ func (a *analysis) genStaticCall(caller *cgnode, site *callsite, call *ssa.CallC
 	site.targets = targets
 }
 
+// A contourKey identifies a context-sensitive function object.
+// Exactly one of context and recvObj is non-zero.
+type contourKey struct {
+	fn      *ssa.Function
+	context *callString // k-call-site sensitivity
+	recvObj nodeid      // object sensitivity
+}
+
+// A callString is a non-empty sequence of call sites, most recent
+// first.  callStrings are interned, so they may be compared using ==.
+type callString struct {
+	site ssa.CallInstruction
+	next *callString
+}
+
+// callString returns the interned call string consisting of site
+// followed by at most k-1 call sites from the prefix of next.
+func (a *analysis) callString(site ssa.CallInstruction, next *callString, k int) *callString {
+	sites := []ssa.CallInstruction{site}
+	for ; next != nil && len(sites) < k; next = next.next {
+		sites = append(sites, next.site)
+	}
+	var cs *callString
+	for i := len(sites) - 1; i >= 0; i-- {
+		key := callString{sites[i], cs}
+		interned, ok := a.callStrings[key]
+		if !ok {
+			interned = &key
+			a.callStrings[key] = interned
+		}
+		cs = interned
+	}
+	return cs
+}
+
+// contour returns the function object (contour) for the static
+// call at site within caller, creating it as needed, according to
+// the context-sensitivity policy in Config.Context.
+//
+func (a *analysis) contour(caller *cgnode, site *callsite, call *ssa.CallCommon) nodeid {
+	fn := call.StaticCallee()
+	if !a.shouldUseContext(fn) {
+		return a.objectNode(nil, fn) // shared contour
+	}
+	policy := &a.config.Context
+
+	// Object sensitivity.  (Intrinsics need their call site.)
+	if policy.Objects && fn.Signature.Recv() != nil && a.findIntrinsic(fn) == nil {
+		if recv := a.receiverObject(caller, call.Args[0]); recv != 0 {
+			key := contourKey{fn: fn, recvObj: recv}
+			obj, ok := a.contours[key]
+			if !ok {
+				obj = a.makeFunctionObject(fn, nil)
+				a.nodes[obj].obj.cgn.recvObj = recv
+				a.contours[key] = obj
+			}
+			return obj
+		}
+	}
+
+	// k-call-site sensitivity.
+	k := policy.K
+	if k == 0 {
+		if policy.Sensitive == nil {
+			return a.makeFunctionObject(fn, site) // new contour
+		}
+		k = 1
+	}
+	cs := a.callString(site.instr, caller.context, k)
+	key := contourKey{fn: fn, context: cs}
+	obj, ok := a.contours[key]
+	if !ok {
+		// All calls sharing this contour share its most
+		// recent call site, so site.instr is the same for each.
+		obj = a.makeFunctionObject(fn, site)
+		a.nodes[obj].obj.cgn.context = cs
+		a.contours[key] = obj
+	}
+	return obj
+}
+
+// receiverObject returns the sole object to which the receiver v of
+// a static method call within caller points, if it is statically
+// known and was allocated outside any context; otherwise it returns
+// zero.  The latter restriction ensures that object-sensitive
+// contours are not created without bound.
+//
+func (a *analysis) receiverObject(caller *cgnode, v ssa.Value) nodeid {
+	var obj nodeid
+	if _, ok := v.(*ssa.Global); ok {
+		obj = a.objectNode(nil, v)
+	} else {
+		obj = a.objectNode(caller, v)
+	}
+	if obj == 0 {
+		return 0
+	}
+	o := a.nodes[obj].obj
+	if o == nil || (o.cgn != nil && !o.cgn.shared()) {
+		return 0 // not the start of an object, or allocated in a context
+	}
+	return obj
+}
+
 // genDynamicCall generates constraints for a dynamic function call.
 func (a *analysis) genDynamicCall(caller *cgnode, site *callsite, call *ssa.CallCommon, result nodeid) {
 	fn := a.valueNode(call.Value)
@@ -1104,9 +1213,202 @@ func (a *analysis) genRootCalls() *cgnode {
 		}
 	}
 
+	// For each library package, call its init() and all its
+	// exported functions and methods.
+	for _, lib := range a.config.Libraries {
+		for _, fn := range libraryEntryPoints(lib) {
+			if a.log != nil {
+				fmt.Fprintf(a.log, "\troot call to %s:\n", fn)
+			}
+			targets := a.addOneNode(fn.Signature, "root.targets", nil)
+			root.sites = append(root.sites, &callsite{targets: targets})
+			a.copy(targets, a.valueNode(fn), 1)
+
+			// Seed the receiver and parameters with
+			// objects of unknown origin.
+			sig := fn.Signature
+			params := a.funcParams(a.objectNode(nil, fn))
+			if recv := sig.Recv(); recv != nil {
+				a.seedUnknown(params, recv.Type(), fn.Pos())
+				params += nodeid(a.sizeof(recv.Type()))
+			}
+			for i, n := 0, sig.Params().Len(); i < n; i++ {
+				T := sig.Params().At(i).Type()
+				a.seedUnknown(params, T, fn.Pos())
+				params += nodeid(a.sizeof(T))
+			}
+		}
+	}
+
 	return root
 }
 
+// libraryEntryPoints returns the entry points of library package
+// pkg, in a deterministic order: its init() function, then its
+// exported functions, then the exported methods of its exported
+// named types (and pointers to them).
+//
+func libraryEntryPoints(pkg *ssa.Package) []*ssa.Function {
+	var names []string
+	for name := range pkg.Members {
+		if ast.IsExported(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var fns, methods []*ssa.Function
+	if init := pkg.Func("init"); init != nil {
+		fns = append(fns, init)
+	}
+	for _, name := range names {
+		switch mem := pkg.Members[name].(type) {
+		case *ssa.Function:
+			fns = append(fns, mem)
+
+		case *ssa.Type:
+			T := mem.Type()
+			if _, ok := T.Underlying().(*types.Interface); ok {
+				continue // abstract methods
+			}
+			seen := make(map[types.Object]bool)
+			for _, t := range [2]types.Type{T, types.NewPointer(T)} {
+				mset := t.MethodSet()
+				for i, n := 0, mset.Len(); i < n; i++ {
+					sel := mset.At(i)
+					if obj := sel.Obj(); obj.IsExported() && !seen[obj] {
+						seen[obj] = true
+						methods = append(methods, pkg.Prog.Method(sel))
+					}
+				}
+			}
+		}
+	}
+	return append(fns, methods...)
+}
+
+// seedUnknown generates constraints so that each pointer within the
+// value of type T whose nodes start at id points to the canonical
+// objects of unknown origin for its type.  pos is the position of the
+// entry point to which the value is passed, for warnings.
+//
+func (a *analysis) seedUnknown(id nodeid, T types.Type, pos token.Pos) {
+	for i, fi := range a.flatten(T) {
+		for _, obj := range a.unknownObjects(fi.typ, pos) {
+			a.addressOf(id+nodeid(i), obj)
+		}
+	}
+}
+
+// unknownObjects returns the canonical objects of unknown origin to
+// which values of pointerlike type T may point, creating them as
+// needed.  It returns nil for non-pointerlike types.
+//
+// An unknown value of a non-empty interface type may hold any type of
+// the program that implements it, so for each such type there is a
+// tagged object of unknown contents.  Unknown functions and values of
+// the empty interface type are not modelled; a warning is reported at
+// pos for each such type.
+//
+func (a *analysis) unknownObjects(T types.Type, pos token.Pos) []nodeid {
+	if v := a.unknowns.At(T); v != nil {
+		return v.([]nodeid)
+	}
+
+	var contents []types.Type // the types of the parts of the object
+	switch t := T.Underlying().(type) {
+	case *types.Pointer:
+		contents = []types.Type{t.Elem()}
+	case *types.Slice:
+		contents = []types.Type{sliceToArray(t)}
+	case *types.Chan:
+		contents = []types.Type{t.Elem()}
+	case *types.Map:
+		contents = []types.Type{t.Key(), t.Elem()}
+	case *types.Interface:
+		return a.unknownTagged(T, t, pos)
+	case *types.Signature:
+		a.warnf(pos, "unsound: functions of unknown origin of type %s are not modelled", T)
+		a.unknowns.Set(T, []nodeid(nil))
+		return nil
+	default:
+		return nil
+	}
+
+	obj := a.nextNode()
+	for _, t := range contents {
+		a.addNodes(t, "unknown")
+	}
+	a.endObject(obj, nil, fmt.Sprintf("<unknown %s>", T))
+
+	// Memoize before seeding the contents,
+	// since the type may be recursive.
+	objs := []nodeid{obj}
+	a.unknowns.Set(T, objs)
+	id := obj
+	for _, t := range contents {
+		a.seedUnknown(id, t, pos)
+		id += nodeid(a.sizeof(t))
+	}
+	return objs
+}
+
+// unknownTagged returns the canonical tagged objects of unknown origin
+// for interface type T, whose underlying type is iface: one for each
+// named type of the program, or pointer to one, that implements it.
+//
+func (a *analysis) unknownTagged(T types.Type, iface *types.Interface, pos token.Pos) []nodeid {
+	var objs []nodeid
+	if iface.NumMethods() == 0 {
+		a.warnf(pos, "unsound: values of unknown origin of type %s are not modelled", T)
+	} else {
+		for _, C := range a.namedTypes() {
+			for _, t := range [2]types.Type{C, types.NewPointer(C)} {
+				if types.Implements(t, iface, false) {
+					objs = append(objs, a.makeTagged(t, nil, fmt.Sprintf("<unknown %s>", t)))
+				}
+			}
+		}
+	}
+
+	// Memoize before seeding the contents,
+	// since the types may be recursive.
+	a.unknowns.Set(T, objs)
+	for _, obj := range objs {
+		a.seedUnknown(obj+1, a.nodes[obj].typ, pos)
+	}
+	return objs
+}
+
+// namedTypes returns the named non-interface types declared at package
+// level in the program, in a deterministic order.
+//
+func (a *analysis) namedTypes() []types.Type {
+	var keys []string
+	named := make(map[string][]types.Type) // (distinct packages may have the same path)
+	for _, pkg := range a.prog.AllPackages() {
+		for _, mem := range pkg.Members {
+			if mem, ok := mem.(*ssa.Type); ok {
+				T := mem.Type()
+				if _, ok := T.Underlying().(*types.Interface); !ok {
+					key := T.String()
+					if named[key] == nil {
+						keys = append(keys, key)
+					}
+					named[key] = append(named[key], T)
+				}
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	var result []types.Type
+	for _, key := range keys {
+		result = append(result, named[key]...)
+	}
+	return result
+}
+
 // genFunc generates constraints for function fn.
 func (a *analysis) genFunc(cgn *cgnode) {
 	fn := cgn.fn
@@ -58,6 +58,9 @@ func (l Label) ReflectType() types.Type {
 // or nil for global objects: global, const, and shared contours for functions.
 //
 func (l Label) Context() call.GraphNode {
+	if l.obj.cgn == nil {
+		return nil // (not a nil *cgnode)
+	}
 	return l.obj.cgn
 }
 
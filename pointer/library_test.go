@@ -0,0 +1,62 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pointer
+
+// This file tests the analysis of library packages (Config.Libraries)
+// using testdata/library.go.
+
+import (
+	"go/build"
+	"sort"
+	"strings"
+	"testing"
+
+	"code.google.com/p/go.tools/importer"
+	"code.google.com/p/go.tools/ssa"
+)
+
+func TestLibrary(t *testing.T) {
+	const input = "testdata/library.go"
+	imp := importer.New(&importer.Config{Build: &build.Default})
+	infos, _, err := imp.LoadInitialPackages([]string{input})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := ssa.NewProgram(imp.Fset, 0)
+	if err := prog.CreatePackages(imp); err != nil {
+		t.Fatal(err)
+	}
+	prog.BuildAll()
+	lib := prog.Package(infos[0].Pkg)
+
+	result, probes := taggedProbes(t, input, &Config{Libraries: []*ssa.Package{lib}})
+
+	for tag, want := range map[string]string{
+		"next":       "<unknown *library.T>",
+		"lookup":     "<unknown *int>",
+		"unexported": "new",
+		"shape":      "<unknown *library.Square>",
+		"area":       "<unknown *int>",
+	} {
+		var labels []string
+		for _, l := range PointsToCombined(probes[tag]).Labels() {
+			labels = append(labels, l.String())
+		}
+		sort.Strings(labels)
+		if got := strings.Join(labels, " | "); got != want {
+			t.Errorf("@%s: got %s, want %s", tag, got, want)
+		}
+	}
+
+	// Unknown functions are not modelled, but reported.
+	const want = "unsound: functions of unknown origin of type func() *int are not modelled"
+	var warnings []string
+	for _, w := range result.Warnings {
+		warnings = append(warnings, w.Message)
+	}
+	if got := strings.Join(warnings, "\n"); got != want {
+		t.Errorf("warnings: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,350 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pointer
+
+// This file defines nodeset, a sparse bit vector representation of
+// a set of nodes, used for points-to sets and constraint graph edges.
+//
+// A nodeset is a sorted slice of fixed-size blocks, each of which
+// holds a bitmap of the elements of the set in a range of nodeids.
+// Blocks with no elements are never represented.
+//
+// This representation is much more compact than a map for the dense
+// clusters of nodeids that typically appear in points-to sets (e.g.
+// the nodes of a single object, or objects allocated by a single
+// function).  Union, difference and intersection operate a word at a
+// time, and the elements are enumerated in ascending order.
+
+import (
+	"bytes"
+	"fmt"
+)
+
+const (
+	bitsPerWord   = 64
+	wordsPerBlock = 4
+	bitsPerBlock  = bitsPerWord * wordsPerBlock
+)
+
+// A block holds the elements of a nodeset in the range
+// [offset, offset+bitsPerBlock).
+type block struct {
+	offset nodeid // a multiple of bitsPerBlock
+	bits   [wordsPerBlock]uint64
+}
+
+// empty reports whether b contains no elements.
+func (b *block) empty() bool {
+	for _, w := range b.bits {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// len returns the number of elements in b.
+func (b *block) len() int {
+	var n int
+	for _, w := range b.bits {
+		n += popcount(w)
+	}
+	return n
+}
+
+// A nodeset is a set of nodeids represented as a sparse bit vector.
+// The zero value is the empty set.
+//
+// NB, mutator methods are attached to *nodeset.  Since the blocks
+// slice may be shared, a nodeset must not be mutated via a copy of
+// it; use clone.
+//
+type nodeset struct {
+	blocks []block // in ascending order of offset; no block is empty
+}
+
+// ---- Accessors ----
+
+func (ns nodeset) String() string {
+	var buf bytes.Buffer
+	buf.WriteRune('{')
+	for i, n := range ns.appendTo(nil) {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, "n%d", n)
+	}
+	buf.WriteRune('}')
+	return buf.String()
+}
+
+// search returns the index of the block of ns for the specified
+// offset, or the index at which it should be inserted if there is none.
+func (ns nodeset) search(offset nodeid) int {
+	// Fast path: additions are often in ascending order.
+	if n := len(ns.blocks); n > 0 && ns.blocks[n-1].offset < offset {
+		return n
+	}
+	i, j := 0, len(ns.blocks)
+	for i < j {
+		h := i + (j-i)/2
+		if ns.blocks[h].offset < offset {
+			i = h + 1
+		} else {
+			j = h
+		}
+	}
+	return i
+}
+
+// isEmpty reports whether ns is the empty set.
+func (ns nodeset) isEmpty() bool {
+	return len(ns.blocks) == 0
+}
+
+// len returns the number of elements of ns.
+func (ns nodeset) len() int {
+	var n int
+	for i := range ns.blocks {
+		n += ns.blocks[i].len()
+	}
+	return n
+}
+
+// has reports whether n is an element of ns.
+func (ns nodeset) has(n nodeid) bool {
+	offset := n &^ (bitsPerBlock - 1)
+	i := ns.search(offset)
+	if i == len(ns.blocks) || ns.blocks[i].offset != offset {
+		return false
+	}
+	bit := n - offset
+	return ns.blocks[i].bits[bit/bitsPerWord]&(1<<(bit%bitsPerWord)) != 0
+}
+
+// appendTo appends the elements of ns to s, in ascending order,
+// and returns the resulting slice.
+func (ns nodeset) appendTo(s []nodeid) []nodeid {
+	for i := range ns.blocks {
+		b := &ns.blocks[i]
+		for j, w := range b.bits {
+			base := b.offset + nodeid(j*bitsPerWord)
+			for w != 0 {
+				s = append(s, base+nodeid(ntz(w)))
+				w &= w - 1 // clear lowest bit
+			}
+		}
+	}
+	return s
+}
+
+// diff returns the set-difference x - y.
+func (x nodeset) diff(y nodeset) nodeset {
+	var z nodeset
+	j := 0
+	for i := range x.blocks {
+		xb := &x.blocks[i]
+		for j < len(y.blocks) && y.blocks[j].offset < xb.offset {
+			j++
+		}
+		if j < len(y.blocks) && y.blocks[j].offset == xb.offset {
+			zb := block{offset: xb.offset}
+			for k := range zb.bits {
+				zb.bits[k] = xb.bits[k] &^ y.blocks[j].bits[k]
+			}
+			if !zb.empty() {
+				z.blocks = append(z.blocks, zb)
+			}
+		} else {
+			z.blocks = append(z.blocks, *xb)
+		}
+	}
+	return z
+}
+
+// intersect returns the set-intersection of x and y.
+func (x nodeset) intersect(y nodeset) nodeset {
+	var z nodeset
+	i, j := 0, 0
+	for i < len(x.blocks) && j < len(y.blocks) {
+		xb, yb := &x.blocks[i], &y.blocks[j]
+		switch {
+		case xb.offset < yb.offset:
+			i++
+		case xb.offset > yb.offset:
+			j++
+		default:
+			zb := block{offset: xb.offset}
+			for k := range zb.bits {
+				zb.bits[k] = xb.bits[k] & yb.bits[k]
+			}
+			if !zb.empty() {
+				z.blocks = append(z.blocks, zb)
+			}
+			i++
+			j++
+		}
+	}
+	return z
+}
+
+// intersects reports whether x and y have any elements in common.
+func (x nodeset) intersects(y nodeset) bool {
+	i, j := 0, 0
+	for i < len(x.blocks) && j < len(y.blocks) {
+		xb, yb := &x.blocks[i], &y.blocks[j]
+		switch {
+		case xb.offset < yb.offset:
+			i++
+		case xb.offset > yb.offset:
+			j++
+		default:
+			for k := range xb.bits {
+				if xb.bits[k]&yb.bits[k] != 0 {
+					return true
+				}
+			}
+			i++
+			j++
+		}
+	}
+	return false
+}
+
+// equals reports whether x and y contain the same elements.
+func (x nodeset) equals(y nodeset) bool {
+	if len(x.blocks) != len(y.blocks) {
+		return false
+	}
+	for i := range x.blocks {
+		if x.blocks[i] != y.blocks[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// clone returns an unaliased copy of x.
+func (x nodeset) clone() nodeset {
+	if x.blocks == nil {
+		return nodeset{}
+	}
+	return nodeset{append([]block(nil), x.blocks...)}
+}
+
+// ---- Mutators ----
+
+// add adds n to ns and reports whether ns grew.
+func (ns *nodeset) add(n nodeid) bool {
+	offset := n &^ (bitsPerBlock - 1)
+	i := ns.search(offset)
+	if i == len(ns.blocks) || ns.blocks[i].offset != offset {
+		// Insert a new block at i.
+		ns.blocks = append(ns.blocks, block{})
+		copy(ns.blocks[i+1:], ns.blocks[i:])
+		ns.blocks[i] = block{offset: offset}
+	}
+	bit := n - offset
+	w := &ns.blocks[i].bits[bit/bitsPerWord]
+	mask := uint64(1) << (bit % bitsPerWord)
+	if *w&mask != 0 {
+		return false
+	}
+	*w |= mask
+	return true
+}
+
+// addAll sets x to the union of x and y and reports whether x grew.
+func (x *nodeset) addAll(y nodeset) bool {
+	if len(y.blocks) == 0 {
+		return false
+	}
+	if len(x.blocks) == 0 {
+		*x = y.clone()
+		return true
+	}
+
+	// Count the blocks of y not in x.
+	extra := 0
+	i := 0
+	for j := range y.blocks {
+		for i < len(x.blocks) && x.blocks[i].offset < y.blocks[j].offset {
+			i++
+		}
+		if i == len(x.blocks) || x.blocks[i].offset != y.blocks[j].offset {
+			extra++
+		}
+	}
+
+	changed := false
+	if extra == 0 {
+		// Update x in place.
+		i := 0
+		for j := range y.blocks {
+			yb := &y.blocks[j]
+			for x.blocks[i].offset < yb.offset {
+				i++
+			}
+			xb := &x.blocks[i]
+			for k := range xb.bits {
+				if w := xb.bits[k] | yb.bits[k]; w != xb.bits[k] {
+					xb.bits[k] = w
+					changed = true
+				}
+			}
+		}
+		return changed
+	}
+
+	// Merge x and y into a new slice.
+	z := make([]block, 0, len(x.blocks)+extra)
+	i, j := 0, 0
+	for i < len(x.blocks) || j < len(y.blocks) {
+		switch {
+		case j == len(y.blocks) || i < len(x.blocks) && x.blocks[i].offset < y.blocks[j].offset:
+			z = append(z, x.blocks[i])
+			i++
+		case i == len(x.blocks) || y.blocks[j].offset < x.blocks[i].offset:
+			z = append(z, y.blocks[j])
+			j++
+		default:
+			zb := x.blocks[i]
+			for k := range zb.bits {
+				zb.bits[k] |= y.blocks[j].bits[k]
+			}
+			z = append(z, zb)
+			i++
+			j++
+		}
+	}
+	x.blocks = z
+	return true
+}
+
+// ---- Bit twiddling ----
+
+// popcount returns the number of set bits in x.
+func popcount(x uint64) int {
+	x -= (x >> 1) & 0x5555555555555555
+	x = (x & 0x3333333333333333) + ((x >> 2) & 0x3333333333333333)
+	x = (x + (x >> 4)) & 0x0f0f0f0f0f0f0f0f
+	return int((x * 0x0101010101010101) >> 56)
+}
+
+// deBruijn64 and ntzTable are used by ntz.
+const deBruijn64 = 0x03f79d71b4ca8b09
+
+var ntzTable = [64]uint8{
+	0, 1, 56, 2, 57, 49, 28, 3, 61, 58, 42, 50, 38, 29, 17, 4,
+	62, 47, 59, 36, 45, 43, 51, 22, 53, 39, 33, 30, 24, 18, 12, 5,
+	63, 55, 48, 27, 60, 41, 37, 16, 46, 35, 44, 21, 52, 32, 23, 11,
+	54, 26, 40, 15, 34, 20, 31, 10, 25, 14, 19, 9, 13, 8, 7, 6,
+}
+
+// ntz returns the number of trailing zero bits in x, which must be
+// non-zero.
+func ntz(x uint64) int {
+	return int(ntzTable[((x&-x)*deBruijn64)>>58])
+}
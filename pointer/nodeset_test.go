@@ -0,0 +1,229 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pointer
+
+// This file tests the sparse bit vector nodeset against a simple
+// map-based implementation, and compares the time and memory
+// performance of the two representations.
+//
+// Run the benchmarks with:
+//
+//   % go test -run=NONE -bench=Nodeset -benchmem
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// mapset is the map-based representation formerly used by nodeset.
+type mapset map[nodeid]struct{}
+
+func (s mapset) add(n nodeid) bool {
+	sz := len(s)
+	s[n] = struct{}{}
+	return len(s) > sz
+}
+
+func (x mapset) addAll(y mapset) bool {
+	sz := len(x)
+	for n := range y {
+		x[n] = struct{}{}
+	}
+	return len(x) > sz
+}
+
+func (x mapset) diff(y mapset) mapset {
+	z := make(mapset)
+	for n := range x {
+		if _, ok := y[n]; !ok {
+			z[n] = struct{}{}
+		}
+	}
+	return z
+}
+
+func (s mapset) sorted() []nodeid {
+	var elems []nodeid
+	for n := range s {
+		elems = append(elems, n)
+	}
+	sort.Sort(nodeidSlice(elems))
+	return elems
+}
+
+type nodeidSlice []nodeid
+
+func (s nodeidSlice) Len() int           { return len(s) }
+func (s nodeidSlice) Less(i, j int) bool { return s[i] < s[j] }
+func (s nodeidSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// randomSet returns a nodeset and a mapset containing the same
+// elements, clustered as they typically are in points-to sets.
+func randomSet(rnd *rand.Rand, size int, max nodeid) (nodeset, mapset) {
+	var ns nodeset
+	ms := make(mapset)
+	for i := 0; i < size; {
+		// A run of up to 8 consecutive nodes.
+		base := nodeid(rnd.Int63n(int64(max)))
+		for j := rnd.Intn(8); j >= 0 && i < size; j-- {
+			ns.add(base)
+			ms.add(base)
+			base++
+			i++
+		}
+	}
+	return ns, ms
+}
+
+func sameElements(ns nodeset, ms mapset) bool {
+	got, want := ns.appendTo(nil), ms.sorted()
+	if len(got) != len(want) || ns.len() != len(want) || ns.isEmpty() != (len(want) == 0) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestNodeset(t *testing.T) {
+	rnd := rand.New(rand.NewSource(0))
+	for i := 0; i < 1000; i++ {
+		max := nodeid(1 + rnd.Intn(5000))
+		x, xm := randomSet(rnd, rnd.Intn(100), max)
+		y, ym := randomSet(rnd, rnd.Intn(100), max)
+		if !sameElements(x, xm) {
+			t.Fatalf("add: got %s, want %v", x, xm.sorted())
+		}
+
+		for n := nodeid(0); n < max; n++ {
+			_, ok := xm[n]
+			if x.has(n) != ok {
+				t.Fatalf("%s.has(n%d) = %t", x, n, !ok)
+			}
+		}
+
+		d := x.diff(y)
+		if dm := xm.diff(ym); !sameElements(d, dm) {
+			t.Fatalf("%s.diff(%s) = %s, want %v", x, y, d, dm.sorted())
+		}
+
+		in := x.intersect(y)
+		inm := xm.diff(xm.diff(ym))
+		if !sameElements(in, inm) {
+			t.Fatalf("%s.intersect(%s) = %s, want %v", x, y, in, inm.sorted())
+		}
+		if x.intersects(y) != (len(inm) > 0) {
+			t.Fatalf("%s.intersects(%s) = %t", x, y, !(len(inm) > 0))
+		}
+
+		// Check that addAll does not mutate the operand or
+		// a previous clone of the result.
+		u := x.clone()
+		ycopy := y.clone()
+		grew := u.addAll(y)
+		wantGrew := len(ym.diff(xm)) > 0
+		xm.addAll(ym)
+		if !sameElements(u, xm) || grew != wantGrew {
+			t.Fatalf("%s.addAll(%s) = %s, %t, want %v, %t", x, y, u, grew, xm.sorted(), wantGrew)
+		}
+		if !y.equals(ycopy) {
+			t.Fatalf("addAll mutated its operand: %s, want %s", y, ycopy)
+		}
+		if u.addAll(y) {
+			t.Fatalf("second addAll(%s) reported growth", y)
+		}
+		if !u.equals(u.clone()) || (!y.isEmpty() && !u.intersects(y)) {
+			t.Fatalf("equals/intersects inconsistent for %s, %s", u, y)
+		}
+	}
+}
+
+// Benchmarks comparing nodeset with mapset.
+
+const benchSetSize = 1000
+
+func benchmarkSets(b *testing.B) (x, y nodeset, xm, ym mapset) {
+	rnd := rand.New(rand.NewSource(0))
+	x, xm = randomSet(rnd, benchSetSize, 20*benchSetSize)
+	y, ym = randomSet(rnd, benchSetSize, 20*benchSetSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	return
+}
+
+func BenchmarkNodesetAdd(b *testing.B) {
+	x, _, _, _ := benchmarkSets(b)
+	elems := x.appendTo(nil)
+	for i := 0; i < b.N; i++ {
+		var s nodeset
+		for _, n := range elems {
+			s.add(n)
+		}
+	}
+}
+
+func BenchmarkMapsetAdd(b *testing.B) {
+	x, _, _, _ := benchmarkSets(b)
+	elems := x.appendTo(nil)
+	for i := 0; i < b.N; i++ {
+		s := make(mapset)
+		for _, n := range elems {
+			s.add(n)
+		}
+	}
+}
+
+func BenchmarkNodesetAddAll(b *testing.B) {
+	x, y, _, _ := benchmarkSets(b)
+	for i := 0; i < b.N; i++ {
+		s := x.clone()
+		s.addAll(y)
+	}
+}
+
+func BenchmarkMapsetAddAll(b *testing.B) {
+	_, _, xm, ym := benchmarkSets(b)
+	for i := 0; i < b.N; i++ {
+		s := make(mapset)
+		s.addAll(xm)
+		s.addAll(ym)
+	}
+}
+
+func BenchmarkNodesetDiff(b *testing.B) {
+	x, y, _, _ := benchmarkSets(b)
+	for i := 0; i < b.N; i++ {
+		x.diff(y)
+	}
+}
+
+func BenchmarkMapsetDiff(b *testing.B) {
+	_, _, xm, ym := benchmarkSets(b)
+	for i := 0; i < b.N; i++ {
+		xm.diff(ym)
+	}
+}
+
+func BenchmarkNodesetIterate(b *testing.B) {
+	x, _, _, _ := benchmarkSets(b)
+	var buf []nodeid
+	for i := 0; i < b.N; i++ {
+		buf = x.appendTo(buf[:0])
+		for _ = range buf {
+		}
+	}
+}
+
+func BenchmarkMapsetIterate(b *testing.B) {
+	_, _, xm, _ := benchmarkSets(b)
+	for i := 0; i < b.N; i++ {
+		for _ = range xm {
+		}
+	}
+}
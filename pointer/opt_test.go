@@ -0,0 +1,221 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pointer
+
+// This file checks that the solver optimisations do not change the
+// solution, and benchmarks them.
+//
+// By default, the benchmarks analyze each of the testdata inputs in
+// turn.  Use the -benchargs flag to benchmark some other program, e.g.
+//
+//   % go test -run=NONE -bench=. -benchargs=code.google.com/p/go.tools/cmd/oracle
+//
+// In addition to the running time and memory allocation per
+// operation, each benchmark logs the size of the constraint system
+// and the effect of the optimisations upon it.
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/build"
+	"sort"
+	"strings"
+	"testing"
+
+	"code.google.com/p/go.tools/importer"
+	"code.google.com/p/go.tools/ssa"
+)
+
+var benchArgs = flag.String("benchargs", "",
+	"Arguments to importer.LoadInitialPackages for the program to benchmark (default: each testdata input)")
+
+// optInputs are the testdata inputs used by the tests and benchmarks
+// in this file.
+var optInputs = []string{
+	"testdata/a_test.go",
+	"testdata/another.go",
+	"testdata/arrays.go",
+	"testdata/channels.go",
+	"testdata/chanreflect.go",
+	"testdata/context.go",
+	"testdata/conv.go",
+	"testdata/flow.go",
+	"testdata/fmtexcerpt.go",
+	"testdata/func.go",
+	"testdata/hello.go",
+	"testdata/interfaces.go",
+	"testdata/funcreflect.go",
+	"testdata/mapreflect.go",
+	"testdata/maps.go",
+	"testdata/panic.go",
+	"testdata/recur.go",
+	"testdata/reflect.go",
+	"testdata/structs.go",
+}
+
+// loadMains loads, creates and builds the program specified by args,
+// and returns its main packages.
+func loadMains(args []string) ([]*ssa.Package, error) {
+	imp := importer.New(&importer.Config{Build: &build.Default})
+	infos, _, err := imp.LoadInitialPackages(args)
+	if err != nil {
+		return nil, err
+	}
+	prog := ssa.NewProgram(imp.Fset, 0)
+	if err := prog.CreatePackages(imp); err != nil {
+		return nil, err
+	}
+	prog.BuildAll()
+
+	var mains []*ssa.Package
+	for _, info := range infos {
+		pkg := prog.Package(info.Pkg)
+		if pkg.Func("main") == nil && pkg.CreateTestMainFunction() == nil {
+			continue
+		}
+		mains = append(mains, pkg)
+	}
+	if mains == nil {
+		return nil, fmt.Errorf("%s: no main packages", strings.Join(args, " "))
+	}
+	return mains, nil
+}
+
+// solution returns a string describing the points-to set of every
+// pointerlike value in the program, as computed by the analysis
+// with the specified configuration.
+//
+func solution(config *Config) string {
+	config.Queries = make(map[ssa.Value]Indirect)
+	parent := make(map[ssa.Value]*ssa.Function)
+	for fn := range ssa.AllFunctions(config.prog()) {
+		for _, p := range fn.Params {
+			if CanPoint(p.Type()) {
+				config.Queries[p] = false
+				parent[p] = fn
+			}
+		}
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				if v, ok := instr.(ssa.Value); ok && CanPoint(v.Type()) {
+					config.Queries[v] = false
+					parent[v] = fn
+				}
+			}
+		}
+	}
+
+	var lines []string
+	for v, ptrs := range Analyze(config).Queries {
+		var labels []string
+		for _, l := range PointsToCombined(ptrs).Labels() {
+			labels = append(labels, fmt.Sprintf("%s@%d", l, l.Pos()))
+		}
+		sort.Strings(labels)
+		lines = append(lines, fmt.Sprintf("%s: %s = %s (%s)\n",
+			parent[v], v.Name(), strings.Join(labels, ", "), v))
+	}
+	sort.Strings(lines)
+
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.WriteString(line)
+	}
+	return buf.String()
+}
+
+func TestOptimizationsPreserveSolution(t *testing.T) {
+	for _, input := range optInputs {
+		mains, err := loadMains([]string{input})
+		if err != nil {
+			t.Errorf("%s: %s", input, err)
+			continue
+		}
+		want := solution(&Config{Mains: mains, Reflection: true, DisableOpts: OptAll})
+
+		for _, test := range []struct {
+			descr  string
+			config Config
+		}{
+			{"HVN only", Config{DisableOpts: OptAll &^ OptHVN}},
+			{"HCD only", Config{DisableOpts: OptAll &^ OptHCD}},
+			{"LCD only", Config{DisableOpts: OptAll &^ OptLCD}},
+			{"all optimisations", Config{}},
+			{"FIFO worklist", Config{Worklist: FIFOWorklist}},
+			{"LIFO worklist", Config{Worklist: LIFOWorklist}},
+			{"node id worklist", Config{Worklist: NodeIDWorklist}},
+			{"LRF worklist", Config{Worklist: LRFWorklist}},
+		} {
+			config := test.config
+			config.Mains = mains
+			config.Reflection = true
+			if got := solution(&config); got != want {
+				t.Errorf("%s: solution with %s differs from unoptimised solution:\n%s\nwant:\n%s",
+					input, test.descr, got, want)
+			}
+		}
+	}
+}
+
+func benchmarkSolve(b *testing.B, disable Opt, order WorklistOrder) {
+	b.StopTimer()
+	var argss [][]string
+	if *benchArgs != "" {
+		argss = append(argss, strings.Fields(*benchArgs))
+	} else {
+		for _, input := range optInputs {
+			argss = append(argss, []string{input})
+		}
+	}
+	var progs [][]*ssa.Package
+	for _, args := range argss {
+		mains, err := loadMains(args)
+		if err != nil {
+			b.Fatal(err)
+		}
+		progs = append(progs, mains)
+	}
+	b.ReportAllocs()
+	b.StartTimer()
+
+	var total stats
+	for i := 0; i < b.N; i++ {
+		total = stats{}
+		for _, mains := range progs {
+			a := analyze(&Config{
+				Mains:       mains,
+				Reflection:  true,
+				DisableOpts: disable,
+				Worklist:    order,
+			})
+			s := &a.stats
+			total.nodes += s.nodes
+			total.constraints += s.constraints
+			total.presolved += s.presolved
+			total.hvnMerged += s.hvnMerged
+			total.hcdMerged += s.hcdMerged
+			total.lcdMerged += s.lcdMerged
+			total.presolveTime += s.presolveTime
+			total.solveTime += s.solveTime
+		}
+	}
+
+	b.Logf("%d nodes, %d constraints (%d after pre-solving)",
+		total.nodes, total.constraints, total.presolved)
+	b.Logf("nodes merged: %d by HVN, %d by HCD, %d by LCD",
+		total.hvnMerged, total.hcdMerged, total.lcdMerged)
+	b.Logf("pre-solve time %s, solve time %s", total.presolveTime, total.solveTime)
+}
+
+func BenchmarkSolveNoOpt(b *testing.B)   { benchmarkSolve(b, OptAll, MapWorklist) }
+func BenchmarkSolveHVN(b *testing.B)     { benchmarkSolve(b, OptAll&^OptHVN, MapWorklist) }
+func BenchmarkSolveHCD(b *testing.B)     { benchmarkSolve(b, OptAll&^OptHCD, MapWorklist) }
+func BenchmarkSolveLCD(b *testing.B)     { benchmarkSolve(b, OptAll&^OptLCD, MapWorklist) }
+func BenchmarkSolveAllOpts(b *testing.B) { benchmarkSolve(b, 0, MapWorklist) }
+func BenchmarkSolveFIFO(b *testing.B)    { benchmarkSolve(b, 0, FIFOWorklist) }
+func BenchmarkSolveLIFO(b *testing.B)    { benchmarkSolve(b, 0, LIFOWorklist) }
+func BenchmarkSolveNodeID(b *testing.B)  { benchmarkSolve(b, 0, NodeIDWorklist) }
+func BenchmarkSolveLRF(b *testing.B)     { benchmarkSolve(b, 0, LRFWorklist) }
@@ -0,0 +1,508 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pointer
+
+// This file defines the pre-solver ("offline") optimisations of the
+// constraint system: offline variable substitution by hash-based
+// value numbering (HVN), and the offline part of hybrid cycle
+// detection (HCD).  See solve.go for the online part.
+//
+// HVN assigns each node a pointer-equivalence (PE) label such that
+// two nodes with the same label are sure to have the same points-to
+// set in the solution.  All nodes with the same label are then
+// merged, and constraints made redundant by the merge are discarded.
+// Nodes with the label zero are sure to point to nothing, so the
+// constraints that depend on them are discarded too.
+//
+// The PE label of a node is computed from the labels of the nodes
+// from which it receives copy edges, and from the "base" effects of
+// the constraints for which it is the destination:
+//
+//   x = &o       contributes a label unique to o;
+//   x = *(y+k)   contributes a label unique to (label(y), k);
+//   x = &y.f     contributes a label unique to (label(y), f).
+//
+// The labels of loads and address computations refer to the label
+// of another node, y, which may not be known until later in the
+// computation, so we iterate the whole labelling to a fixed point
+// using the labels of the previous iteration.  This is the "ref"
+// refinement of the HRU algorithm.
+//
+// A node is "indirect" if the solver may add labels or copy edges
+// to it that are not apparent from the constraints.  Each indirect
+// node receives a fresh label.  The indirect nodes are all nodes of
+// objects, since they may be the target of a store or a dynamic call,
+// plus those nodes marked by the presolve method of each constraint.
+//
+// PE labels are internal to the solver: unlike location equivalence
+// (LE), which is not implemented, HVN does not change the identity of
+// the labels in the solution.
+//
+// Reference: Hardekopf & Lin, SAS'07: "Exploiting pointer and
+// location equivalence to optimize pointer analysis".
+
+import (
+	"fmt"
+	"sort"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+// A peLabel is a pointer-equivalence label.  Zero means "points to
+// nothing".
+type peLabel uint32
+
+// A baseKey identifies the base effect on node x of a constraint for
+// which it is the destination.
+type baseKey struct {
+	kind   byte   // 'a' for x=&o, 'l' for x=*(y+k), 'o' for x=&y.f
+	id     nodeid // o or y
+	offset uint32 // k or f
+}
+
+// hvn holds the state of the HVN pre-solver.
+type hvn struct {
+	a        *analysis
+	N        int         // number of nodes (at the end of constraint generation)
+	indirect []bool      // indirect[x] iff node x is indirect
+	deps     [][]nodeid  // deps[x] are the sources of copy edges into x
+	bases    [][]baseKey // bases[x] are the base effects upon x
+	sccs     [][]nodeid  // SCCs of the copy graph, in dependency order
+}
+
+// optimize applies the pre-solver optimisations enabled by the
+// configuration to the constraint system a.constraints.
+//
+func (a *analysis) optimize() {
+	if a.config.DisableOpts&OptHVN == 0 {
+		a.hvn()
+	}
+	if a.config.DisableOpts&OptHCD == 0 {
+		a.hcdOffline()
+	}
+}
+
+// hvn performs offline variable substitution.
+func (a *analysis) hvn() {
+	h := &hvn{
+		a:        a,
+		N:        len(a.nodes),
+		indirect: make([]bool, len(a.nodes)),
+		deps:     make([][]nodeid, len(a.nodes)),
+		bases:    make([][]baseKey, len(a.nodes)),
+	}
+
+	// All nodes of objects are indirect.
+	for id, n := range a.nodes {
+		if n.obj != nil {
+			h.markIndirect(nodeid(id), n.obj.size)
+		}
+	}
+	for _, c := range a.constraints {
+		c.presolve(h)
+	}
+
+	h.findSCCs()
+
+	// Compute PE labels until the number of distinct labels
+	// no longer decreases.
+	label, nlabels := h.labelNodes(nil)
+	for {
+		if a.log != nil {
+			fmt.Fprintf(a.log, "HVN: %d distinct PE labels\n", nlabels)
+		}
+		next, n := h.labelNodes(label)
+		if n >= nlabels {
+			break
+		}
+		label, nlabels = next, n
+	}
+
+	// Merge the nodes in each equivalence class.
+	canon := make(map[peLabel]nodeid)
+	for id := 1; id < h.N; id++ {
+		l := label[id]
+		if l == 0 {
+			continue
+		}
+		if rep, ok := canon[l]; ok {
+			a.unify(rep, nodeid(id))
+			a.stats.hvnMerged++
+		} else {
+			canon[l] = nodeid(id)
+		}
+	}
+
+	// Discard constraints that are duplicates after merging
+	// or that depend on nodes that point to nothing.
+	// (Only the representatives of addr constraints' src
+	// nodes are significant, as they are labels.)
+	type constraintKey struct {
+		kind     byte
+		offset   uint32
+		dst, src nodeid
+	}
+	seen := make(map[constraintKey]bool)
+	cc := a.constraints[:0]
+	for _, c := range a.constraints {
+		var key constraintKey
+		switch c := c.(type) {
+		case *addrConstraint:
+			key = constraintKey{'a', 0, a.find(c.dst), c.src}
+		case *copyConstraint:
+			if label[c.src] == 0 || a.find(c.src) == a.find(c.dst) {
+				continue
+			}
+			key = constraintKey{'c', 0, a.find(c.dst), a.find(c.src)}
+		case *loadConstraint:
+			if label[c.src] == 0 {
+				continue
+			}
+			key = constraintKey{'l', c.offset, a.find(c.dst), a.find(c.src)}
+		case *storeConstraint:
+			if label[c.dst] == 0 || label[c.src] == 0 {
+				continue
+			}
+			key = constraintKey{'s', c.offset, a.find(c.dst), a.find(c.src)}
+		case *offsetAddrConstraint:
+			if label[c.src] == 0 {
+				continue
+			}
+			key = constraintKey{'o', c.offset, a.find(c.dst), a.find(c.src)}
+		default:
+			if label[c.ptr()] != 0 {
+				cc = append(cc, c)
+			}
+			continue
+		}
+		if !seen[key] {
+			seen[key] = true
+			cc = append(cc, c)
+		}
+	}
+	a.constraints = cc
+
+	if a.log != nil {
+		fmt.Fprintf(a.log, "HVN: merged %d nodes; %d constraints remain\n",
+			a.stats.hvnMerged, len(a.constraints))
+	}
+}
+
+// markIndirect marks the size nodes starting at id as indirect.
+func (h *hvn) markIndirect(id nodeid, size uint32) {
+	for i := uint32(0); i < size; i++ {
+		h.indirect[id+nodeid(i)] = true
+	}
+}
+
+// findSCCs computes the strongly connected components of the copy
+// graph, in an order such that the sources of each node's copy
+// edges precede it, unless they are in the same component.
+//
+func (h *hvn) findSCCs() {
+	index := make([]int, h.N) // 1-based; zero means "not yet visited"
+	lowlink := make([]int, h.N)
+	onstack := make([]bool, h.N)
+	var stack []nodeid
+	var nextIndex int
+
+	var visit func(x nodeid)
+	visit = func(x nodeid) {
+		nextIndex++
+		index[x] = nextIndex
+		lowlink[x] = nextIndex
+		stack = append(stack, x)
+		onstack[x] = true
+
+		for _, y := range h.deps[x] {
+			if index[y] == 0 {
+				visit(y)
+				if lowlink[y] < lowlink[x] {
+					lowlink[x] = lowlink[y]
+				}
+			} else if onstack[y] && index[y] < lowlink[x] {
+				lowlink[x] = index[y]
+			}
+		}
+
+		if lowlink[x] == index[x] {
+			i := len(stack) - 1
+			for stack[i] != x {
+				i--
+			}
+			scc := make([]nodeid, len(stack)-i)
+			copy(scc, stack[i:])
+			stack = stack[:i]
+			for _, y := range scc {
+				onstack[y] = false
+			}
+			h.sccs = append(h.sccs, scc)
+		}
+	}
+	for x := 1; x < h.N; x++ {
+		if index[x] == 0 {
+			visit(nodeid(x))
+		}
+	}
+}
+
+// labelNodes computes a PE label for each node, given the labels
+// computed by the previous iteration (or nil), and returns the
+// labels and the number of distinct non-zero labels.
+//
+func (h *hvn) labelNodes(prev []peLabel) ([]peLabel, int) {
+	label := make([]peLabel, h.N)
+	var nlabels int
+	fresh := func() peLabel {
+		nlabels++
+		return peLabel(nlabels)
+	}
+	baseLabels := make(map[baseKey]peLabel)
+	setLabels := make(map[string]peLabel)
+
+	var set []peLabel
+	for _, scc := range h.sccs {
+		set = set[:0]
+		indirect := false
+		for _, x := range scc {
+			if h.indirect[x] {
+				indirect = true
+				break
+			}
+			for _, y := range h.deps[x] {
+				if l := label[y]; l != 0 {
+					set = append(set, l) // (y in scc is not yet labelled)
+				}
+			}
+			for _, k := range h.bases[x] {
+				if k.kind != 'a' && prev != nil {
+					// Key loads and address computations
+					// by the label of the pointer.
+					if prev[k.id] == 0 {
+						continue // y points to nothing
+					}
+					k.id = nodeid(prev[k.id])
+				}
+				l, ok := baseLabels[k]
+				if !ok {
+					l = fresh()
+					baseLabels[k] = l
+				}
+				set = append(set, l)
+			}
+		}
+
+		var l peLabel
+		if indirect {
+			l = fresh()
+		} else {
+			set = sortUniq(set)
+			switch len(set) {
+			case 0:
+				// points to nothing
+			case 1:
+				l = set[0]
+			default:
+				key := fmt.Sprint(set)
+				var ok bool
+				if l, ok = setLabels[key]; !ok {
+					l = fresh()
+					setLabels[key] = l
+				}
+			}
+		}
+		for _, x := range scc {
+			label[x] = l
+		}
+	}
+	return label, nlabels
+}
+
+type peLabels []peLabel
+
+func (s peLabels) Len() int           { return len(s) }
+func (s peLabels) Less(i, j int) bool { return s[i] < s[j] }
+func (s peLabels) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// sortUniq sorts set and removes duplicates, in place.
+func sortUniq(set []peLabel) []peLabel {
+	sort.Sort(peLabels(set))
+	out := set[:0]
+	for i, l := range set {
+		if i == 0 || l != set[i-1] {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// hcdOffline performs the offline part of hybrid cycle detection.
+//
+// It finds the strongly connected components of the offline
+// constraint graph, which has a node for each (representative) node
+// x, a "ref" node for its dereference *x, and an edge for each copy,
+// and each load and store at offset zero.  If a direct node y and a
+// ref node *p lie on a cycle that passes through no other ref node,
+// then every member of pts(p) will be in a cycle with y, so the solver
+// may unify them as soon as they appear.  (Cycles through several ref
+// nodes are not used, since they are real only if the points-to sets
+// of all of them are non-empty; unifying their nodes would thus lose
+// precision.)
+//
+func (a *analysis) hcdOffline() {
+	N := nodeid(len(a.nodes))
+	succs := make(map[nodeid][]nodeid) // ref node for x is N+x
+	preds := make(map[nodeid][]nodeid)
+	addEdge := func(x, y nodeid) {
+		succs[x] = append(succs[x], y)
+		preds[y] = append(preds[y], x)
+	}
+	for _, c := range a.constraints {
+		switch c := c.(type) {
+		case *copyConstraint:
+			addEdge(a.find(c.src), a.find(c.dst))
+		case *loadConstraint:
+			if c.offset == 0 {
+				addEdge(N+a.find(c.src), a.find(c.dst))
+			}
+		case *storeConstraint:
+			if c.offset == 0 {
+				addEdge(a.find(c.src), N+a.find(c.dst))
+			}
+		}
+	}
+
+	// reach returns the set of nodes of scc reachable from ref
+	// node r along the specified edges, avoiding other ref nodes.
+	reach := func(r nodeid, scc map[nodeid]bool, edges map[nodeid][]nodeid) map[nodeid]bool {
+		seen := map[nodeid]bool{r: true}
+		q := []nodeid{r}
+		for len(q) > 0 {
+			x := q[len(q)-1]
+			q = q[:len(q)-1]
+			for _, y := range edges[x] {
+				if scc[y] && !seen[y] && y < N {
+					seen[y] = true
+					q = append(q, y)
+				}
+			}
+		}
+		return seen
+	}
+
+	index := make(map[nodeid]int)
+	lowlink := make(map[nodeid]int)
+	onstack := make(map[nodeid]bool)
+	var stack []nodeid
+
+	var visit func(x nodeid)
+	visit = func(x nodeid) {
+		index[x] = len(index) + 1
+		lowlink[x] = index[x]
+		stack = append(stack, x)
+		onstack[x] = true
+
+		for _, y := range succs[x] {
+			if index[y] == 0 {
+				visit(y)
+				if lowlink[y] < lowlink[x] {
+					lowlink[x] = lowlink[y]
+				}
+			} else if onstack[y] && index[y] < lowlink[x] {
+				lowlink[x] = index[y]
+			}
+		}
+
+		if lowlink[x] == index[x] {
+			i := len(stack) - 1
+			for stack[i] != x {
+				i--
+			}
+			scc := stack[i:]
+			stack = stack[:i]
+
+			var refs, direct []nodeid
+			for _, y := range scc {
+				onstack[y] = false
+				if y >= N {
+					refs = append(refs, y-N)
+				} else {
+					direct = append(direct, y)
+				}
+			}
+			if len(refs) == 0 {
+				// A cycle of copy edges.
+				for _, y := range direct[1:] {
+					a.unify(direct[0], y)
+					a.stats.hcdMerged++
+				}
+			} else if len(direct) > 0 {
+				if a.hcd == nil {
+					a.hcd = make(map[nodeid]nodeid)
+				}
+				if len(refs) == 1 {
+					a.hcd[refs[0]] = direct[0]
+				} else {
+					members := make(map[nodeid]bool)
+					for _, y := range scc {
+						members[y] = true
+					}
+					for _, r := range refs {
+						fwd := reach(N+r, members, succs)
+						for y := range reach(N+r, members, preds) {
+							if y < N && fwd[y] {
+								a.hcd[r] = y
+								break
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	for x := range succs {
+		if index[x] == 0 {
+			visit(x)
+		}
+	}
+
+	if a.log != nil {
+		fmt.Fprintf(a.log, "HCD: %d pointers with offline cycles\n", len(a.hcd))
+	}
+}
+
+// ---- presolve methods of each constraint ----
+
+func (c *addrConstraint) presolve(h *hvn) {
+	h.bases[c.dst] = append(h.bases[c.dst], baseKey{'a', c.src, 0})
+}
+
+func (c *copyConstraint) presolve(h *hvn) {
+	h.deps[c.dst] = append(h.deps[c.dst], c.src)
+}
+
+func (c *loadConstraint) presolve(h *hvn) {
+	h.bases[c.dst] = append(h.bases[c.dst], baseKey{'l', c.src, c.offset})
+}
+
+func (c *storeConstraint) presolve(h *hvn) {
+	// Stores affect only the nodes of objects, which are indirect.
+}
+
+func (c *offsetAddrConstraint) presolve(h *hvn) {
+	h.bases[c.dst] = append(h.bases[c.dst], baseKey{'o', c.src, c.offset})
+}
+
+func (c *typeAssertConstraint) presolve(h *hvn) {
+	h.markIndirect(c.dst, h.a.sizeof(c.typ))
+}
+
+func (c *invokeConstraint) presolve(h *hvn) {
+	// The solver adds labels to the targets node and copy edges
+	// to the results block.
+	sig := c.method.Type().(*types.Signature)
+	h.markIndirect(c.params, 1)
+	h.markIndirect(c.params+1+nodeid(h.a.sizeof(sig.Params())), h.a.sizeof(sig.Results()))
+}
@@ -59,10 +59,14 @@ func (c *rVInterfaceConstraint) ptr() nodeid {
 	return c.v
 }
 
-func (c *rVInterfaceConstraint) solve(a *analysis, _ *node, delta nodeset) {
-	resultPts := &a.nodes[c.result].pts
+func (c *rVInterfaceConstraint) presolve(h *hvn) {
+	h.markIndirect(c.result, 1)
+}
+
+func (c *rVInterfaceConstraint) solve(a *analysis, _ *node, delta []nodeid) {
+	resultPts := &a.nodes[a.find(c.result)].pts
 	changed := false
-	for vObj := range delta {
+	for _, vObj := range delta {
 		tDyn, _, indirect := a.taggedValue(vObj)
 		if tDyn == nil {
 			panic("not a tagged object")
@@ -106,9 +110,13 @@ func (c *rVMapIndexConstraint) ptr() nodeid {
 	return c.v
 }
 
-func (c *rVMapIndexConstraint) solve(a *analysis, _ *node, delta nodeset) {
+func (c *rVMapIndexConstraint) presolve(h *hvn) {
+	h.markIndirect(c.result, 1)
+}
+
+func (c *rVMapIndexConstraint) solve(a *analysis, _ *node, delta []nodeid) {
 	changed := false
-	for vObj := range delta {
+	for _, vObj := range delta {
 		tDyn, m, indirect := a.taggedValue(vObj)
 		tMap, _ := tDyn.Underlying().(*types.Map)
 		if tMap == nil {
@@ -156,9 +164,13 @@ func (c *rVMapKeysConstraint) ptr() nodeid {
 	return c.v
 }
 
-func (c *rVMapKeysConstraint) solve(a *analysis, _ *node, delta nodeset) {
+func (c *rVMapKeysConstraint) presolve(h *hvn) {
+	h.markIndirect(c.result, 1)
+}
+
+func (c *rVMapKeysConstraint) solve(a *analysis, _ *node, delta []nodeid) {
 	changed := false
-	for vObj := range delta {
+	for _, vObj := range delta {
 		tDyn, m, indirect := a.taggedValue(vObj)
 		tMap, _ := tDyn.Underlying().(*types.Map)
 		if tMap == nil {
@@ -215,9 +227,13 @@ func (c *rVRecvConstraint) ptr() nodeid {
 	return c.v
 }
 
-func (c *rVRecvConstraint) solve(a *analysis, _ *node, delta nodeset) {
+func (c *rVRecvConstraint) presolve(h *hvn) {
+	h.markIndirect(c.result, 1)
+}
+
+func (c *rVRecvConstraint) solve(a *analysis, _ *node, delta []nodeid) {
 	changed := false
-	for vObj := range delta {
+	for _, vObj := range delta {
 		tDyn, ch, indirect := a.taggedValue(vObj)
 		tChan, _ := tDyn.Underlying().(*types.Chan)
 		if tChan == nil {
@@ -266,8 +282,12 @@ func (c *rVSendConstraint) ptr() nodeid {
 	return c.v
 }
 
-func (c *rVSendConstraint) solve(a *analysis, _ *node, delta nodeset) {
-	for vObj := range delta {
+func (c *rVSendConstraint) presolve(h *hvn) {
+	// Stores affect only the nodes of objects, which are indirect.
+}
+
+func (c *rVSendConstraint) solve(a *analysis, _ *node, delta []nodeid) {
+	for _, vObj := range delta {
 		tDyn, ch, indirect := a.taggedValue(vObj)
 		tChan, _ := tDyn.Underlying().(*types.Chan)
 		if tChan == nil {
@@ -317,8 +337,12 @@ func (c *rVSetMapIndexConstraint) ptr() nodeid {
 	return c.v
 }
 
-func (c *rVSetMapIndexConstraint) solve(a *analysis, _ *node, delta nodeset) {
-	for vObj := range delta {
+func (c *rVSetMapIndexConstraint) presolve(h *hvn) {
+	// Stores affect only the nodes of objects, which are indirect.
+}
+
+func (c *rVSetMapIndexConstraint) solve(a *analysis, _ *node, delta []nodeid) {
+	for _, vObj := range delta {
 		tDyn, m, indirect := a.taggedValue(vObj)
 		tMap, _ := tDyn.Underlying().(*types.Map)
 		if tMap == nil {
@@ -381,9 +405,13 @@ func (c *reflectChanOfConstraint) ptr() nodeid {
 	return c.t
 }
 
-func (c *reflectChanOfConstraint) solve(a *analysis, _ *node, delta nodeset) {
+func (c *reflectChanOfConstraint) presolve(h *hvn) {
+	h.markIndirect(c.result, 1)
+}
+
+func (c *reflectChanOfConstraint) solve(a *analysis, _ *node, delta []nodeid) {
 	changed := false
-	for tObj := range delta {
+	for _, tObj := range delta {
 		T := a.rtypeTaggedValue(tObj)
 
 		for _, dir := range c.dirs {
@@ -445,9 +473,13 @@ func (c *reflectIndirectConstraint) ptr() nodeid {
 	return c.v
 }
 
-func (c *reflectIndirectConstraint) solve(a *analysis, _ *node, delta nodeset) {
+func (c *reflectIndirectConstraint) presolve(h *hvn) {
+	h.markIndirect(c.result, 1)
+}
+
+func (c *reflectIndirectConstraint) solve(a *analysis, _ *node, delta []nodeid) {
 	changed := false
-	for vObj := range delta {
+	for _, vObj := range delta {
 		tDyn, _, _ := a.taggedValue(vObj)
 		if tDyn == nil {
 			panic("not a tagged value")
@@ -497,9 +529,13 @@ func (c *reflectMakeChanConstraint) ptr() nodeid {
 	return c.typ
 }
 
-func (c *reflectMakeChanConstraint) solve(a *analysis, _ *node, delta nodeset) {
+func (c *reflectMakeChanConstraint) presolve(h *hvn) {
+	h.markIndirect(c.result, 1)
+}
+
+func (c *reflectMakeChanConstraint) solve(a *analysis, _ *node, delta []nodeid) {
 	changed := false
-	for typObj := range delta {
+	for _, typObj := range delta {
 		T := a.rtypeTaggedValue(typObj)
 		tChan, ok := T.Underlying().(*types.Chan)
 		if !ok || tChan.Dir() != ast.SEND|ast.RECV {
@@ -551,9 +587,13 @@ func (c *reflectMakeMapConstraint) ptr() nodeid {
 	return c.typ
 }
 
-func (c *reflectMakeMapConstraint) solve(a *analysis, _ *node, delta nodeset) {
+func (c *reflectMakeMapConstraint) presolve(h *hvn) {
+	h.markIndirect(c.result, 1)
+}
+
+func (c *reflectMakeMapConstraint) solve(a *analysis, _ *node, delta []nodeid) {
 	changed := false
-	for typObj := range delta {
+	for _, typObj := range delta {
 		T := a.rtypeTaggedValue(typObj)
 		tMap, ok := T.Underlying().(*types.Map)
 		if !ok {
@@ -607,9 +647,13 @@ func (c *reflectNewConstraint) ptr() nodeid {
 	return c.typ
 }
 
-func (c *reflectNewConstraint) solve(a *analysis, _ *node, delta nodeset) {
+func (c *reflectNewConstraint) presolve(h *hvn) {
+	h.markIndirect(c.result, 1)
+}
+
+func (c *reflectNewConstraint) solve(a *analysis, _ *node, delta []nodeid) {
 	changed := false
-	for typObj := range delta {
+	for _, typObj := range delta {
 		T := a.rtypeTaggedValue(typObj)
 
 		// allocate new T object
@@ -669,9 +713,13 @@ func (c *reflectTypeOfConstraint) ptr() nodeid {
 	return c.i
 }
 
-func (c *reflectTypeOfConstraint) solve(a *analysis, _ *node, delta nodeset) {
+func (c *reflectTypeOfConstraint) presolve(h *hvn) {
+	h.markIndirect(c.result, 1)
+}
+
+func (c *reflectTypeOfConstraint) solve(a *analysis, _ *node, delta []nodeid) {
 	changed := false
-	for iObj := range delta {
+	for _, iObj := range delta {
 		tDyn, _, _ := a.taggedValue(iObj)
 		if tDyn == nil {
 			panic("not a tagged value")
@@ -720,9 +768,13 @@ func (c *reflectZeroConstraint) ptr() nodeid {
 	return c.typ
 }
 
-func (c *reflectZeroConstraint) solve(a *analysis, _ *node, delta nodeset) {
+func (c *reflectZeroConstraint) presolve(h *hvn) {
+	h.markIndirect(c.result, 1)
+}
+
+func (c *reflectZeroConstraint) solve(a *analysis, _ *node, delta []nodeid) {
 	changed := false
-	for typObj := range delta {
+	for _, typObj := range delta {
 		T := a.rtypeTaggedValue(typObj)
 
 		// memoize using a.reflectZeros[T]
@@ -769,13 +821,17 @@ func (c *rtypeElemConstraint) ptr() nodeid {
 	return c.t
 }
 
-func (c *rtypeElemConstraint) solve(a *analysis, _ *node, delta nodeset) {
+func (c *rtypeElemConstraint) presolve(h *hvn) {
+	h.markIndirect(c.result, 1)
+}
+
+func (c *rtypeElemConstraint) solve(a *analysis, _ *node, delta []nodeid) {
 	// Implemented by *types.{Map,Chan,Array,Slice,Pointer}.
 	type hasElem interface {
 		Elem() types.Type
 	}
 	changed := false
-	for tObj := range delta {
+	for _, tObj := range delta {
 		T := a.nodes[tObj].obj.data.(types.Type)
 		if tHasElem, ok := T.Underlying().(hasElem); ok {
 			if a.addLabel(c.result, a.makeRtype(tHasElem.Elem())) {
@@ -819,9 +875,13 @@ func (c *rtypeInOutConstraint) ptr() nodeid {
 	return c.t
 }
 
-func (c *rtypeInOutConstraint) solve(a *analysis, _ *node, delta nodeset) {
+func (c *rtypeInOutConstraint) presolve(h *hvn) {
+	h.markIndirect(c.result, 1)
+}
+
+func (c *rtypeInOutConstraint) solve(a *analysis, _ *node, delta []nodeid) {
 	changed := false
-	for tObj := range delta {
+	for _, tObj := range delta {
 		T := a.nodes[tObj].obj.data.(types.Type)
 		sig, ok := T.Underlying().(*types.Signature)
 		if !ok {
@@ -883,9 +943,13 @@ func (c *rtypeKeyConstraint) ptr() nodeid {
 	return c.t
 }
 
-func (c *rtypeKeyConstraint) solve(a *analysis, _ *node, delta nodeset) {
+func (c *rtypeKeyConstraint) presolve(h *hvn) {
+	h.markIndirect(c.result, 1)
+}
+
+func (c *rtypeKeyConstraint) solve(a *analysis, _ *node, delta []nodeid) {
 	changed := false
-	for tObj := range delta {
+	for _, tObj := range delta {
 		T := a.nodes[tObj].obj.data.(types.Type)
 		if tMap, ok := T.Underlying().(*types.Map); ok {
 			if a.addLabel(c.result, a.makeRtype(tMap.Key())) {
@@ -926,6 +990,10 @@ func (c *rtypeMethodByNameConstraint) ptr() nodeid {
 	return c.t
 }
 
+func (c *rtypeMethodByNameConstraint) presolve(h *hvn) {
+	h.markIndirect(c.result+3, 2) // the Type and Func fields; see addMethod
+}
+
 func (c *rtypeMethodByNameConstraint) addMethod(a *analysis, meth *types.Selection) {
 	// type Method struct {
 	// 0     __identity__
@@ -959,8 +1027,8 @@ func changeRecv(sig *types.Signature) *types.Signature {
 	return types.NewSignature(nil, nil, types.NewTuple(p2...), sig.Results(), sig.IsVariadic())
 }
 
-func (c *rtypeMethodByNameConstraint) solve(a *analysis, _ *node, delta nodeset) {
-	for tObj := range delta {
+func (c *rtypeMethodByNameConstraint) solve(a *analysis, _ *node, delta []nodeid) {
+	for _, tObj := range delta {
 		T := a.nodes[tObj].obj.data.(types.Type)
 
 		// We don't use Lookup(c.name) when c.name != "" to avoid
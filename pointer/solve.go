@@ -4,8 +4,28 @@
 
 package pointer
 
-// This file defines a naive Andersen-style solver for the inclusion
-// constraint system.
+// This file defines an Andersen-style solver for the inclusion
+// constraint system, using difference propagation.
+//
+// Nodes that must have equal points-to sets are merged into
+// equivalence classes, represented by a union-find structure (see
+// find and unify).  All solver state (pts, prevPts, copyTo, complex)
+// resides in the representative node of each class.  Nodes are
+// merged offline by HVN (see presolve.go) and online by two forms of
+// cycle detection:
+//
+// - Hybrid cycle detection (HCD): the pre-solver finds cycles in the
+//   offline constraint graph that pass through a dereference *p; when
+//   the solver visits p, it unifies each member of pts(p) with the
+//   rest of the cycle.
+//
+// - Lazy cycle detection (LCD): when the solver propagates along a
+//   copy edge x→y and finds pts(x) = pts(y), it suspects a cycle and
+//   searches for one starting at y, collapsing any it finds.
+//   Each edge is checked at most once.
+//
+// Reference: Hardekopf & Lin, PLDI'07: "The Ant and the Grasshopper:
+// fast and accurate pointer analysis for millions of lines of code".
 
 import (
 	"fmt"
@@ -29,6 +49,7 @@ func (a *analysis) solve() {
 		if id == empty {
 			break
 		}
+		id = a.find(id)
 		if a.log != nil {
 			fmt.Fprintf(a.log, "\tnode n%d\n", id)
 		}
@@ -37,13 +58,34 @@ func (a *analysis) solve() {
 
 		// Difference propagation.
 		delta := n.pts.diff(n.prevPts)
-		if delta == nil {
+		if delta.isEmpty() {
 			continue
 		}
+
+		// HCD: unify each new member of pts(n) with the
+		// node with which it must be in a cycle.
+		if b, ok := a.hcd[id]; ok {
+			for _, v := range delta.appendTo(nil) {
+				if a.find(v) != a.find(b) {
+					a.unify(b, v)
+					a.stats.hcdMerged++
+				}
+			}
+			if id != a.find(id) {
+				// n itself was merged; its representative
+				// is now on the worklist.
+				continue
+			}
+			delta = n.pts.diff(n.prevPts)
+			if delta.isEmpty() {
+				continue
+			}
+		}
+
 		n.prevPts = n.pts.clone()
 
 		// Apply all resolution rules attached to n.
-		a.solveConstraints(n, delta)
+		a.solveConstraints(id, delta)
 
 		if a.log != nil {
 			fmt.Fprintf(a.log, "\t\tpts(n%d) = %s\n", id, n.pts)
@@ -69,14 +111,14 @@ func (a *analysis) processNewConstraints() {
 	// Initialize points-to sets from addr-of (base) constraints.
 	for _, c := range constraints {
 		if c, ok := c.(*addrConstraint); ok {
-			dst := a.nodes[c.dst]
+			dst := a.nodes[a.find(c.dst)]
 			dst.pts.add(c.src)
 
 			// Populate the worklist with nodes that point to
 			// something initially (due to addrConstraints) and
 			// have other constraints attached.
 			// (A no-op in round 1.)
-			if dst.copyTo != nil || dst.complex != nil {
+			if !dst.copyTo.isEmpty() || dst.complex != nil {
 				a.addWork(c.dst)
 			}
 		}
@@ -92,63 +134,210 @@ func (a *analysis) processNewConstraints() {
 			continue
 		case *copyConstraint:
 			// simple (copy) constraint
-			id = c.src
-			a.nodes[id].copyTo.add(c.dst)
+			id = a.find(c.src)
+			dst := a.find(c.dst)
+			if id == dst {
+				continue // trivial
+			}
+			a.nodes[id].copyTo.add(dst)
 		default:
 			// complex constraint
-			id = c.ptr()
+			id = a.find(c.ptr())
 			a.nodes[id].complex.add(c)
 		}
 
-		if n := a.nodes[id]; len(n.pts) > 0 {
-			if len(n.prevPts) > 0 {
+		if n := a.nodes[id]; !n.pts.isEmpty() {
+			if !n.prevPts.isEmpty() {
 				stale.add(id)
 			}
 			a.addWork(id)
 		}
 	}
 	// Apply new constraints to pre-existing PTS labels.
-	for id := range stale {
-		n := a.nodes[id]
-		a.solveConstraints(n, n.prevPts)
+	for _, id := range stale.appendTo(nil) {
+		id = a.find(id)
+		a.solveConstraints(id, a.nodes[id].prevPts)
 	}
 }
 
-// solveConstraints applies each resolution rule attached to node n to
-// the set of labels delta.  It may generate new constraints in
-// a.constraints.
+// solveConstraints applies each resolution rule attached to node id
+// (a representative) to the set of labels delta.  It may generate
+// new constraints in a.constraints.
 //
-func (a *analysis) solveConstraints(n *node, delta nodeset) {
-	if delta == nil {
+func (a *analysis) solveConstraints(id nodeid, delta nodeset) {
+	if delta.isEmpty() {
 		return
 	}
+	n := a.nodes[id]
 
 	// Process complex constraints dependent on n.
-	for c := range n.complex {
-		if a.log != nil {
-			fmt.Fprintf(a.log, "\t\tconstraint %s\n", c)
+	if n.complex != nil {
+		// The elements of delta are enumerated into a buffer
+		// that is reused across calls.
+		a.deltaSpace = delta.appendTo(a.deltaSpace[:0])
+		for c := range n.complex {
+			if a.log != nil {
+				fmt.Fprintf(a.log, "\t\tconstraint %s\n", c)
+			}
+			// TODO(adonovan): parameter n is never used.  Remove?
+			c.solve(a, n, a.deltaSpace)
 		}
-		// TODO(adonovan): parameter n is never used.  Remove?
-		c.solve(a, n, delta)
 	}
 
 	// Process copy constraints.
 	var copySeen nodeset
-	for mid := range n.copyTo {
-		if copySeen.add(mid) {
-			if a.nodes[mid].pts.addAll(delta) {
-				a.addWork(mid)
+	var lcdRoots []nodeid
+	for _, mid := range n.copyTo.appendTo(nil) {
+		mid = a.find(mid)
+		if mid == id || !copySeen.add(mid) {
+			continue
+		}
+		m := a.nodes[mid]
+		if m.pts.addAll(delta) {
+			a.addWork(mid)
+		}
+
+		// LCD: equal points-to sets at both ends of an
+		// edge suggest a cycle.
+		if a.config.DisableOpts&OptLCD == 0 && m.pts.len() == n.pts.len() {
+			edge := [2]nodeid{id, mid}
+			if !a.lcdChecked[edge] && m.pts.equals(n.pts) {
+				if a.lcdChecked == nil {
+					a.lcdChecked = make(map[[2]nodeid]bool)
+				}
+				a.lcdChecked[edge] = true
+				lcdRoots = append(lcdRoots, mid)
+			}
+		}
+	}
+	for _, root := range lcdRoots {
+		a.collapseCycles(root)
+	}
+}
+
+// collapseCycles searches the graph of copy edges reachable from
+// node root, and unifies the nodes of each cycle it finds.
+// It uses Tarjan's algorithm for strongly connected components.
+//
+func (a *analysis) collapseCycles(root nodeid) {
+	index := make(map[nodeid]int)
+	lowlink := make(map[nodeid]int)
+	var stack []nodeid
+	onstack := make(map[nodeid]bool)
+	var sccs [][]nodeid
+
+	var visit func(x nodeid)
+	visit = func(x nodeid) {
+		index[x] = len(index)
+		lowlink[x] = index[x]
+		stack = append(stack, x)
+		onstack[x] = true
+
+		for _, y := range a.nodes[x].copyTo.appendTo(nil) {
+			y = a.find(y)
+			if _, ok := index[y]; !ok {
+				visit(y)
+				if lowlink[y] < lowlink[x] {
+					lowlink[x] = lowlink[y]
+				}
+			} else if onstack[y] && index[y] < lowlink[x] {
+				lowlink[x] = index[y]
+			}
+		}
+
+		if lowlink[x] == index[x] {
+			// x is the root of an SCC; pop it.
+			i := len(stack) - 1
+			for stack[i] != x {
+				i--
 			}
+			scc := stack[i:]
+			stack = stack[:i]
+			for _, y := range scc {
+				onstack[y] = false
+			}
+			if len(scc) > 1 {
+				sccs = append(sccs, scc)
+			}
+		}
+	}
+	visit(a.find(root))
+
+	for _, scc := range sccs {
+		for _, y := range scc[1:] {
+			a.unify(scc[0], y)
+			a.stats.lcdMerged++
 		}
 	}
 }
 
+// find returns the representative of the equivalence class of nodes
+// to which node id belongs.
+//
+func (a *analysis) find(id nodeid) nodeid {
+	root := id
+	for a.nodes[root].rep != root {
+		root = a.nodes[root].rep
+	}
+	// Path compression.
+	for id != root {
+		n := a.nodes[id]
+		id, n.rep = n.rep, root
+	}
+	return root
+}
+
+// unify merges the equivalence classes of nodes x and y, whose
+// points-to sets are thereafter equal, and returns the
+// representative of the merged class.
+//
+func (a *analysis) unify(x, y nodeid) nodeid {
+	x, y = a.find(x), a.find(y)
+	if x == y {
+		return x
+	}
+	if a.log != nil {
+		fmt.Fprintf(a.log, "\t\tunify n%d n%d\n", x, y)
+	}
+	nx, ny := a.nodes[x], a.nodes[y]
+	ny.rep = x
+
+	// Only the labels in both prevPts sets have been propagated
+	// along the edges of both nodes; all others must be
+	// propagated (again) from the merged node.
+	nx.prevPts = nx.prevPts.intersect(ny.prevPts)
+	nx.pts.addAll(ny.pts)
+	nx.copyTo.addAll(ny.copyTo)
+	for c := range ny.complex {
+		nx.complex.add(c)
+	}
+	ny.pts, ny.prevPts, ny.copyTo = nodeset{}, nodeset{}, nodeset{}
+	ny.complex = nil
+
+	// HCD: if both nodes had a cycle partner, so must they.
+	by, ok := a.hcd[y]
+	if ok {
+		delete(a.hcd, y)
+	}
+	if bx, okx := a.hcd[x]; ok && okx {
+		a.unify(bx, by)
+	} else if ok {
+		a.hcd[x] = by
+	}
+
+	if !nx.pts.isEmpty() {
+		a.addWork(x)
+	}
+	return a.find(x)
+}
+
 // addLabel adds label to the points-to set of ptr and reports whether the set grew.
 func (a *analysis) addLabel(ptr, label nodeid) bool {
-	return a.nodes[ptr].pts.add(label)
+	return a.nodes[a.find(ptr)].pts.add(label)
 }
 
 func (a *analysis) addWork(id nodeid) {
+	id = a.find(id)
 	a.work.add(id)
 	if a.log != nil {
 		fmt.Fprintf(a.log, "\t\twork: n%d\n", id)
@@ -188,6 +377,7 @@ func (c *invokeConstraint) ptr() nodeid {
 // It returns true if pts(dst) changed.
 //
 func (a *analysis) onlineCopy(dst, src nodeid) bool {
+	dst, src = a.find(dst), a.find(src)
 	if dst != src {
 		if nsrc := a.nodes[src]; nsrc.copyTo.add(dst) {
 			if a.log != nil {
@@ -216,9 +406,9 @@ func (a *analysis) onlineCopyN(dst, src nodeid, sizeof uint32) uint32 {
 	return sizeof
 }
 
-func (c *loadConstraint) solve(a *analysis, n *node, delta nodeset) {
+func (c *loadConstraint) solve(a *analysis, n *node, delta []nodeid) {
 	var changed bool
-	for k := range delta {
+	for _, k := range delta {
 		koff := k + nodeid(c.offset)
 		if a.onlineCopy(c.dst, koff) {
 			changed = true
@@ -229,8 +419,8 @@ func (c *loadConstraint) solve(a *analysis, n *node, delta nodeset) {
 	}
 }
 
-func (c *storeConstraint) solve(a *analysis, n *node, delta nodeset) {
-	for k := range delta {
+func (c *storeConstraint) solve(a *analysis, n *node, delta []nodeid) {
+	for _, k := range delta {
 		koff := k + nodeid(c.offset)
 		if a.onlineCopy(koff, c.src) {
 			a.addWork(koff)
@@ -238,19 +428,18 @@ func (c *storeConstraint) solve(a *analysis, n *node, delta nodeset) {
 	}
 }
 
-func (c *offsetAddrConstraint) solve(a *analysis, n *node, delta nodeset) {
-	dst := a.nodes[c.dst]
-	for k := range delta {
-		if dst.pts.add(k + nodeid(c.offset)) {
+func (c *offsetAddrConstraint) solve(a *analysis, n *node, delta []nodeid) {
+	for _, k := range delta {
+		if a.addLabel(c.dst, k+nodeid(c.offset)) {
 			a.addWork(c.dst)
 		}
 	}
 }
 
-func (c *typeAssertConstraint) solve(a *analysis, n *node, delta nodeset) {
+func (c *typeAssertConstraint) solve(a *analysis, n *node, delta []nodeid) {
 	tIface, _ := c.typ.Underlying().(*types.Interface)
 
-	for ifaceObj := range delta {
+	for _, ifaceObj := range delta {
 		tDyn, v, indirect := a.taggedValue(ifaceObj)
 		if tDyn == nil {
 			panic("not a tagged value")
@@ -282,8 +471,8 @@ func (c *typeAssertConstraint) solve(a *analysis, n *node, delta nodeset) {
 	}
 }
 
-func (c *invokeConstraint) solve(a *analysis, n *node, delta nodeset) {
-	for ifaceObj := range delta {
+func (c *invokeConstraint) solve(a *analysis, n *node, delta []nodeid) {
+	for _, ifaceObj := range delta {
 		tDyn, v, indirect := a.taggedValue(ifaceObj)
 		if tDyn == nil {
 			panic("not a tagged value")
@@ -314,9 +503,11 @@ func (c *invokeConstraint) solve(a *analysis, n *node, delta nodeset) {
 		}
 
 		// Make callsite's fn variable point to identity of
-		// concrete method.  (There's no need to add it to
-		// worklist since it never has attached constraints.)
-		a.addLabel(c.params, fnObj)
+		// concrete method.  (It never has attached constraints,
+		// but the node with which it was merged may.)
+		if a.addLabel(c.params, fnObj) {
+			a.addWork(c.params)
+		}
 
 		// Extract value and connect to method's receiver.
 		// Copy payload to method's receiver param (arg0).
@@ -339,10 +530,10 @@ func (c *invokeConstraint) solve(a *analysis, n *node, delta nodeset) {
 	}
 }
 
-func (c *addrConstraint) solve(a *analysis, n *node, delta nodeset) {
+func (c *addrConstraint) solve(a *analysis, n *node, delta []nodeid) {
 	panic("addr is not a complex constraint")
 }
 
-func (c *copyConstraint) solve(a *analysis, n *node, delta nodeset) {
+func (c *copyConstraint) solve(a *analysis, n *node, delta []nodeid) {
 	panic("copy is not a complex constraint")
 }
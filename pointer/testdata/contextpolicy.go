@@ -0,0 +1,42 @@
+// +build ignore
+
+package main
+
+// Test of the context-sensitivity policies of Config.Context.
+// The expected results are in context_test.go.
+
+var a, b int
+
+func id(x *int) *int { return x }
+
+func id2(x *int) *int { return id(x) }
+
+func rec(x *int, n int) *int {
+	if n > 0 {
+		return rec(x, n-1)
+	}
+	return x
+}
+
+func show(x *int) {
+	print(x) // @line show
+}
+
+type U struct{ p *int }
+
+func (u *U) Init() { u.p = new(int) }
+
+func main() {
+	print(id2(&a)) // @line id2a
+	print(id2(&b)) // @line id2b
+
+	print(rec(&a, 1)) // @line reca
+
+	show(&a)
+	show(&b)
+
+	u := new(U)
+	u.Init()
+	u.Init()
+	print(u.p) // @line up
+}
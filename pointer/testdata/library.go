@@ -0,0 +1,56 @@
+// +build ignore
+
+package library
+
+// Test of the analysis of library packages: the exported functions
+// and methods are entry points whose parameters point to objects of
+// unknown origin.  The expected results are in library_test.go.
+
+type T struct {
+	next *T
+	m    map[string][]*int
+}
+
+func (t *T) Next() *T {
+	print(t.next) // @line next
+	return t.next
+}
+
+func Lookup(t *T, k string) *int {
+	for _, p := range t.m[k] {
+		print(p) // @line lookup
+	}
+	return nil
+}
+
+func Local() {
+	x := new(int)
+	unexported(x)
+}
+
+func unexported(x *int) {
+	print(x) // @line unexported
+}
+
+type Shape interface {
+	Area() *int
+}
+
+type Square struct {
+	side *int
+}
+
+func (s *Square) Area() *int {
+	return s.side
+}
+
+func Measure(s Shape) *int {
+	print(s) // @line shape
+	a := s.Area()
+	print(a) // @line area
+	return a
+}
+
+func Apply(f func() *int) *int {
+	return f()
+}
@@ -6,6 +6,7 @@ package pointer
 
 import (
 	"bytes"
+	"container/heap"
 	"fmt"
 
 	"code.google.com/p/go.tools/go/types"
@@ -199,70 +200,6 @@ func sliceToArray(slice types.Type) *types.Array {
 	return types.NewArray(slice.Underlying().(*types.Slice).Elem(), 1)
 }
 
-// Node set -------------------------------------------------------------------
-
-// NB, mutator methods are attached to *nodeset.
-// nodeset may be a reference, but its address matters!
-type nodeset map[nodeid]struct{}
-
-// ---- Accessors ----
-
-func (ns nodeset) String() string {
-	var buf bytes.Buffer
-	buf.WriteRune('{')
-	var sep string
-	for n := range ns {
-		fmt.Fprintf(&buf, "%sn%d", sep, n)
-		sep = ", "
-	}
-	buf.WriteRune('}')
-	return buf.String()
-}
-
-// diff returns the set-difference x - y.  nil => empty.
-//
-// TODO(adonovan): opt: extremely inefficient.  BDDs do this in
-// constant time.  Sparse bitvectors are linear but very fast.
-func (x nodeset) diff(y nodeset) nodeset {
-	var z nodeset
-	for k := range x {
-		if _, ok := y[k]; !ok {
-			z.add(k)
-		}
-	}
-	return z
-}
-
-// clone() returns an unaliased copy of x.
-func (x nodeset) clone() nodeset {
-	return x.diff(nil)
-}
-
-// ---- Mutators ----
-
-func (ns *nodeset) add(n nodeid) bool {
-	sz := len(*ns)
-	if *ns == nil {
-		*ns = make(nodeset)
-	}
-	(*ns)[n] = struct{}{}
-	return len(*ns) > sz
-}
-
-func (x *nodeset) addAll(y nodeset) bool {
-	if y == nil {
-		return false
-	}
-	sz := len(*x)
-	if *x == nil {
-		*x = make(nodeset)
-	}
-	for n := range y {
-		(*x)[n] = struct{}{}
-	}
-	return len(*x) > sz
-}
-
 // Constraint set -------------------------------------------------------------
 
 type constraintset map[constraint]struct{}
@@ -285,9 +222,26 @@ type worklist interface {
 	take() nodeid // Takes a node from the set and returns it, or empty
 }
 
+// makeWorklist returns a new, empty worklist of the specified order.
+func makeWorklist(order WorklistOrder) worklist {
+	switch order {
+	case MapWorklist:
+		return makeMapWorklist()
+	case FIFOWorklist:
+		return &queueWorklist{}
+	case LIFOWorklist:
+		return &queueWorklist{lifo: true}
+	case NodeIDWorklist:
+		return &heapWorklist{}
+	case LRFWorklist:
+		return &heapWorklist{lrf: true}
+	}
+	panic(fmt.Sprintf("invalid worklist order: %d", order))
+}
+
 // Simple nondeterministic worklist based on a built-in map.
 type mapWorklist struct {
-	set nodeset
+	set map[nodeid]struct{}
 }
 
 func (w *mapWorklist) add(n nodeid) {
@@ -303,5 +257,102 @@ func (w *mapWorklist) take() nodeid {
 }
 
 func makeMapWorklist() worklist {
-	return &mapWorklist{make(nodeset)}
+	return &mapWorklist{make(map[nodeid]struct{})}
+}
+
+// queueWorklist is a double-ended queue of nodes in insertion order,
+// with a fast membership test.  Nodes are taken from the front
+// (FIFO) or, if lifo is set, from the back.
+type queueWorklist struct {
+	lifo   bool
+	queue  []nodeid
+	head   int    // index of front of queue (FIFO only)
+	member []bool // member[n] iff n is in the queue
+}
+
+func (w *queueWorklist) add(n nodeid) {
+	for int(n) >= len(w.member) {
+		w.member = append(w.member, false)
+	}
+	if !w.member[n] {
+		w.member[n] = true
+		w.queue = append(w.queue, n)
+	}
+}
+
+func (w *queueWorklist) take() nodeid {
+	if w.head == len(w.queue) {
+		w.queue, w.head = w.queue[:0], 0
+		return empty
+	}
+	var n nodeid
+	if w.lifo {
+		n = w.queue[len(w.queue)-1]
+		w.queue = w.queue[:len(w.queue)-1]
+	} else {
+		n = w.queue[w.head]
+		w.head++
+		if w.head > 1024 && 2*w.head > len(w.queue) {
+			// Reclaim the space before the head.
+			w.queue = w.queue[:copy(w.queue, w.queue[w.head:])]
+			w.head = 0
+		}
+	}
+	w.member[n] = false
+	return n
+}
+
+// heapWorklist is a priority queue of nodes.  Nodes are taken in
+// ascending order of node id or, if lrf is set, least recently fired
+// (i.e. taken) first.
+type heapWorklist struct {
+	lrf    bool
+	heap   []nodeid
+	member []bool   // member[n] iff n is in the heap
+	fired  []uint32 // fired[n] is the time at which n was last taken (if lrf)
+	clock  uint32
+}
+
+func (w *heapWorklist) add(n nodeid) {
+	for int(n) >= len(w.member) {
+		w.member = append(w.member, false)
+		if w.lrf {
+			w.fired = append(w.fired, 0)
+		}
+	}
+	if !w.member[n] {
+		w.member[n] = true
+		heap.Push(w, n)
+	}
+}
+
+func (w *heapWorklist) take() nodeid {
+	if len(w.heap) == 0 {
+		return empty
+	}
+	n := heap.Pop(w).(nodeid)
+	w.member[n] = false
+	if w.lrf {
+		w.clock++
+		w.fired[n] = w.clock
+	}
+	return n
+}
+
+// heap.Interface methods.
+
+func (w *heapWorklist) Len() int      { return len(w.heap) }
+func (w *heapWorklist) Swap(i, j int) { w.heap[i], w.heap[j] = w.heap[j], w.heap[i] }
+func (w *heapWorklist) Less(i, j int) bool {
+	x, y := w.heap[i], w.heap[j]
+	if w.lrf && w.fired[x] != w.fired[y] {
+		return w.fired[x] < w.fired[y]
+	}
+	return x < y
+}
+func (w *heapWorklist) Push(x interface{}) { w.heap = append(w.heap, x.(nodeid)) }
+func (w *heapWorklist) Pop() interface{} {
+	n := w.heap[len(w.heap)-1]
+	w.heap = w.heap[:len(w.heap)-1]
+	return n
 }
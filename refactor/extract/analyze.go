@@ -0,0 +1,357 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package extract
+
+// This file computes the parameters, results and control transfers
+// of the selection, and rejects selections that cannot be extracted.
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+// analyze populates the params, outs, returns, branches and terminal
+// fields of e, or returns an error if the selection cannot be
+// extracted.
+//
+func (e *extractor) analyze() error {
+	if err := e.analyzeControl(); err != nil {
+		return err
+	}
+
+	// Classify each reference to a variable local to the
+	// enclosing declaration, and each reference after the
+	// selection to a variable declared within it.
+	free := make(map[*types.Var]bool)
+	defined := make(map[*types.Var]bool) // declared in selection, used after it
+	uses := make(map[*types.Var][]token.Pos)
+	for id, obj := range e.info.Objects {
+		if obj == nil || obj.Parent() == nil || !(e.decl.Pos() <= obj.Pos() && obj.Pos() < e.decl.End()) {
+			continue // not local (e.g. package-level, field, method)
+		}
+		if _, ok := obj.(*types.Label); ok {
+			continue // see analyzeControl
+		}
+		if id.Pos() == obj.Pos() {
+			continue // the declaration, not a reference
+		}
+		switch {
+		case e.contains(id.Pos()) && !e.contains(obj.Pos()):
+			v, ok := obj.(*types.Var)
+			if !ok {
+				return fmt.Errorf("selection refers to local %s declared outside it", obj)
+			}
+			free[v] = true
+
+		case !e.contains(id.Pos()) && e.contains(obj.Pos()):
+			v, ok := obj.(*types.Var)
+			if !ok {
+				return fmt.Errorf("%s is declared in the selection but used after it", obj)
+			}
+			defined[v] = true
+
+		case !e.contains(id.Pos()):
+			if v, ok := obj.(*types.Var); ok {
+				uses[v] = append(uses[v], id.Pos())
+			}
+		}
+	}
+
+	assigned, err := e.assignedVars(free)
+	if err != nil {
+		return err
+	}
+
+	for v := range free {
+		e.params = append(e.params, v)
+		if assigned[v] && e.liveAfter(v, uses[v]) {
+			e.outs = append(e.outs, v)
+		}
+	}
+	for v := range defined {
+		e.outs = append(e.outs, v)
+	}
+	sort.Sort(varsByPos(e.params))
+	sort.Sort(varsByPos(e.outs))
+
+	if e.terminal {
+		// Control never reaches the statement after the
+		// selection, so it needs no results other than those
+		// of the enclosing function.
+		e.outs = nil
+	}
+	return nil
+}
+
+// analyzeControl finds the statements of the selection that transfer
+// control out of it, and rejects those that cannot be extracted.
+//
+func (e *extractor) analyzeControl() error {
+	labels := make(map[string]bool) // labels declared within the selection
+	var stack []ast.Node            // enclosing nodes within the selection
+	var err error
+	for _, s := range e.stmts {
+		ast.Inspect(s, func(n ast.Node) bool {
+			if n == nil {
+				stack = stack[:len(stack)-1]
+				return true
+			}
+			if err != nil {
+				return false
+			}
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				return false // control cannot leave a function literal
+
+			case *ast.LabeledStmt:
+				labels[n.Label.Name] = true
+
+			case *ast.DeferStmt:
+				if !e.deferOK() {
+					err = fmt.Errorf("selection contains a defer statement, which would run at the end of the new function")
+				}
+
+			case *ast.CallExpr:
+				if id, ok := unparen(n.Fun).(*ast.Ident); ok && id.Name == "recover" {
+					if _, ok := e.info.ObjectOf(id).(*types.Builtin); ok {
+						err = fmt.Errorf("selection calls recover, which would not stop a panic in the new function")
+					}
+				}
+
+			case *ast.ReturnStmt:
+				if len(n.Results) == 0 && e.sig.Results().Len() > 0 {
+					err = fmt.Errorf("selection contains a return statement without results")
+				} else if len(n.Results) == 1 && e.sig.Results().Len() > 1 {
+					err = fmt.Errorf("selection contains a return of a multi-valued call")
+				}
+				e.returns = append(e.returns, n)
+
+			case *ast.BranchStmt:
+				switch n.Tok {
+				case token.GOTO:
+					err = fmt.Errorf("selection contains a goto statement")
+				case token.FALLTHROUGH:
+					err = fmt.Errorf("selection contains a fallthrough statement")
+				default:
+					if !isInnerBranch(n, stack, labels) {
+						e.branches = append(e.branches, n)
+					}
+				}
+			}
+			stack = append(stack, n)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// A goto outside the selection may refer to a label within it.
+	ast.Inspect(e.body, func(n ast.Node) bool {
+		if b, ok := n.(*ast.BranchStmt); ok && b.Tok == token.GOTO && !e.contains(b.Pos()) && labels[b.Label.Name] {
+			err = fmt.Errorf("goto at %s jumps into the selection", e.fset.Position(b.Pos()))
+		}
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Deferred calls in the enclosing function may observe
+	// variables updated by the selection before it returns.
+	if len(e.returns) > 0 {
+		ast.Inspect(e.body, func(n ast.Node) bool {
+			switch n.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.DeferStmt:
+				if !e.contains(n.Pos()) {
+					err = fmt.Errorf("selection contains a return statement, and the function defers a call at %s",
+						e.fset.Position(n.Pos()))
+				}
+			}
+			return err == nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, endsWithReturn := e.stmts[len(e.stmts)-1].(*ast.ReturnStmt)
+	e.terminal = endsWithReturn && e.branches == nil
+	return nil
+}
+
+// isInnerBranch reports whether the break or continue statement b,
+// whose enclosing nodes within the selection are stack, transfers
+// control to a statement within the selection.
+//
+func isInnerBranch(b *ast.BranchStmt, stack []ast.Node, labels map[string]bool) bool {
+	if b.Label != nil {
+		return labels[b.Label.Name]
+	}
+	for _, n := range stack {
+		switch n.(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			return true
+		case *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+			if b.Tok == token.BREAK {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// deferOK reports whether a call deferred by the selection would run
+// at the same time in the new function as in the enclosing one: the
+// selection must be the tail of the body of a function without
+// results.
+//
+func (e *extractor) deferOK() bool {
+	return e.blockNode == e.body &&
+		e.stmts[len(e.stmts)-1] == e.body.List[len(e.body.List)-1] &&
+		e.sig.Results().Len() == 0
+}
+
+// assignedVars returns the subset of the free variables that the
+// selection updates, either directly or through a field, array
+// element or method with a pointer receiver.  It returns an error if
+// the selection takes the address of a free variable, since the new
+// function would take the address of a copy.
+//
+func (e *extractor) assignedVars(free map[*types.Var]bool) (map[*types.Var]bool, error) {
+	assigned := make(map[*types.Var]bool)
+	update := func(x ast.Expr) {
+		if v := e.rootVar(x); v != nil && free[v] {
+			assigned[v] = true
+		}
+	}
+	var err error
+	for _, s := range e.stmts {
+		ast.Inspect(s, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				for _, lhs := range n.Lhs {
+					update(lhs)
+				}
+			case *ast.IncDecStmt:
+				update(n.X)
+			case *ast.RangeStmt:
+				if n.Tok == token.ASSIGN {
+					update(n.Key)
+					if n.Value != nil {
+						update(n.Value)
+					}
+				}
+			case *ast.UnaryExpr:
+				if n.Op == token.AND {
+					if v := e.rootVar(n.X); v != nil && free[v] {
+						err = fmt.Errorf("selection takes the address of %s", v.Name())
+					}
+				}
+			case *ast.SelectorExpr:
+				// A call of a method with a pointer receiver
+				// on an addressable value updates the value.
+				if sel := e.info.Selections[n]; sel != nil && sel.Kind() == types.MethodVal {
+					recv := sel.Obj().Type().(*types.Signature).Recv().Type()
+					if _, ok := recv.(*types.Pointer); ok {
+						if _, ok := sel.Recv().Underlying().(*types.Pointer); !ok {
+							update(n.X)
+						}
+					}
+				}
+			}
+			return err == nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return assigned, nil
+}
+
+// rootVar returns the variable whose storage contains that of the
+// addressable expression x, or nil if it is not a variable, or is
+// reached through a pointer, slice or map.
+//
+func (e *extractor) rootVar(x ast.Expr) *types.Var {
+	switch x := unparen(x).(type) {
+	case *ast.Ident:
+		v, _ := e.info.ObjectOf(x).(*types.Var)
+		return v
+	case *ast.SelectorExpr:
+		if sel := e.info.Selections[x]; sel != nil && sel.Kind() == types.FieldVal && !sel.Indirect() {
+			if _, ok := sel.Recv().Underlying().(*types.Pointer); !ok {
+				return e.rootVar(x.X)
+			}
+		}
+	case *ast.IndexExpr:
+		if _, ok := e.info.TypeOf(x.X).Underlying().(*types.Array); ok {
+			return e.rootVar(x.X)
+		}
+	}
+	return nil
+}
+
+// liveAfter reports whether the value of v, a free variable of the
+// selection with references outside it at uses, may be used after
+// the selection.  It is conservative.
+//
+func (e *extractor) liveAfter(v *types.Var, uses []token.Pos) bool {
+	// A result variable is used by the function's return.
+	results := e.sig.Results()
+	for i, n := 0, results.Len(); i < n; i++ {
+		if results.At(i) == v {
+			return true
+		}
+	}
+	// A variable declared outside an enclosing loop may be used
+	// in the next iteration.
+	if e.loop != nil && v.Pos() < e.loop.Pos() {
+		return true
+	}
+	for _, pos := range uses {
+		if pos > e.end {
+			return true
+		}
+	}
+	// A function literal referring to v may be called after the
+	// selection.
+	live := false
+	ast.Inspect(e.body, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.FuncLit); ok && !(lit.Pos() <= e.start && e.end <= lit.End()) {
+			for _, pos := range uses {
+				if lit.Pos() <= pos && pos < lit.End() {
+					live = true
+				}
+			}
+			return false
+		}
+		return !live
+	})
+	return live
+}
+
+// unparen returns e with any enclosing parentheses stripped.
+func unparen(e ast.Expr) ast.Expr {
+	for {
+		p, ok := e.(*ast.ParenExpr)
+		if !ok {
+			return e
+		}
+		e = p.X
+	}
+}
+
+type varsByPos []*types.Var
+
+func (a varsByPos) Len() int           { return len(a) }
+func (a varsByPos) Less(i, j int) bool { return a[i].Pos() < a[j].Pos() }
+func (a varsByPos) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
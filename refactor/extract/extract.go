@@ -0,0 +1,340 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package extract contains the implementation of the 'goextract' tool
+// whose command-line is provided by code.google.com/p/go.tools/cmd/goextract.
+//
+// The tool moves a selected sequence of statements into a new
+// package-level function, and replaces them by a call to it.  The free
+// variables of the selection become the parameters of the new
+// function; the variables it defines or updates that are needed after
+// it become its results.  Return, break and continue statements that
+// transfer control out of the selection are replaced by a return of a
+// code that the call site uses to perform the same transfer.
+//
+// The tool refuses selections that it cannot extract without changing
+// the meaning of the program, for example those that take the address
+// of a local variable, call recover, or defer a call that would run at
+// a different time.
+//
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"code.google.com/p/go.tools/go/types"
+	"code.google.com/p/go.tools/importer"
+	"code.google.com/p/go.tools/refactor/util"
+)
+
+// A Config specifies an extraction.
+type Config struct {
+	// Build is the go/build configuration for locating packages.
+	Build *build.Context
+
+	// Args specifies the packages to load, in
+	// importer.CreatePackageFromArgs syntax.
+	Args []string
+
+	// Pos is the extent of the statements to extract, of the form
+	// "foo.go:#123,#456".  It must consist of whole statements of a
+	// single block, plus any surrounding whitespace.
+	Pos string
+
+	// Name is the name of the new function.
+	Name string
+
+	// If Diff is set, the file is not modified, but the change is
+	// displayed as a unified diff to Out.
+	Diff bool
+
+	// Out receives the diff.
+	Out io.Writer
+}
+
+// writeFile is a seam for testing.
+var writeFile = func(filename string, content []byte) error {
+	return ioutil.WriteFile(filename, content, 0644)
+}
+
+// An extractor holds the state of an extraction.
+type extractor struct {
+	config *Config
+	fset   *token.FileSet
+	info   *importer.PackageInfo
+	file   *ast.File
+	src    []byte // content of file
+
+	stmts      []ast.Stmt       // the selected statements
+	start, end token.Pos        // extent of the selected statements
+	blockNode  ast.Node         // the enclosing block, case or comm clause
+	block      *types.Scope     // its scope
+	fn         ast.Node         // the innermost enclosing *ast.FuncDecl or *ast.FuncLit
+	body       *ast.BlockStmt   // its body
+	sig        *types.Signature // its type
+	decl       ast.Decl         // the enclosing package-level declaration
+	loop       ast.Node         // innermost loop within fn enclosing the selection, if any
+
+	params   []*types.Var      // free variables of the selection
+	outs     []*types.Var      // variables defined or updated by the selection and used after it
+	returns  []*ast.ReturnStmt // return statements of the selection
+	branches []*ast.BranchStmt // break and continue statements leaving the selection
+	terminal bool              // the selection ends with a return and has no branches
+}
+
+// Main extracts the statements specified by config into a new
+// function, and replaces them by a call to it.
+//
+func Main(config *Config) error {
+	if !util.IsValidIdentifier(config.Name) {
+		return fmt.Errorf("invalid function name %q", config.Name)
+	}
+
+	imp := importer.New(&importer.Config{Build: config.Build})
+	if _, rest, err := imp.LoadInitialPackages(config.Args); err != nil {
+		return err
+	} else if len(rest) > 0 {
+		return fmt.Errorf("surplus arguments: %q", rest)
+	}
+
+	start, end, err := parsePos(imp.Fset, config.Pos)
+	if err != nil {
+		return err
+	}
+	info, path, _ := imp.PathEnclosingInterval(start, end)
+	if path == nil {
+		return fmt.Errorf("no syntax here")
+	}
+
+	e := &extractor{
+		config: config,
+		fset:   imp.Fset,
+		info:   info,
+		file:   path[len(path)-1].(*ast.File),
+	}
+	filename := e.fset.File(e.file.Pos()).Name()
+	if e.src, err = ioutil.ReadFile(filename); err != nil {
+		return err
+	}
+	if err := e.selectStmts(path, start, end); err != nil {
+		return err
+	}
+	if err := e.checkName(); err != nil {
+		return err
+	}
+	if err := e.analyze(); err != nil {
+		return err
+	}
+	content, err := e.rewrite()
+	if err != nil {
+		return err
+	}
+	if config.Diff {
+		return util.Diff(config.Out, filename, content)
+	}
+	if err := writeFile(filename, content); err != nil {
+		return fmt.Errorf("failed to write %s: %s", filename, err)
+	}
+	return nil
+}
+
+// selectStmts finds the selected statements, and the syntax that
+// encloses them, from the path to the selection [start, end).
+//
+func (e *extractor) selectStmts(path []ast.Node, start, end token.Pos) error {
+	i := 0
+	var list []ast.Stmt
+	for ; i < len(path); i++ {
+		if list = stmtList(path[i]); list != nil {
+			break
+		}
+	}
+	for _, s := range list {
+		contained := start <= s.Pos() && s.End() <= end
+		if !contained && s.Pos() < end && start < s.End() {
+			return fmt.Errorf("selection must consist of whole statements")
+		}
+		if contained {
+			e.stmts = append(e.stmts, s)
+		}
+	}
+	if e.stmts == nil {
+		return fmt.Errorf("selection contains no statements")
+	}
+	e.start = e.stmts[0].Pos()
+	e.end = e.stmts[len(e.stmts)-1].End()
+	e.blockNode = path[i]
+
+	for _, n := range path[i+1:] {
+		switch n := n.(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			if e.loop == nil {
+				e.loop = n
+			}
+		case *ast.FuncDecl:
+			e.fn = n
+			e.body = n.Body
+			e.sig = e.info.ObjectOf(n.Name).Type().(*types.Signature)
+			e.block = e.info.Scopes[n.Type]
+		case *ast.FuncLit:
+			e.fn = n
+			e.body = n.Body
+			e.sig = e.info.TypeOf(n).(*types.Signature)
+			e.block = e.info.Scopes[n.Type]
+		}
+		if e.fn != nil {
+			break
+		}
+	}
+	if e.fn == nil {
+		return fmt.Errorf("selection is not within a function")
+	}
+	if s := e.info.Scopes[e.blockNode]; s != nil {
+		e.block = s // (the body of a function shares its FuncType's scope)
+	}
+	e.decl = path[len(path)-2].(ast.Decl)
+	return nil
+}
+
+// stmtList returns the statement list of a block, case clause or comm
+// clause, or nil for any other node.
+//
+func stmtList(n ast.Node) []ast.Stmt {
+	switch n := n.(type) {
+	case *ast.BlockStmt:
+		return n.List
+	case *ast.CaseClause:
+		return n.Body
+	case *ast.CommClause:
+		return n.Body
+	}
+	return nil
+}
+
+// checkName checks that the new function name is not already in use
+// in the package, nor shadowed at the call site.
+//
+func (e *extractor) checkName() error {
+	name := e.config.Name
+	if obj := e.info.Pkg.Scope().Lookup(name); obj != nil {
+		return fmt.Errorf("%s is already declared in package %s", name, e.info.Pkg.Name())
+	}
+	for _, f := range e.info.Files {
+		if obj := e.info.Scopes[f].Lookup(name); obj != nil {
+			return fmt.Errorf("%s conflicts with an import at %s", name, e.fset.Position(obj.Pos()))
+		}
+	}
+	if obj := e.block.LookupParent(name); obj != nil {
+		return fmt.Errorf("a call to %s would refer to the local %s declared at %s",
+			name, obj.Name(), e.fset.Position(obj.Pos()))
+	}
+	return nil
+}
+
+// contains reports whether pos lies within the selection.
+func (e *extractor) contains(pos token.Pos) bool {
+	return e.start <= pos && pos < e.end
+}
+
+// text returns the source text of the interval [start, end).
+func (e *extractor) text(start, end token.Pos) string {
+	base := e.fset.File(e.file.Pos()).Base()
+	return string(e.src[int(start)-base : int(end)-base])
+}
+
+// ---------- Applying the change ----------
+
+// An edit replaces the source text [start, end) by text.
+type edit struct {
+	start, end token.Pos
+	text       string
+}
+
+// apply returns the source text of [start, end) with the edits,
+// which must be sorted and disjoint, applied.
+//
+func (e *extractor) apply(start, end token.Pos, edits []edit) string {
+	var buf bytes.Buffer
+	for _, ed := range edits {
+		buf.WriteString(e.text(start, ed.start))
+		buf.WriteString(ed.text)
+		start = ed.end
+	}
+	buf.WriteString(e.text(start, end))
+	return buf.String()
+}
+
+// rewrite returns the formatted content of the file after the
+// extraction.
+//
+func (e *extractor) rewrite() ([]byte, error) {
+	fn, err := e.newFunc()
+	if err != nil {
+		return nil, err
+	}
+	call, err := e.callSite()
+	if err != nil {
+		return nil, err
+	}
+	tf := e.fset.File(e.file.Pos())
+	src := e.apply(token.Pos(tf.Base()), token.Pos(tf.Base()+tf.Size()), []edit{
+		{e.start, e.end, call},
+		{e.decl.End(), e.decl.End(), "\n\n" + fn},
+	})
+
+	// Reformat.
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, tf.Name(), src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: extraction produced invalid code: %s", err)
+	}
+	var buf bytes.Buffer
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	if err := cfg.Fprint(&buf, fset, f); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ---------- Utilities ----------
+
+// parsePos parses a position of the form "foo.go:#123,#456".
+func parsePos(fset *token.FileSet, pos string) (start, end token.Pos, err error) {
+	colon := strings.LastIndex(pos, ":")
+	comma := strings.Index(pos[colon+1:], ",")
+	if colon < 0 || comma < 0 {
+		return 0, 0, fmt.Errorf("invalid position %q; want file:#start,#end", pos)
+	}
+	filename := pos[:colon]
+	startOffset := util.ParseOctothorpDecimal(pos[colon+1 : colon+1+comma])
+	endOffset := util.ParseOctothorpDecimal(pos[colon+1+comma+1:])
+	if startOffset < 0 || endOffset < startOffset {
+		return 0, 0, fmt.Errorf("invalid offsets in position %q", pos)
+	}
+
+	var file *token.File
+	fset.Iterate(func(f *token.File) bool {
+		if util.SameFile(filename, f.Name()) {
+			file = f
+			return false // done
+		}
+		return true // continue
+	})
+	if file == nil {
+		return 0, 0, fmt.Errorf("couldn't find file containing position %s", pos)
+	}
+	if endOffset > file.Size() {
+		return 0, 0, fmt.Errorf("end offset %d is beyond end of file %s", endOffset, filename)
+	}
+	return file.Pos(startOffset), file.Pos(endOffset), nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+const testFile = "testdata/src/ex/ex.go"
+
+// TestExtract extracts each selection of testdata/src/ex/ex.go, which
+// is delimited by "// start NAME" and "// end NAME" comments, into a
+// function named "extracted".  It checks that the result is well-typed
+// and contains the expected text.
+//
+func TestExtract(t *testing.T) {
+	defer func(saved func(string, []byte) error) { writeFile = saved }(writeFile)
+
+	for _, test := range []struct {
+		name    string
+		want    []string // expected substrings of the result
+		wantErr string   // expected substring of error
+	}{
+		{name: "simple", want: []string{
+			"sum, prod := extracted(a, b)",
+			"func extracted(a int, b int) (int, int) {",
+			"return sum, prod\n}",
+		}},
+		{name: "update", want: []string{
+			"total = extracted(total, i)",
+			"func extracted(total int, i int) int {",
+		}},
+		{name: "early", want: []string{
+			"r0, r1, code := extracted(x)",
+			"if code == 1 {\n\t\t\treturn r0, r1\n\t\t}",
+			"if code == 2 {\n\t\t\tcontinue\n\t\t}",
+			"if code == 3 {\n\t\t\tbreak\n\t\t}",
+			"func extracted(x int) (int, bool, int) {",
+			"return x, false, 1",
+			"return 0, false, 2",
+			"return 0, false, 0",
+		}},
+		{name: "tail", want: []string{
+			"// start tail\n\treturn extracted(p)\n",
+			"func extracted(p *point) int {",
+		}},
+		{name: "labeled", want: []string{
+			"var code int\n\t\t\tcount, code = extracted(count, v)",
+			"if code == 2 {\n\t\t\t\tcontinue outer\n\t\t\t}",
+			"return count, 2",
+			"return count, 0",
+		}},
+		{name: "preserve", want: []string{
+			"var code int\n\t\tn, code = extracted(n, x)",
+			"func extracted(n int, x int) (int, int) {",
+			"n += x\n\tif n > 100 {\n\t\treturn n, 2\n\t}",
+		}},
+		{name: "shadow", wantErr: "n is shadowed where control leaves the selection"},
+		{name: "deferok", want: []string{
+			"// start deferok\n\textracted(mu)\n",
+			"func extracted(mu *lock) {\n\tdefer mu.Unlock()",
+		}},
+		{name: "method", want: []string{
+			"p, l = extracted(p, l)",
+			"func extracted(p point, l lock) (point, lock) {",
+		}},
+
+		{name: "baddefer", wantErr: "selection contains a defer statement"},
+		{name: "addr", wantErr: "selection takes the address of v"},
+		{name: "localtype", wantErr: "the new function would refer to local type T"},
+		{name: "goto", wantErr: "selection contains a goto statement"},
+		{name: "partial", wantErr: "selection must consist of whole statements"},
+	} {
+		var got []byte
+		writeFile = func(filename string, content []byte) error {
+			got = content
+			return nil
+		}
+
+		pos, err := selection(test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config := testConfig()
+		config.Pos = pos
+		err = Main(config)
+		if test.wantErr != "" {
+			if err == nil {
+				t.Errorf("%s: got success, want error %q", test.name, test.wantErr)
+			} else if !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("%s: got error %q, want %q", test.name, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+			continue
+		}
+		if err := typecheck(got); err != nil {
+			t.Errorf("%s: result is ill-typed: %s\n%s", test.name, err, got)
+			continue
+		}
+		for _, substr := range test.want {
+			if !bytes.Contains(got, []byte(substr)) {
+				t.Errorf("%s: result does not contain %q:\n%s", test.name, substr, got)
+			}
+		}
+	}
+}
+
+func TestBadName(t *testing.T) {
+	pos, err := selection("simple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, wantErr := range map[string]string{
+		"update": "update is already declared in package ex",
+		"b":      "a call to b would refer to the local b",
+		"1x":     `invalid function name "1x"`,
+		"func":   `invalid function name "func"`,
+	} {
+		config := testConfig()
+		config.Pos = pos
+		config.Name = name
+		if err := Main(config); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("extracting to %s: got error %v, want %q", name, err, wantErr)
+		}
+	}
+}
+
+func testConfig() *Config {
+	buildContext := build.Default
+	buildContext.GOPATH = "testdata"
+	return &Config{
+		Build: &buildContext,
+		Args:  []string{"ex"},
+		Name:  "extracted",
+	}
+}
+
+// selection returns the position of the named selection of the test
+// file, in "file:#start,#end" form.
+//
+func selection(name string) (string, error) {
+	content, err := ioutil.ReadFile(testFile)
+	if err != nil {
+		return "", err
+	}
+	start := bytes.Index(content, []byte("// start "+name+"\n"))
+	end := bytes.Index(content, []byte("// end "+name+"\n"))
+	if start < 0 || end < 0 {
+		return "", fmt.Errorf("no selection %s in %s", name, testFile)
+	}
+	start += len("// start " + name + "\n")
+	return fmt.Sprintf("%s:#%d,#%d", filepath.FromSlash(testFile), start, end), nil
+}
+
+// typecheck type-checks the Go source file content.
+func typecheck(content []byte) error {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "ex.go", content, 0)
+	if err != nil {
+		return err
+	}
+	_, err = new(types.Config).Check("ex", fset, []*ast.File{f}, nil)
+	return err
+}
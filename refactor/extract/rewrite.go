@@ -0,0 +1,517 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package extract
+
+// This file generates the source text of the new function and of the
+// call that replaces the selection.
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+// Result codes returned by the new function when it must transfer
+// control on behalf of the selection; branch i uses codeBranch+i.
+const (
+	codeNormal = iota
+	codeReturn
+	codeBranch
+)
+
+// needCode reports whether the new function returns a result code.
+func (e *extractor) needCode() bool {
+	return !e.terminal && (e.returns != nil || e.branches != nil)
+}
+
+// newFunc returns the source text of the new function.
+func (e *extractor) newFunc() (string, error) {
+	var params []string
+	for _, v := range e.params {
+		t, err := e.typeString(v.Type())
+		if err != nil {
+			return "", err
+		}
+		params = append(params, v.Name()+" "+t)
+	}
+
+	var results []string
+	for _, v := range e.outs {
+		t, err := e.typeString(v.Type())
+		if err != nil {
+			return "", err
+		}
+		results = append(results, t)
+	}
+	if e.terminal || e.returns != nil {
+		for i, n := 0, e.sig.Results().Len(); i < n; i++ {
+			t, err := e.typeString(e.sig.Results().At(i).Type())
+			if err != nil {
+				return "", err
+			}
+			results = append(results, t)
+		}
+	}
+	if e.needCode() {
+		results = append(results, "int")
+	}
+
+	// Rewrite the statements that leave the selection.
+	var edits []edit
+	if !e.terminal {
+		for _, ret := range e.returns {
+			vals, err := e.exitOuts(ret.Pos())
+			if err != nil {
+				return "", err
+			}
+			for _, r := range ret.Results {
+				vals = append(vals, e.text(r.Pos(), r.End()))
+			}
+			vals = append(vals, strconv.Itoa(codeReturn))
+			edits = append(edits, edit{ret.Pos(), ret.End(), "return " + strings.Join(vals, ", ")})
+		}
+		for i, b := range e.branches {
+			vals, err := e.exitOuts(b.Pos())
+			if err != nil {
+				return "", err
+			}
+			if e.returns != nil {
+				zeros, err := e.zeroResults()
+				if err != nil {
+					return "", err
+				}
+				vals = append(vals, zeros...)
+			}
+			vals = append(vals, strconv.Itoa(codeBranch+i))
+			edits = append(edits, edit{b.Pos(), b.End(), "return " + strings.Join(vals, ", ")})
+		}
+	}
+	sortEdits(edits)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "func %s(%s) ", e.config.Name, strings.Join(params, ", "))
+	switch len(results) {
+	case 0:
+	case 1:
+		fmt.Fprintf(&buf, "%s ", results[0])
+	default:
+		fmt.Fprintf(&buf, "(%s) ", strings.Join(results, ", "))
+	}
+	fmt.Fprintf(&buf, "{\n%s\n", e.apply(e.start, e.end, edits))
+
+	// Fall through to the statement after the selection.
+	if !e.terminal && len(results) > 0 && !e.endsWithBranch() {
+		var vals []string
+		for _, v := range e.outs {
+			vals = append(vals, v.Name())
+		}
+		if e.returns != nil {
+			zeros, err := e.zeroResults()
+			if err != nil {
+				return "", err
+			}
+			vals = append(vals, zeros...)
+		}
+		if e.needCode() {
+			vals = append(vals, strconv.Itoa(codeNormal))
+		}
+		fmt.Fprintf(&buf, "return %s\n", strings.Join(vals, ", "))
+	}
+	buf.WriteString("}\n")
+	return buf.String(), nil
+}
+
+// endsWithBranch reports whether the last selected statement is a
+// branch out of the selection.
+//
+func (e *extractor) endsWithBranch() bool {
+	last := e.stmts[len(e.stmts)-1]
+	for _, b := range e.branches {
+		if b == last {
+			return true
+		}
+	}
+	return false
+}
+
+// callSite returns the source text that replaces the selection.
+func (e *extractor) callSite() (string, error) {
+	var args []string
+	for _, v := range e.params {
+		args = append(args, v.Name())
+	}
+	call := fmt.Sprintf("%s(%s)", e.config.Name, strings.Join(args, ", "))
+
+	if e.terminal {
+		if e.sig.Results().Len() > 0 {
+			return "return " + call, nil
+		}
+		if e.blockNode == e.body && e.stmts[len(e.stmts)-1] == e.body.List[len(e.body.List)-1] {
+			return call, nil // implicit return at end of function
+		}
+		return call + "\nreturn", nil
+	}
+
+	// Choose fresh names for the results of the enclosing
+	// function and the result code.
+	used := e.usedNames()
+	fresh := func(name string) string {
+		for i := 0; used[name]; i++ {
+			name = fmt.Sprintf("%s%d", strings.TrimRight(name, "0123456789"), i)
+		}
+		used[name] = true
+		return name
+	}
+
+	var lhs []string
+	var decls []string   // new variables, as "name type"
+	existing := false    // some outs are declared outside the selection
+	redeclarable := true // all such outs may appear on the lhs of :=
+	for _, v := range e.outs {
+		lhs = append(lhs, v.Name())
+		if e.contains(v.Pos()) {
+			t, err := e.typeString(v.Type())
+			if err != nil {
+				return "", err
+			}
+			decls = append(decls, v.Name()+" "+t)
+		} else {
+			existing = true
+			if v.Parent() != e.block {
+				redeclarable = false
+			}
+		}
+	}
+	var temps []string
+	if e.returns != nil {
+		results := e.sig.Results()
+		for i, n := 0, results.Len(); i < n; i++ {
+			name := fresh(fmt.Sprintf("r%d", i))
+			t, err := e.typeString(results.At(i).Type())
+			if err != nil {
+				return "", err
+			}
+			temps = append(temps, name)
+			decls = append(decls, name+" "+t)
+		}
+		lhs = append(lhs, temps...)
+	}
+	var code string
+	if e.needCode() {
+		code = fresh("code")
+		lhs = append(lhs, code)
+		decls = append(decls, code+" int")
+	}
+
+	var buf bytes.Buffer
+	switch {
+	case lhs == nil:
+		buf.WriteString(call)
+	case decls == nil:
+		fmt.Fprintf(&buf, "%s = %s", strings.Join(lhs, ", "), call)
+	case !existing || redeclarable:
+		fmt.Fprintf(&buf, "%s := %s", strings.Join(lhs, ", "), call)
+	default:
+		// := would declare new variables that shadow the outs.
+		for _, decl := range decls {
+			fmt.Fprintf(&buf, "var %s\n", decl)
+		}
+		fmt.Fprintf(&buf, "%s = %s", strings.Join(lhs, ", "), call)
+	}
+	if e.returns != nil {
+		fmt.Fprintf(&buf, "\nif %s == %d {\nreturn %s\n}", code, codeReturn, strings.Join(temps, ", "))
+	}
+	for i, b := range e.branches {
+		fmt.Fprintf(&buf, "\nif %s == %d {\n%s\n}", code, codeBranch+i, e.text(b.Pos(), b.End()))
+	}
+	return buf.String(), nil
+}
+
+// usedNames returns the set of names declared in the package or
+// referred to within the enclosing declaration.
+//
+func (e *extractor) usedNames() map[string]bool {
+	used := make(map[string]bool)
+	for _, name := range e.info.Pkg.Scope().Names() {
+		used[name] = true
+	}
+	ast.Inspect(e.decl, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			used[id.Name] = true
+		}
+		return true
+	})
+	return used
+}
+
+// exitOuts returns the values of the outs returned by the statement
+// at pos that leaves the selection.  An out declared outside the
+// selection keeps its current value, which the call site assigns to
+// it; an out declared within the selection is out of scope wherever
+// control goes, so its zero value is returned.
+//
+func (e *extractor) exitOuts(pos token.Pos) ([]string, error) {
+	var vals []string
+	for _, v := range e.outs {
+		if e.contains(v.Pos()) {
+			z, err := e.zero(v.Type())
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, z)
+			continue
+		}
+		if e.shadowed(v, pos) {
+			return nil, fmt.Errorf("%s is shadowed where control leaves the selection at %s",
+				v.Name(), e.fset.Position(pos))
+		}
+		vals = append(vals, v.Name())
+	}
+	return vals, nil
+}
+
+// shadowed reports whether, at pos within the selection, the name of
+// v, declared outside it, denotes another object declared within it.
+//
+func (e *extractor) shadowed(v *types.Var, pos token.Pos) bool {
+	for node, s := range e.info.Scopes {
+		if e.contains(node.Pos()) && node.Pos() <= pos && pos < node.End() {
+			if obj := s.Lookup(v.Name()); obj != nil && obj.Pos() < pos {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// zeroResults returns the zero values of the results of the
+// enclosing function.
+//
+func (e *extractor) zeroResults() ([]string, error) {
+	var zeros []string
+	results := e.sig.Results()
+	for i, n := 0, results.Len(); i < n; i++ {
+		z, err := e.zero(results.At(i).Type())
+		if err != nil {
+			return nil, err
+		}
+		zeros = append(zeros, z)
+	}
+	return zeros, nil
+}
+
+// ---------- Types ----------
+
+// zero returns an expression for the zero value of type T.
+func (e *extractor) zero(T types.Type) (string, error) {
+	switch t := T.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case t.Info()&types.IsBoolean != 0:
+			return "false", nil
+		case t.Info()&types.IsString != 0:
+			return `""`, nil
+		case t.Info()&types.IsNumeric != 0:
+			return "0", nil
+		}
+		return "nil", nil // unsafe.Pointer
+	case *types.Struct, *types.Array:
+		s, err := e.typeString(T)
+		if err != nil {
+			return "", err
+		}
+		if _, ok := T.(*types.Named); !ok {
+			s = "(" + s + ")"
+		}
+		return s + "{}", nil
+	}
+	return "nil", nil
+}
+
+// typeString returns the source text of a type expression denoting T
+// in the file of the selection.
+//
+func (e *extractor) typeString(T types.Type) (string, error) {
+	var buf bytes.Buffer
+	if err := e.writeType(&buf, T); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (e *extractor) writeType(buf *bytes.Buffer, T types.Type) error {
+	switch t := T.(type) {
+	case *types.Basic:
+		if t.Info()&types.IsUntyped != 0 {
+			return fmt.Errorf("can't express untyped type %s", t)
+		}
+		if t.Kind() == types.UnsafePointer {
+			return e.writeQualified(buf, "unsafe", "unsafe", t.Name())
+		}
+		buf.WriteString(t.Name())
+
+	case *types.Named:
+		obj := t.Obj()
+		switch {
+		case obj.Pkg() == nil:
+			buf.WriteString(obj.Name()) // error
+		case obj.Pkg() == e.info.Pkg:
+			if obj.Parent() != e.info.Pkg.Scope() {
+				return fmt.Errorf("the new function would refer to local type %s", obj.Name())
+			}
+			buf.WriteString(obj.Name())
+		default:
+			return e.writeQualified(buf, obj.Pkg().Path(), obj.Pkg().Name(), obj.Name())
+		}
+
+	case *types.Pointer:
+		buf.WriteByte('*')
+		return e.writeType(buf, t.Elem())
+
+	case *types.Slice:
+		buf.WriteString("[]")
+		return e.writeType(buf, t.Elem())
+
+	case *types.Array:
+		fmt.Fprintf(buf, "[%d]", t.Len())
+		return e.writeType(buf, t.Elem())
+
+	case *types.Map:
+		buf.WriteString("map[")
+		if err := e.writeType(buf, t.Key()); err != nil {
+			return err
+		}
+		buf.WriteByte(']')
+		return e.writeType(buf, t.Elem())
+
+	case *types.Chan:
+		switch t.Dir() {
+		case ast.SEND:
+			buf.WriteString("chan<- ")
+		case ast.RECV:
+			buf.WriteString("<-chan ")
+		default:
+			buf.WriteString("chan ")
+			if c, ok := t.Elem().(*types.Chan); ok && c.Dir() == ast.RECV {
+				buf.WriteByte('(')
+				if err := e.writeType(buf, c); err != nil {
+					return err
+				}
+				buf.WriteByte(')')
+				return nil
+			}
+		}
+		return e.writeType(buf, t.Elem())
+
+	case *types.Signature:
+		buf.WriteString("func")
+		return e.writeSignature(buf, t)
+
+	case *types.Struct:
+		buf.WriteString("struct{")
+		for i, n := 0, t.NumFields(); i < n; i++ {
+			if i > 0 {
+				buf.WriteString("; ")
+			}
+			f := t.Field(i)
+			if !f.Anonymous() {
+				buf.WriteString(f.Name())
+				buf.WriteByte(' ')
+			}
+			if err := e.writeType(buf, f.Type()); err != nil {
+				return err
+			}
+			if tag := t.Tag(i); tag != "" {
+				fmt.Fprintf(buf, " %s", strconv.Quote(tag))
+			}
+		}
+		buf.WriteByte('}')
+
+	case *types.Interface:
+		buf.WriteString("interface{")
+		for i, n := 0, t.NumMethods(); i < n; i++ {
+			if i > 0 {
+				buf.WriteString("; ")
+			}
+			m := t.Method(i)
+			buf.WriteString(m.Name())
+			if err := e.writeSignature(buf, m.Type().(*types.Signature)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+
+	default:
+		return fmt.Errorf("can't express type %s", T)
+	}
+	return nil
+}
+
+// writeSignature writes the parameters and results of sig.
+func (e *extractor) writeSignature(buf *bytes.Buffer, sig *types.Signature) error {
+	if err := e.writeTuple(buf, sig.Params(), sig.IsVariadic()); err != nil {
+		return err
+	}
+	if results := sig.Results(); results.Len() > 0 {
+		buf.WriteByte(' ')
+		return e.writeTuple(buf, results, false)
+	}
+	return nil
+}
+
+func (e *extractor) writeTuple(buf *bytes.Buffer, tuple *types.Tuple, isVariadic bool) error {
+	buf.WriteByte('(')
+	for i, n := 0, tuple.Len(); i < n; i++ {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		T := tuple.At(i).Type()
+		if isVariadic && i == n-1 {
+			buf.WriteString("...")
+			T = T.(*types.Slice).Elem()
+		}
+		if err := e.writeType(buf, T); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(')')
+	return nil
+}
+
+// writeQualified writes a reference to the named member of the
+// package with the specified path, which must be imported by the file
+// of the selection.
+//
+func (e *extractor) writeQualified(buf *bytes.Buffer, path, pkgname, name string) error {
+	for _, spec := range e.file.Imports {
+		if p, _ := strconv.Unquote(spec.Path.Value); p == path {
+			if spec.Name != nil {
+				if spec.Name.Name == "." || spec.Name.Name == "_" {
+					break
+				}
+				pkgname = spec.Name.Name
+			}
+			fmt.Fprintf(buf, "%s.%s", pkgname, name)
+			return nil
+		}
+	}
+	return fmt.Errorf("the new function would refer to %s.%s, but package %q is not imported by this file",
+		pkgname, name, path)
+}
+
+// sortEdits sorts the edits by position.
+func sortEdits(edits []edit) {
+	// Insertion sort: the lists are short and nearly sorted.
+	for i := 1; i < len(edits); i++ {
+		for j := i; j > 0 && edits[j].start < edits[j-1].start; j-- {
+			edits[j], edits[j-1] = edits[j-1], edits[j]
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package ex
+
+type point struct{ x, y int }
+
+type lock struct{ n int }
+
+func (l *lock) Lock()   {}
+func (l *lock) Unlock() {}
+
+func simple(a, b int) int {
+	// start simple
+	sum := a + b
+	prod := a * b
+	// end simple
+	return sum + prod
+}
+
+func update(n int) int {
+	total := 0
+	for i := 0; i < n; i++ {
+		// start update
+		total += i
+		// end update
+	}
+	return total
+}
+
+func early(xs []int) (int, bool) {
+	for _, x := range xs {
+		// start early
+		if x < 0 {
+			return x, false
+		}
+		if x == 0 {
+			continue
+		}
+		if x > 100 {
+			break
+		}
+		// end early
+	}
+	return 0, true
+}
+
+func tail(p *point) int {
+	// start tail
+	if p == nil {
+		return 0
+	}
+	return p.x + p.y
+	// end tail
+}
+
+func labeled(grid [][]int) int {
+	count := 0
+outer:
+	for _, row := range grid {
+		for _, v := range row {
+			// start labeled
+			if v < 0 {
+				continue outer
+			}
+			count++
+			// end labeled
+		}
+	}
+	return count
+}
+
+func withDefer(mu *lock) {
+	mu.Lock()
+	// start deferok
+	defer mu.Unlock()
+	mu.n++
+	// end deferok
+}
+
+func badDefer(mu *lock) int {
+	// start baddefer
+	mu.Lock()
+	defer mu.Unlock()
+	// end baddefer
+	return mu.n
+}
+
+func method(p point) point {
+	var l lock
+	// start method
+	l.Lock()
+	p.x++
+	// end method
+	l.Unlock()
+	return p
+}
+
+func addr() *int {
+	v := 1
+	// start addr
+	p := &v
+	// end addr
+	return p
+}
+
+func localType() int {
+	type T int
+	var t T
+	// start localtype
+	t++
+	// end localtype
+	return int(t)
+}
+
+func jump(n int) int {
+	// start goto
+	if n > 0 {
+		goto done
+	}
+	n = -n
+	// end goto
+done:
+	return n
+}
+
+func partial(a int) int {
+	// start partial
+	b := a +
+		// end partial
+		1
+	return b
+}
+
+func preserve(xs []int) int {
+	n := 0
+	for _, x := range xs {
+		// start preserve
+		n += x
+		if n > 100 {
+			break
+		}
+		// end preserve
+	}
+	return n
+}
+
+func shadow(xs []int) int {
+	n := 0
+	for _, x := range xs {
+		// start shadow
+		n++
+		if x < 0 {
+			n := x
+			_ = n
+			break
+		}
+		// end shadow
+	}
+	return n
+}
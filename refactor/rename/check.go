@@ -0,0 +1,393 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rename
+
+// This file defines the safety checks for each kind of renaming.
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+// errorf records a conflict at the specified position.
+func (r *renamer) errorf(pos token.Pos, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if pos != token.NoPos {
+		msg = fmt.Sprintf("%s: %s", r.imp.Fset.Position(pos), msg)
+	}
+	r.errors = append(r.errors, msg)
+}
+
+// check appends to r.errors a description of each way in which the
+// renaming would change the meaning of the program.
+//
+func (r *renamer) check() {
+	switch obj := r.from.(type) {
+	case *types.Var:
+		if obj.Parent() == nil {
+			r.checkField(obj)
+		} else {
+			r.checkLexical()
+		}
+	case *types.Func:
+		if recv := obj.Type().(*types.Signature).Recv(); recv != nil {
+			r.checkMethod(obj, recv.Type())
+		} else {
+			r.checkLexical()
+		}
+	default:
+		r.checkLexical()
+	}
+	r.checkExport()
+}
+
+// checkExport checks that an exported object that is referenced from
+// other packages does not become unexported.
+//
+func (r *renamer) checkExport() {
+	if !r.from.IsExported() || ast.IsExported(r.to) {
+		return
+	}
+	for id, info := range r.refs {
+		if info.Pkg != r.from.Pkg() {
+			r.errorf(id.Pos(), "%s would become unexported but is referenced from package %s",
+				r.from.Name(), info.Pkg.Path())
+			return
+		}
+	}
+}
+
+// ---------- Lexical objects ----------
+
+// checkLexical checks the renaming of the objects declared in lexical
+// blocks: a package-level or local const, var, type or func, or the
+// variables of a type switch.
+//
+func (r *renamer) checkLexical() {
+	for obj := range r.objs {
+		r.checkLexicalObject(obj)
+	}
+}
+
+// checkLexicalObject checks the renaming of from, one of r.objs.
+func (r *renamer) checkLexicalObject(from types.Object) {
+	block := from.Parent()
+	pkg := from.Pkg()
+
+	if block == pkg.Scope() {
+		if _, ok := from.(*types.Func); ok {
+			if from.Name() == "init" || r.to == "init" {
+				r.errorf(from.Pos(), "can't rename to or from init function")
+			}
+			if pkg.Name() == "main" && (from.Name() == "main" || r.to == "main") {
+				r.errorf(from.Pos(), "can't rename to or from main function of package main")
+			}
+		}
+		// Package-level objects conflict with imports in any file.
+		for i, n := 0, block.NumChildren(); i < n; i++ {
+			if prev := block.Child(i).Lookup(r.to); prev != nil {
+				r.errorf(prev.Pos(), "renamed %s would conflict with this import", from.Name())
+			}
+		}
+	}
+
+	// Conflicting declaration in the same block?
+	if prev := block.Lookup(r.to); prev != nil {
+		r.errorf(prev.Pos(), "renamed %s would conflict with this declaration of %s", from.Name(), prev)
+	}
+
+	// Would any reference be shadowed by an inner declaration?
+	for id, info := range r.refs {
+		if info.ObjectOf(id) != from || id.Pos() == from.Pos() || r.selectors[id] {
+			continue // another object, declaration, or qualified reference
+		}
+		for s := r.scopes[id]; s != nil && s != block; s = s.Parent() {
+			if prev := s.Lookup(r.to); prev != nil {
+				r.errorf(id.Pos(), "this reference to %s would be shadowed by the declaration of %s at %s",
+					from.Name(), prev, r.imp.Fset.Position(prev.Pos()))
+				break
+			}
+		}
+	}
+
+	// Would any existing reference to r.to be captured by the
+	// renamed object?  Only references within the same package
+	// can be affected.
+	for _, info := range r.pkgs {
+		if info.Pkg != pkg {
+			continue
+		}
+		for id, obj := range info.Objects {
+			if id.Name != r.to || obj == nil || obj.Parent() == nil || r.selectors[id] {
+				continue
+			}
+			for s := r.scopes[id]; s != nil && s != obj.Parent(); s = s.Parent() {
+				if s == block {
+					r.errorf(id.Pos(), "this reference to %s would refer to the renamed %s",
+						obj, from.Name())
+					break
+				}
+			}
+		}
+	}
+
+	// A type used as an embedded field gives its name to the field.
+	if tname, ok := from.(*types.TypeName); ok {
+		for _, info := range r.pkgs {
+			for id, obj := range info.Objects {
+				if v, ok := obj.(*types.Var); ok && v.Anonymous() {
+					if named, ok := deref(v.Type()).(*types.Named); ok && named.Obj() == tname {
+						r.errorf(id.Pos(), "renaming %s would change the name of this embedded field",
+							from.Name())
+					}
+				}
+			}
+		}
+	}
+}
+
+// ---------- Fields and methods ----------
+
+// checkField checks the renaming of a struct field.
+func (r *renamer) checkField(field *types.Var) {
+	for _, T := range r.structsWithField(field) {
+		if prev, _, _ := types.LookupFieldOrMethod(T, field.Pkg(), r.to); prev != nil {
+			r.errorf(prev.Pos(), "renamed field %s would conflict with this %s", field.Name(), prev)
+		}
+	}
+	r.checkSelections()
+}
+
+// checkMethod checks the renaming of a method of the specified
+// receiver type.
+//
+func (r *renamer) checkMethod(method *types.Func, recv types.Type) {
+	if iface, ok := recv.Underlying().(*types.Interface); ok {
+		r.checkInterfaceMethod(method, recv, iface)
+		return
+	}
+
+	T := types.NewPointer(deref(recv))
+	if prev, _, _ := types.LookupFieldOrMethod(T, method.Pkg(), r.to); prev != nil {
+		r.errorf(prev.Pos(), "renamed method %s would conflict with this %s", method.Name(), prev)
+	}
+	r.checkSelections()
+
+	// Would the receiver type, or a type to which the method is
+	// promoted by embedding, stop or start implementing some
+	// interface?  Either would change the outcome of dynamic
+	// calls and type assertions.
+	ifaces := r.interfaces()
+	for _, tname := range r.typesWithMethod(method) {
+		T := types.NewPointer(tname.Type())
+		pos := tname.Pos()
+		if tname.Type() == deref(recv) {
+			pos = method.Pos()
+		}
+		for _, iface := range ifaces {
+			before := types.Implements(T, iface.iface, false)
+			after := r.implementsAfter(T, iface.iface, method)
+			if before && !after {
+				r.errorf(pos, "renaming %s would make %s no longer implement %s",
+					method.Name(), tname.Type(), iface.typ)
+			} else if after && !before {
+				r.errorf(pos, "renaming %s would make %s implement %s",
+					method.Name(), tname.Type(), iface.typ)
+			}
+		}
+	}
+}
+
+// typesWithMethod returns the named non-interface types whose method
+// sets (of *T) include method, declared by the type or promoted to it
+// from an embedded field.
+//
+func (r *renamer) typesWithMethod(method *types.Func) []*types.TypeName {
+	var result []*types.TypeName
+	seen := make(map[*types.TypeName]bool)
+	for _, info := range r.pkgs {
+		for _, obj := range info.Objects {
+			tname, ok := obj.(*types.TypeName)
+			if !ok || seen[tname] {
+				continue
+			}
+			seen[tname] = true
+			if _, ok := tname.Type().Underlying().(*types.Interface); ok {
+				continue
+			}
+			T := types.NewPointer(tname.Type())
+			if obj, _, _ := types.LookupFieldOrMethod(T, method.Pkg(), method.Name()); obj == method {
+				result = append(result, tname)
+			}
+		}
+	}
+	return result
+}
+
+// implementsAfter reports whether T, whose method set includes
+// method, would implement iface once method is renamed.
+//
+func (r *renamer) implementsAfter(T types.Type, iface *types.Interface, method *types.Func) bool {
+	for i, n := 0, iface.NumMethods(); i < n; i++ {
+		m := iface.Method(i)
+		var obj types.Object
+		switch {
+		case sameMethodName(m, r.to, method.Pkg()):
+			obj = method
+		case sameMethodName(m, method.Name(), method.Pkg()):
+			return false // T would lose the method
+		default:
+			obj, _, _ = types.LookupFieldOrMethod(T, m.Pkg(), m.Name())
+		}
+		f, ok := obj.(*types.Func)
+		if !ok || !types.IsIdentical(f.Type(), m.Type()) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameMethodName reports whether m is named name, where an unexported
+// name is qualified by the package pkg.
+//
+func sameMethodName(m *types.Func, name string, pkg *types.Package) bool {
+	return m.Name() == name && (ast.IsExported(name) || m.Pkg() == pkg)
+}
+
+// checkInterfaceMethod checks the renaming of an interface method.
+// Since the corresponding methods of concrete types are not renamed,
+// it conservatively rejects the renaming if any type in the program
+// implements the interface.
+//
+func (r *renamer) checkInterfaceMethod(method *types.Func, recv types.Type, iface *types.Interface) {
+	if prev, _, _ := types.LookupFieldOrMethod(recv, method.Pkg(), r.to); prev != nil {
+		r.errorf(prev.Pos(), "renamed method %s would conflict with this %s", method.Name(), prev)
+	}
+	seen := make(map[*types.TypeName]bool)
+	for _, info := range r.pkgs {
+		for _, obj := range info.Objects {
+			tname, ok := obj.(*types.TypeName)
+			if !ok || seen[tname] {
+				continue
+			}
+			seen[tname] = true
+			if _, ok := tname.Type().Underlying().(*types.Interface); ok {
+				continue
+			}
+			if types.Implements(types.NewPointer(tname.Type()), iface, false) {
+				r.errorf(tname.Pos(), "renaming %s would make %s no longer implement %s",
+					method.Name(), tname.Name(), recv)
+			}
+		}
+	}
+}
+
+// checkSelections checks that every selection of the renamed field or
+// method would still select it, and that no other selection of the
+// new name would select it instead.
+//
+func (r *renamer) checkSelections() {
+	for _, info := range r.pkgs {
+		for sel, s := range info.Selections {
+			switch {
+			case s.Obj() == r.from:
+				prev, index, _ := types.LookupFieldOrMethod(s.Recv(), r.from.Pkg(), r.to)
+				if prev != nil {
+					r.errorf(sel.Sel.Pos(), "this selection of %s would conflict with %s at %s",
+						r.from.Name(), prev, r.imp.Fset.Position(prev.Pos()))
+				} else if index != nil {
+					r.errorf(sel.Sel.Pos(), "this selection of %s would become ambiguous", r.from.Name())
+				}
+
+			case sel.Sel.Name == r.to && s.Kind() != types.PackageObj:
+				if obj, _, _ := types.LookupFieldOrMethod(s.Recv(), r.from.Pkg(), r.from.Name()); obj == r.from {
+					r.errorf(sel.Sel.Pos(), "this selection of %s would conflict with the renamed %s",
+						s.Obj(), r.from.Name())
+				}
+			}
+		}
+	}
+}
+
+// structsWithField returns the types whose underlying struct declares
+// field, as suitable arguments to LookupFieldOrMethod.
+//
+func (r *renamer) structsWithField(field *types.Var) []types.Type {
+	var result []types.Type
+	seen := make(map[types.Type]bool)
+	add := func(T types.Type) {
+		if seen[T] {
+			return
+		}
+		seen[T] = true
+		if s, ok := T.Underlying().(*types.Struct); ok {
+			for i, n := 0, s.NumFields(); i < n; i++ {
+				if s.Field(i) == field {
+					if _, ok := T.(*types.Named); ok {
+						T = types.NewPointer(T) // include methods
+					}
+					result = append(result, T)
+					return
+				}
+			}
+		}
+	}
+	for _, info := range r.pkgs {
+		for _, obj := range info.Objects {
+			if tname, ok := obj.(*types.TypeName); ok {
+				add(tname.Type())
+			}
+		}
+		for _, T := range info.Types {
+			add(T)
+		}
+	}
+	return result
+}
+
+// An ifaceType is an interface type and its (possibly named) type.
+type ifaceType struct {
+	typ   types.Type
+	iface *types.Interface
+}
+
+// interfaces returns all the non-empty interface types in the
+// loaded packages.
+//
+func (r *renamer) interfaces() []ifaceType {
+	var result []ifaceType
+	seen := make(map[types.Type]bool)
+	add := func(T types.Type) {
+		if seen[T] {
+			return
+		}
+		seen[T] = true
+		if iface, ok := T.Underlying().(*types.Interface); ok && iface.NumMethods() > 0 {
+			result = append(result, ifaceType{T, iface})
+		}
+	}
+	for _, info := range r.pkgs {
+		for _, obj := range info.Objects {
+			if tname, ok := obj.(*types.TypeName); ok {
+				add(tname.Type())
+			}
+		}
+		for _, T := range info.Types {
+			add(T)
+		}
+	}
+	return result
+}
+
+// deref returns a pointer's element type; otherwise it returns typ.
+func deref(typ types.Type) types.Type {
+	if p, ok := typ.Underlying().(*types.Pointer); ok {
+		return p.Elem()
+	}
+	return typ
+}
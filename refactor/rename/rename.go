@@ -0,0 +1,455 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package rename contains the implementation of the 'gorename' tool
+// whose command-line is provided by code.google.com/p/go.tools/cmd/gorename.
+//
+// The tool renames a package-level object (const, var, type or func),
+// a struct field, a method, or a local variable, and updates all
+// references to it within the packages loaded from the command-line
+// arguments.  It refuses to make changes that would alter the meaning
+// of the program, such as introducing a conflicting declaration,
+// shadowing or capturing another reference, or causing a type to no
+// longer satisfy an interface.
+//
+// Packages that refer to the renamed object but are not loaded are
+// not updated, so the arguments should include all packages that
+// depend upon the one that declares it.
+//
+package rename
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"code.google.com/p/go.tools/go/types"
+	"code.google.com/p/go.tools/importer"
+	"code.google.com/p/go.tools/refactor/util"
+)
+
+// A Config specifies a renaming.
+type Config struct {
+	// Build is the go/build configuration for locating packages.
+	Build *build.Context
+
+	// Args specifies the packages to load, in
+	// importer.CreatePackageFromArgs syntax.
+	Args []string
+
+	// Exactly one of Pos and From identifies the object to rename.
+	//
+	// Pos is the position of an identifier denoting it,
+	// e.g. "foo.go:#123".
+	//
+	// From is its qualified name: "pkg.Name" for a package-level
+	// object, or "pkg.Type.Name" for a field or method, where pkg
+	// is an import path, e.g. "encoding/json.Decoder.Decode".
+	Pos, From string
+
+	// To is the new name.
+	To string
+
+	// If Diff is set, the changes are not applied, but are
+	// displayed as a unified diff to Out.
+	Diff bool
+
+	// Out receives the diff, and a summary of the changes.
+	Out io.Writer
+}
+
+// writeFile is a seam for testing.
+var writeFile = func(filename string, content []byte) error {
+	return ioutil.WriteFile(filename, content, 0644)
+}
+
+// A renamer holds the state of a renaming.
+type renamer struct {
+	config *Config
+	imp    *importer.Importer
+	pkgs   []*importer.PackageInfo
+	from   types.Object // the object to rename
+	to     string       // its new name
+
+	// objs is the set of objects renamed: from, and, if it is the
+	// variable declared by a type switch, that of each clause.
+	objs map[types.Object]bool
+
+	// refs maps each identifier referring to one of objs (including
+	// its declaration) to the package containing it.
+	refs map[*ast.Ident]*importer.PackageInfo
+
+	// scopes maps each identifier in the program to its innermost
+	// enclosing lexical scope.
+	scopes map[*ast.Ident]*types.Scope
+
+	// selectors is the set of identifiers that are resolved not
+	// lexically but relative to a type or package: the Sel of
+	// each selector expression, and the keys of struct literals.
+	selectors map[*ast.Ident]bool
+
+	errors []string // conflicts found
+}
+
+// Main renames the object specified by config, and updates all
+// references to it.
+//
+func Main(config *Config) error {
+	if !util.IsValidIdentifier(config.To) {
+		return fmt.Errorf("invalid identifier %q", config.To)
+	}
+	if (config.Pos == "") == (config.From == "") {
+		return fmt.Errorf("exactly one of -pos and -from must be specified")
+	}
+
+	imp := importer.New(&importer.Config{Build: config.Build})
+	if _, rest, err := imp.LoadInitialPackages(config.Args); err != nil {
+		return err
+	} else if len(rest) > 0 {
+		return fmt.Errorf("surplus arguments: %q", rest)
+	}
+
+	r := &renamer{
+		config:    config,
+		imp:       imp,
+		pkgs:      imp.AllPackages(),
+		to:        config.To,
+		refs:      make(map[*ast.Ident]*importer.PackageInfo),
+		scopes:    make(map[*ast.Ident]*types.Scope),
+		selectors: make(map[*ast.Ident]bool),
+	}
+	var err error
+	if config.Pos != "" {
+		r.from, err = r.objectAtPos(config.Pos)
+	} else {
+		r.from, err = r.objectByName(config.From)
+	}
+	if err != nil {
+		return err
+	}
+	if r.from.Name() == r.to {
+		return fmt.Errorf("%s is already named %s", r.from, r.to)
+	}
+
+	switch obj := r.from.(type) {
+	case *types.PkgName:
+		return fmt.Errorf("can't rename package %s", obj.Name())
+	case *types.Label:
+		return fmt.Errorf("can't rename label %s", obj.Name())
+	}
+	if r.from.Pkg() == nil {
+		return fmt.Errorf("can't rename built-in %s", r.from.Name())
+	}
+	if r.isStandard(r.from) {
+		return fmt.Errorf("can't rename %s, declared in the standard library", r.from)
+	}
+
+	r.findRefs()
+	r.check()
+	if r.errors != nil {
+		sort.Strings(r.errors)
+		r.errors = dedup(r.errors)
+		return fmt.Errorf("renaming %s to %s would cause conflicts:\n\t%s",
+			r.from.Name(), r.to, strings.Join(r.errors, "\n\t"))
+	}
+	return r.update()
+}
+
+// dedup returns the sorted list a without adjacent duplicates.
+func dedup(a []string) []string {
+	var out []string
+	for i, s := range a {
+		if i == 0 || s != a[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// ---------- Finding the object ----------
+
+// objectAtPos returns the object denoted by the identifier at the
+// position pos, of the form "file:#offset".
+//
+func (r *renamer) objectAtPos(pos string) (types.Object, error) {
+	colon := strings.LastIndex(pos, ":")
+	if colon < 0 || !strings.HasPrefix(pos[colon+1:], "#") {
+		return nil, fmt.Errorf("invalid position %q; want file:#offset", pos)
+	}
+	filename := pos[:colon]
+	offset, err := strconv.Atoi(pos[colon+2:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid offset in position %q", pos)
+	}
+
+	var file *token.File
+	r.imp.Fset.Iterate(func(f *token.File) bool {
+		if util.SameFile(filename, f.Name()) {
+			file = f
+			return false // done
+		}
+		return true // continue
+	})
+	if file == nil {
+		return nil, fmt.Errorf("couldn't find file containing position %s", pos)
+	}
+	if offset > file.Size() {
+		return nil, fmt.Errorf("offset %d is beyond end of file %s", offset, filename)
+	}
+	start := file.Pos(offset)
+
+	info, path, _ := r.imp.PathEnclosingInterval(start, start)
+	if path == nil {
+		return nil, fmt.Errorf("no syntax at position %s", pos)
+	}
+	id, _ := path[0].(*ast.Ident)
+	if id == nil {
+		return nil, fmt.Errorf("no identifier at position %s", pos)
+	}
+	obj := info.ObjectOf(id)
+	if obj == nil {
+		// The symbol x of a type switch "switch x := y.(type)"
+		// denotes the variable implicitly declared in each clause.
+		for node, v := range info.Implicits {
+			if _, ok := node.(*ast.CaseClause); ok && v.Pos() == id.Pos() {
+				obj = v
+				break
+			}
+		}
+	}
+	if obj == nil {
+		return nil, fmt.Errorf("no object for identifier %s", id.Name)
+	}
+	return obj, nil
+}
+
+// objectByName returns the object of the specified qualified name:
+// "pkg.Name" or "pkg.Type.Name".
+//
+func (r *renamer) objectByName(name string) (types.Object, error) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return nil, fmt.Errorf("invalid qualified name %q", name)
+	}
+	path := name[:slash+1+dot]
+	names := strings.Split(name[slash+1+dot+1:], ".")
+	if len(names) > 2 {
+		return nil, fmt.Errorf("invalid qualified name %q", name)
+	}
+
+	var pkg *types.Package
+	for _, info := range r.pkgs {
+		if info.Pkg.Path() == path {
+			pkg = info.Pkg
+			break
+		}
+	}
+	if pkg == nil {
+		return nil, fmt.Errorf("package %q is not loaded", path)
+	}
+
+	obj := pkg.Scope().Lookup(names[0])
+	if obj == nil {
+		return nil, fmt.Errorf("no member %s in package %s", names[0], path)
+	}
+	if len(names) == 1 {
+		return obj, nil
+	}
+
+	tname, ok := obj.(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a type", obj)
+	}
+	// Look up a field or method declared by (not embedded in) the type.
+	T := tname.Type()
+	if _, ok := T.Underlying().(*types.Interface); !ok {
+		T = types.NewPointer(T) // include methods of *T
+	}
+	mem, index, _ := types.LookupFieldOrMethod(T, pkg, names[1])
+	if mem == nil || len(index) != 1 {
+		return nil, fmt.Errorf("type %s has no field or method %s", tname.Name(), names[1])
+	}
+	return mem, nil
+}
+
+// ---------- Finding references ----------
+
+// findRefs populates r.objs, r.refs, r.scopes and r.selectors.
+//
+// The variables implicitly declared in the clauses of a type switch
+// "switch x := y.(type)" share the position of the symbol x, which
+// itself denotes no object; all of them, and x, are renamed together.
+//
+func (r *renamer) findRefs() {
+	r.objs = map[types.Object]bool{r.from: true}
+	if _, ok := r.from.(*types.Var); ok {
+		for _, info := range r.pkgs {
+			for node, obj := range info.Implicits {
+				if _, ok := node.(*ast.CaseClause); ok && obj.Pos() == r.from.Pos() {
+					r.objs[obj] = true
+				}
+			}
+		}
+	}
+	for _, info := range r.pkgs {
+		for _, f := range info.Files {
+			r.walkScopes(info, f)
+		}
+		for id, obj := range info.Objects {
+			if r.objs[obj] || obj == nil && len(r.objs) > 1 && id.Pos() == r.from.Pos() {
+				r.refs[id] = info
+			}
+		}
+	}
+}
+
+// walkScopes records the innermost lexical scope of each identifier in
+// file f, and the identifiers that are selectors.
+//
+func (r *renamer) walkScopes(info *importer.PackageInfo, f *ast.File) {
+	var stack []*types.Scope
+	var nodes []ast.Node
+	ast.Inspect(f, func(n ast.Node) bool {
+		if n == nil {
+			// Pop.
+			if s := info.Scopes[nodes[len(nodes)-1]]; s != nil || isFunc(nodes[len(nodes)-1]) {
+				stack = stack[:len(stack)-1]
+			}
+			nodes = nodes[:len(nodes)-1]
+			return true
+		}
+		nodes = append(nodes, n)
+
+		// Push.
+		s := info.Scopes[n]
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			// A function's body shares the scope of its parameters.
+			s = info.Scopes[n.Type]
+		case *ast.FuncLit:
+			s = info.Scopes[n.Type]
+		}
+		if s == nil && isFunc(n) {
+			s = stack[len(stack)-1] // e.g. no FuncType scope
+		}
+		if s != nil {
+			stack = append(stack, s)
+		}
+
+		switch n := n.(type) {
+		case *ast.Ident:
+			if len(stack) > 0 {
+				r.scopes[n] = stack[len(stack)-1]
+			}
+		case *ast.SelectorExpr:
+			r.selectors[n.Sel] = true
+		case *ast.CompositeLit:
+			if T := info.TypeOf(n); T == nil {
+				// ill-typed
+			} else if _, ok := deref(T).Underlying().(*types.Struct); ok {
+				for _, elt := range n.Elts {
+					if kv, ok := elt.(*ast.KeyValueExpr); ok {
+						if id, ok := kv.Key.(*ast.Ident); ok {
+							r.selectors[id] = true
+						}
+					}
+				}
+			}
+		}
+		return true
+	})
+}
+
+func isFunc(n ast.Node) bool {
+	switch n.(type) {
+	case *ast.FuncDecl, *ast.FuncLit:
+		return true
+	}
+	return false
+}
+
+// isStandard reports whether obj is declared in the standard library.
+func (r *renamer) isStandard(obj types.Object) bool {
+	if obj.Pos() == token.NoPos {
+		return true
+	}
+	filename := r.imp.Fset.Position(obj.Pos()).Filename
+	goroot := filepath.Clean(r.config.Build.GOROOT) + string(filepath.Separator)
+	return strings.HasPrefix(filepath.Clean(filename), goroot)
+}
+
+// ---------- Applying the changes ----------
+
+// update renames all references to r.from by replacing the text of
+// each referring identifier in the source files, leaving the rest of
+// each file, including its comments and layout, unchanged.  It then
+// writes out the modified files (or a diff of them).
+//
+func (r *renamer) update() error {
+	// offsets maps the name of each file to update to the set of byte
+	// offsets of the references within it.  (A file may be loaded
+	// more than once, e.g. by a package and its external test.)
+	offsets := make(map[string]map[int]bool)
+	for id := range r.refs {
+		posn := r.imp.Fset.Position(id.Pos())
+		if offsets[posn.Filename] == nil {
+			offsets[posn.Filename] = make(map[int]bool)
+		}
+		offsets[posn.Filename][posn.Offset] = true
+	}
+
+	var names []string
+	for name := range offsets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	nrefs := 0
+	for _, name := range names {
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %s", name, err)
+		}
+		var sorted []int
+		for offset := range offsets[name] {
+			sorted = append(sorted, offset)
+		}
+		sort.Ints(sorted)
+
+		var buf bytes.Buffer
+		last := 0
+		for _, offset := range sorted {
+			end := offset + len(r.from.Name())
+			if end > len(src) || string(src[offset:end]) != r.from.Name() {
+				return fmt.Errorf("%s has changed since it was loaded", name)
+			}
+			buf.Write(src[last:offset])
+			buf.WriteString(r.to)
+			last = end
+		}
+		buf.Write(src[last:])
+		nrefs += len(sorted)
+
+		if r.config.Diff {
+			if err := util.Diff(r.config.Out, name, buf.Bytes()); err != nil {
+				return err
+			}
+		} else if err := writeFile(name, buf.Bytes()); err != nil {
+			return fmt.Errorf("failed to write %s: %s", name, err)
+		}
+	}
+	if !r.config.Diff && r.config.Out != nil {
+		fmt.Fprintf(r.config.Out, "Renamed %d occurrence(s) in %d file(s).\n", nrefs, len(names))
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rename
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestRename applies each renaming to the packages beneath testdata.
+// Files are not written; instead, their new contents are checked for
+// the expected substrings.
+//
+func TestRename(t *testing.T) {
+	defer func(saved func(string, []byte) error) { writeFile = saved }(writeFile)
+
+	for _, test := range []struct {
+		from, offset, to string
+		want             map[string][]string // expected substrings of each changed file
+		wantErr          string              // expected substring of error
+	}{
+		// Successful renamings.
+		{from: "lib.V", to: "W", want: map[string][]string{
+			"lib.go":    {"var W = 1", "return y + W"},
+			"client.go": {"_ = t.F + lib.W"},
+		}},
+		{from: "lib.T.F", to: "H", want: map[string][]string{
+			"lib.go":    {"H, G int", "return t.H"},
+			"client.go": {"_ = t.H + lib.V"},
+		}},
+		{from: "lib.T.N", to: "Size", want: map[string][]string{
+			"lib.go": {"func (t *T) Size() int"},
+		}},
+		{offset: "lib.go:y := x", to: "w", want: map[string][]string{
+			"lib.go": {"w := x", "return w + V"},
+		}},
+		{offset: "lib.go:x := y.(type)", to: "v", want: map[string][]string{
+			"lib.go": {"switch v := y.(type)", "return v\n", "n := len(v)"},
+		}},
+		{offset: "lib.go:x)", to: "v", want: map[string][]string{
+			"lib.go": {"switch v := y.(type)", "return v\n", "n := len(v)"},
+		}},
+
+		// Conflicts.
+		{from: "lib.V", to: "v",
+			wantErr: "V would become unexported but is referenced from package client"},
+		{from: "lib.V", to: "C",
+			wantErr: "renamed V would conflict with this declaration of const C"},
+		{from: "lib.T.F", to: "G",
+			wantErr: "renamed field F would conflict with this var G int"},
+		{from: "lib.T.N", to: "M",
+			wantErr: "renamed method N would conflict with this func (lib.T).M()"},
+		{from: "lib.T.M", to: "MM",
+			wantErr: "renaming M would make lib.T no longer implement lib.I"},
+		{from: "lib.Base.M", to: "MM",
+			wantErr: "renaming M would make lib.Derived no longer implement lib.I"},
+		{from: "lib.Base.Shut", to: "Close",
+			wantErr: "renaming Shut would make lib.Derived implement lib.Closer"},
+		{from: "lib.I.M", to: "P",
+			wantErr: "renaming M would make T no longer implement lib.I"},
+		{from: "lib.C", to: "z",
+			wantErr: "this reference to C would be shadowed by the declaration of var z int"},
+		{offset: "lib.go:y := x", to: "x",
+			wantErr: "renamed y would conflict with this declaration of var x int"},
+		{offset: "lib.go:x := y.(type)", to: "n",
+			wantErr: "renamed x would conflict with this declaration of var n int"},
+		{offset: "lib.go:y := x", to: "V",
+			wantErr: "this reference to var V int would refer to the renamed y"},
+		{from: "lib.F", to: "init",
+			wantErr: "can't rename to or from init function"},
+		{from: "lib.F", to: "func",
+			wantErr: `invalid identifier "func"`},
+		{from: "lib.T", to: "U",
+			want: map[string][]string{"client.go": {"var t lib.U"}}},
+		{from: "lib.nonesuch", to: "x",
+			wantErr: "no member nonesuch in package lib"},
+	} {
+		written := make(map[string]string)
+		writeFile = func(filename string, content []byte) error {
+			written[filepath.Base(filename)] = string(content)
+			return nil
+		}
+
+		config := testConfig()
+		config.From = test.from
+		config.To = test.to
+		if test.offset != "" {
+			pos, err := offsetOf(test.offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config.Pos = pos
+		}
+		name := test.from + test.offset
+		err := Main(config)
+		if test.wantErr != "" {
+			if err == nil {
+				t.Errorf("renaming %s to %s: got success, want error %q", name, test.to, test.wantErr)
+			} else if !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("renaming %s to %s: got error %q, want %q", name, test.to, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("renaming %s to %s: unexpected error: %s", name, test.to, err)
+			continue
+		}
+		for file, substrs := range test.want {
+			content, ok := written[file]
+			if !ok {
+				t.Errorf("renaming %s to %s: %s was not changed", name, test.to, file)
+				continue
+			}
+			for _, substr := range substrs {
+				if !strings.Contains(content, substr) {
+					t.Errorf("renaming %s to %s: %s does not contain %q:\n%s",
+						name, test.to, file, substr, content)
+				}
+			}
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	switch runtime.GOOS {
+	case "windows":
+		t.Skipf("skipping test on %q (no diff)", runtime.GOOS)
+	}
+
+	var out bytes.Buffer
+	config := testConfig()
+	config.From = "lib.C"
+	config.To = "D"
+	config.Diff = true
+	config.Out = &out
+	if err := Main(config); err != nil {
+		t.Fatal(err)
+	}
+	for _, substr := range []string{"-const C = 2", "+const D = 2", "-\treturn z + C", "+\treturn z + D"} {
+		if !strings.Contains(out.String(), substr) {
+			t.Errorf("diff does not contain %q:\n%s", substr, out.String())
+		}
+	}
+}
+
+// TestComments checks that renaming changes only the text of the
+// references, preserving the comments and layout of the file.
+func TestComments(t *testing.T) {
+	defer func(saved func(string, []byte) error) { writeFile = saved }(writeFile)
+	var got []byte
+	writeFile = func(filename string, content []byte) error {
+		got = content
+		return nil
+	}
+
+	config := testConfig()
+	config.Args = []string{"comments"}
+	config.From = "comments.Count"
+	config.To = "Total"
+	if err := Main(config); err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile(filepath.Join("testdata", "comments.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func testConfig() *Config {
+	buildContext := build.Default
+	buildContext.GOPATH = "testdata"
+	return &Config{
+		Build: &buildContext,
+		Args:  []string{"client"},
+	}
+}
+
+// offsetOf returns the position, in "file:#offset" form, of the
+// start of the first occurrence of text in the specified file of
+// package lib.  Its argument has the form "file:text".
+//
+func offsetOf(spec string) (string, error) {
+	i := strings.Index(spec, ":")
+	filename := filepath.Join("testdata", "src", "lib", spec[:i])
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	offset := bytes.Index(content, []byte(spec[i+1:]))
+	if offset < 0 {
+		return "", fmt.Errorf("%s does not contain %q", filename, spec[i+1:])
+	}
+	return fmt.Sprintf("%s:#%d", filename, offset), nil
+}
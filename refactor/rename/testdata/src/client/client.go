@@ -0,0 +1,11 @@
+package client
+
+import "lib"
+
+func f() {
+	var t lib.T
+	t.M()
+	_ = t.F + lib.V
+	var i lib.I = t
+	_ = i
+}
@@ -0,0 +1,13 @@
+// +build !nonesuch
+
+// Package comments is used by TestComments.  Renaming must preserve
+// its comments and layout.
+package comments
+
+// Count is the number of things; the name in this comment is left unchanged.
+var Count = 1 /* an inline comment */
+
+func Double() int {
+	n:=Count*2 // an unformatted statement
+	return n   // Count
+}
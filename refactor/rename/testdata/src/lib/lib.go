@@ -0,0 +1,55 @@
+package lib
+
+type T struct {
+	F, G int
+}
+
+func (T) M() {}
+
+func (t *T) N() int { return t.F }
+
+type I interface {
+	M()
+}
+
+var V = 1
+
+const C = 2
+
+func F(x int) int {
+	y := x
+	return y + V
+}
+
+func G() int {
+	z := 0
+	return z + C
+}
+
+func init() {}
+
+func S(y interface{}) int {
+	switch x := y.(type) {
+	case int:
+		return x
+	case string:
+		n := len(x)
+		return n
+	}
+	return 0
+}
+
+type Base struct{}
+
+func (Base) M() {}
+
+func (Base) Shut() {}
+
+// Derived gets the methods of Base by embedding.
+type Derived struct {
+	Base
+}
+
+type Closer interface {
+	Close()
+}
@@ -0,0 +1,111 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package util provides utilities shared by the refactoring tools,
+// the oracle and vet: parsing of positions given as byte offsets,
+// validation of identifiers, matching of import path patterns, and
+// display of proposed changes as diffs.
+//
+package util
+
+import (
+	"fmt"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Diff writes to out a unified diff of the named file against the
+// proposed content.  Both sides of the diff are labelled with the
+// file name, so that the output may be applied using patch.
+//
+func Diff(out io.Writer, filename string, content []byte) error {
+	f, err := ioutil.TempFile("", "go.tools")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if _, err := f.Write(content); err != nil {
+		return err
+	}
+
+	cmd := exec.Command("diff", "-u", "-L", filename, "-L", filename, filename, f.Name())
+	cmd.Stdout = out
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return fmt.Errorf("failed to run diff: %s", err)
+		}
+		// Exit status 1 indicates that the files differ.
+	}
+	return nil
+}
+
+// SameFile reports whether x and y have the same basename and denote
+// the same file.
+//
+func SameFile(x, y string) bool {
+	if filepath.Base(x) == filepath.Base(y) { // (optimisation)
+		if xi, err := os.Stat(x); err == nil {
+			if yi, err := os.Stat(y); err == nil {
+				return os.SameFile(xi, yi)
+			}
+		}
+	}
+	return false
+}
+
+// ParseOctothorpDecimal returns the numeric value if s matches "#%d",
+// otherwise -1.
+func ParseOctothorpDecimal(s string) int {
+	if s != "" && s[0] == '#' {
+		if s, err := strconv.ParseInt(s[1:], 10, 32); err == nil {
+			return int(s)
+		}
+	}
+	return -1
+}
+
+// IsValidIdentifier reports whether id is a valid Go identifier
+// (other than the blank identifier or a keyword).
+func IsValidIdentifier(id string) bool {
+	if id == "" || id == "_" || token.Lookup(id).IsKeyword() {
+		return false
+	}
+	for i, r := range id {
+		if !isLetter(r) && (i == 0 || !isDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchPackage reports whether the import path matches pattern, in
+// which "..." matches any string, as for the go command.
+// As a special case, "foo/..." also matches "foo".
+//
+func MatchPackage(pattern, path string) bool {
+	re := regexp.QuoteMeta(pattern)
+	re = strings.Replace(re, `\.\.\.`, `.*`, -1)
+	if strings.HasSuffix(re, `/.*`) {
+		re = re[:len(re)-len(`/.*`)] + `(/.*)?`
+	}
+	matched, _ := regexp.MatchString("^"+re+"$", path)
+	return matched
+}
+
+func isLetter(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || r == '_' || r >= 0x80 && unicode.IsLetter(r)
+}
+
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9' || r >= 0x80 && unicode.IsDigit(r)
+}
@@ -0,0 +1,107 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package util
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestIsValidIdentifier(t *testing.T) {
+	for _, test := range []struct {
+		id   string
+		want bool
+	}{
+		{"x", true},
+		{"_x1", true},
+		{"café", true},
+		{"x١", true}, // Arabic-Indic digit one
+		{"", false},
+		{"_", false},
+		{"1x", false},
+		{"x-y", false},
+		{"func", false},
+		{"type", false},
+		{"١x", false},
+	} {
+		if got := IsValidIdentifier(test.id); got != test.want {
+			t.Errorf("IsValidIdentifier(%q) = %t, want %t", test.id, got, test.want)
+		}
+	}
+}
+
+func TestParseOctothorpDecimal(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		want int
+	}{
+		{"#0", 0},
+		{"#123", 123},
+		{"", -1},
+		{"123", -1},
+		{"#", -1},
+		{"#x", -1},
+	} {
+		if got := ParseOctothorpDecimal(test.s); got != test.want {
+			t.Errorf("ParseOctothorpDecimal(%q) = %d, want %d", test.s, got, test.want)
+		}
+	}
+}
+
+func TestMatchPackage(t *testing.T) {
+	for _, test := range []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"foo", "foo", true},
+		{"foo", "foobar", false},
+		{"foo/...", "foo", true},
+		{"foo/...", "foo/bar/baz", true},
+		{"foo/...", "foobar", false},
+		{"...bar", "foo/bar", true},
+		{"foo.bar", "fooxbar", false},
+	} {
+		if got := MatchPackage(test.pattern, test.path); got != test.want {
+			t.Errorf("MatchPackage(%q, %q) = %t, want %t", test.pattern, test.path, got, test.want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	switch runtime.GOOS {
+	case "windows":
+		t.Skipf("skipping test on %q (no diff)", runtime.GOOS)
+	}
+
+	dir, err := ioutil.TempDir("", "util")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "a.go")
+	if err := ioutil.WriteFile(filename, []byte("package a\nvar x = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := Diff(&out, filename, []byte("package a\nvar y = 1\n")); err != nil {
+		t.Fatal(err)
+	}
+	// Both headers name the file, not the temporary file.
+	want := "--- " + filename + "\n+++ " + filename + "\n"
+	if !strings.HasPrefix(out.String(), want) {
+		t.Errorf("diff does not begin with %q:\n%s", want, out.String())
+	}
+	for _, substr := range []string{"-var x = 1", "+var y = 1"} {
+		if !strings.Contains(out.String(), substr) {
+			t.Errorf("diff does not contain %q:\n%s", substr, out.String())
+		}
+	}
+}
@@ -0,0 +1,487 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+// This file defines the propagation of taint over the SSA value flow
+// graph and the objects of the pointer analysis.
+
+import (
+	"fmt"
+	"go/token"
+	"sort"
+	"strings"
+
+	"code.google.com/p/go.tools/call"
+	"code.google.com/p/go.tools/go/types"
+	"code.google.com/p/go.tools/internal/ptaindex"
+	"code.google.com/p/go.tools/pointer"
+	"code.google.com/p/go.tools/ssa"
+)
+
+// An object identifies an object of the pointer analysis.  Taint is
+// tracked per object, not per field, since a store of a whole struct
+// and a load of one of its fields may use different labels.
+type object struct {
+	v    ssa.Value      // allocation, if any
+	cgn  call.GraphNode // context of allocation, if any
+	name string         // label, sans subelement path
+}
+
+// An item is a tainted entity: an ssa.Value or an object.
+type item interface{}
+
+// A pred records how an item became tainted.
+type pred struct {
+	from   item      // the item from which taint was propagated, or nil for a source
+	step   Step      // the step by which it was propagated
+	source *Endpoint // the source, if from is nil
+}
+
+type analysis struct {
+	spec       *Spec
+	prog       *ssa.Program
+	ptrs       map[ssa.Value][]pointer.Pointer   // results of the pointer analysis
+	objs       map[ssa.Value][]object            // memoization of pointsTo
+	index      *ptaindex.Index                   // call graph, loads and stores
+	globalRefs map[*ssa.Global][]ssa.Instruction // referrers of each global
+	loads      map[object][]ssa.Value            // values loaded from each object
+	sinkObjs   map[object][]sinkArg              // sink arguments that may point to each object
+	preds      map[item]pred                     // tainted items
+	queue      []item                            // tainted items yet to be propagated
+	flows      []*Flow
+	seenFlows  map[[2]interface{}]bool // (sink instruction, source item) pairs
+}
+
+// A sinkArg is a slice argument of a call to a sink, such as the
+// variadic arguments of exec.Command, whose elements are checked.
+type sinkArg struct {
+	site  ssa.CallInstruction
+	name  string // full name of the callee
+	index int    // parameter index of the argument
+}
+
+// Analyze runs the taint analysis specified by spec on the program
+// described by config, and returns the flows of tainted data it finds,
+// ordered by the position of the sink.
+//
+// Analyze runs the pointer analysis on a copy of config with its own
+// Queries and with BuildCallGraph enabled; config itself is not
+// modified.
+//
+func Analyze(config *pointer.Config, spec *Spec) []*Flow {
+	var prog *ssa.Program
+	for _, pkg := range append(config.Mains, config.Libraries...) {
+		prog = pkg.Prog
+	}
+	if prog == nil {
+		panic("empty scope")
+	}
+
+	a := &analysis{
+		spec:       spec,
+		prog:       prog,
+		objs:       make(map[ssa.Value][]object),
+		globalRefs: make(map[*ssa.Global][]ssa.Instruction),
+		loads:      make(map[object][]ssa.Value),
+		sinkObjs:   make(map[object][]sinkArg),
+		preds:      make(map[item]pred),
+		seenFlows:  make(map[[2]interface{}]bool),
+	}
+
+	// Query the points-to sets of all pointers through which
+	// the program loads or stores, and of all slices passed to
+	// functions, whose elements may reach a sink.
+	conf := *config
+	conf.Queries = nil
+	a.index = ptaindex.New(prog, &conf)
+	for fn := range ssa.AllFunctions(prog) {
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				var rands [10]*ssa.Value
+				for _, op := range instr.Operands(rands[:0]) {
+					if g, ok := (*op).(*ssa.Global); ok {
+						a.globalRefs[g] = append(a.globalRefs[g], instr)
+					}
+				}
+				if site, ok := instr.(ssa.CallInstruction); ok {
+					for _, arg := range site.Common().Args {
+						if _, ok := arg.Type().Underlying().(*types.Slice); ok {
+							conf.Queries[arg] = false
+						}
+					}
+				}
+			}
+		}
+	}
+	result := pointer.Analyze(&conf)
+	a.ptrs = result.Queries
+	a.index.Init(result)
+
+	// Index the loads by object.
+	for _, v := range a.index.Loads {
+		for _, ptr := range ptaindex.Addresses(v.(ssa.Instruction)) {
+			for _, obj := range a.pointsTo(ptr) {
+				a.loads[obj] = append(a.loads[obj], v)
+			}
+		}
+	}
+
+	a.indexSinkArgs()
+	a.seedSources()
+	for len(a.queue) > 0 {
+		x := a.queue[0]
+		a.queue = a.queue[1:]
+		switch x := x.(type) {
+		case ssa.Value:
+			a.propagateValue(x)
+		case object:
+			for _, v := range a.loads[x] {
+				a.taint(v, x, Step{v.Pos(), "load"})
+			}
+			for _, arg := range a.sinkObjs[x] {
+				a.sink(arg.site, x, arg.name, arg.index, "call to "+arg.name)
+			}
+		}
+	}
+
+	sort.Sort(byPos(a.flows))
+	return a.flows
+}
+
+type byPos []*Flow
+
+func (s byPos) Len() int { return len(s) }
+func (s byPos) Less(i, j int) bool {
+	x, y := s[i].Path, s[j].Path
+	if p, q := x[len(x)-1].Pos, y[len(y)-1].Pos; p != q {
+		return p < q
+	}
+	return x[0].Pos < y[0].Pos
+}
+func (s byPos) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+// pointsTo returns the objects to which v may point.
+func (a *analysis) pointsTo(v ssa.Value) []object {
+	objs, ok := a.objs[v]
+	if !ok {
+		seen := make(map[object]bool)
+		for _, l := range pointer.PointsToCombined(a.ptrs[v]).Labels() {
+			obj := object{l.Value(), l.Context(), strings.TrimSuffix(l.String(), l.Path())}
+			if !seen[obj] {
+				seen[obj] = true
+				objs = append(objs, obj)
+			}
+		}
+		a.objs[v] = objs
+	}
+	return objs
+}
+
+// lookup returns the function or global of the specified full name,
+// or nil if not found.
+func (a *analysis) lookup(name string) ssa.Value {
+	for _, pkg := range a.prog.AllPackages() {
+		for _, mem := range pkg.Members {
+			switch mem := mem.(type) {
+			case *ssa.Function:
+				if mem.String() == name {
+					return mem
+				}
+			case *ssa.Global:
+				if mem.FullName() == name {
+					return mem
+				}
+			}
+		}
+	}
+	// Methods are not package members.
+	for fn := range a.index.Callers {
+		if fn.String() == name {
+			return fn
+		}
+	}
+	return nil
+}
+
+// indexSinkArgs populates a.sinkObjs with the objects to which each
+// slice argument of a call to a sink may point.  A tainted element of
+// such a slice, e.g. the "-c" command of exec.Command("sh", "-c", cmd),
+// reaches the sink.
+func (a *analysis) indexSinkArgs() {
+	for _, snk := range a.spec.Sinks {
+		fn, ok := a.lookup(snk.Name).(*ssa.Function)
+		if !ok {
+			continue
+		}
+		for _, site := range a.index.Callers[fn] {
+			args := site.Common().Args
+			if site.Common().IsInvoke() {
+				args = append([]ssa.Value{site.Common().Value}, args...)
+			}
+			if snk.Index < 0 || snk.Index >= len(args) {
+				continue
+			}
+			arg := args[snk.Index]
+			if _, ok := arg.Type().Underlying().(*types.Slice); !ok {
+				continue
+			}
+			for _, obj := range a.pointsTo(arg) {
+				a.sinkObjs[obj] = append(a.sinkObjs[obj], sinkArg{site, snk.Name, snk.Index})
+			}
+		}
+	}
+}
+
+// lookupType returns the named type of the specified full name, e.g.
+// "net/http.Request", or nil if not found.
+func (a *analysis) lookupType(name string) *types.Named {
+	for _, pkg := range a.prog.AllPackages() {
+		for _, mem := range pkg.Members {
+			if t, ok := mem.(*ssa.Type); ok {
+				if nt, ok := t.Type().(*types.Named); ok && pkg.Object.Path()+"."+t.Name() == name {
+					return nt
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// seedSources taints the sources of the spec.
+func (a *analysis) seedSources() {
+	for i := range a.spec.Sources {
+		src := &a.spec.Sources[i]
+		if nt := a.lookupType(src.Name); nt != nil {
+			a.seedParams(nt, src)
+			continue
+		}
+		switch v := a.lookup(src.Name).(type) {
+		case *ssa.Global:
+			a.source(v, src, Step{v.Pos(), "global " + v.FullName()})
+
+		case *ssa.Function:
+			if src.Index == Return {
+				for _, site := range a.index.Callers[v] {
+					if res := site.Value(); res != nil {
+						a.source(res, src, Step{site.Pos(), "call to " + v.String()})
+					}
+				}
+			} else if src.Index < len(v.Params) {
+				p := v.Params[src.Index]
+				a.source(p, src, Step{p.Pos(), fmt.Sprintf("parameter %s of %s", p.Name(), v)})
+			}
+		}
+	}
+}
+
+// seedParams taints, as sources, the parameters of type T or *T,
+// where T is the named type nt, of the functions outside T's package,
+// such as the *http.Request parameter of each HTTP handler.
+func (a *analysis) seedParams(nt *types.Named, src *Endpoint) {
+	ptr := types.NewPointer(nt)
+	for fn := range ssa.AllFunctions(a.prog) {
+		if fn.Pkg == nil || fn.Pkg.Object == nt.Obj().Pkg() || fn.Synthetic != "" {
+			continue
+		}
+		for _, p := range fn.Params {
+			if types.IsIdentical(p.Type(), nt) || types.IsIdentical(p.Type(), ptr) {
+				a.source(p, src, Step{p.Pos(), fmt.Sprintf("parameter %s of %s", p.Name(), fn)})
+			}
+		}
+	}
+}
+
+// source marks x as a tainted source.
+func (a *analysis) source(x item, src *Endpoint, step Step) {
+	if _, ok := a.preds[x]; !ok {
+		a.preds[x] = pred{step: step, source: src}
+		a.queue = append(a.queue, x)
+	}
+}
+
+// taint marks x as tainted by the propagation of taint from item
+// from, by the specified step.
+func (a *analysis) taint(x, from item, step Step) {
+	if _, ok := a.preds[x]; !ok {
+		a.preds[x] = pred{from: from, step: step}
+		a.queue = append(a.queue, x)
+	}
+}
+
+// taintObjects taints the objects to which ptr may point.
+func (a *analysis) taintObjects(ptr ssa.Value, from item, step Step) {
+	for _, obj := range a.pointsTo(ptr) {
+		a.taint(obj, from, step)
+	}
+}
+
+// referrers returns the instructions that refer to v.
+func (a *analysis) referrers(v ssa.Value) []ssa.Instruction {
+	if g, ok := v.(*ssa.Global); ok {
+		return a.globalRefs[g]
+	}
+	if refs := v.Referrers(); refs != nil {
+		return *refs
+	}
+	return nil
+}
+
+// propagateValue propagates taint from tainted value v to each of
+// its referrers.
+func (a *analysis) propagateValue(v ssa.Value) {
+	for _, instr := range a.referrers(v) {
+		switch instr := instr.(type) {
+		case *ssa.Store:
+			if instr.Val == v {
+				a.taintObjects(instr.Addr, v, Step{instr.Pos(), "store"})
+			}
+
+		case *ssa.MapUpdate:
+			if instr.Key == v || instr.Value == v {
+				a.taintObjects(instr.Map, v, Step{instr.Pos(), "map update"})
+			}
+
+		case *ssa.Send:
+			if instr.X == v {
+				a.taintObjects(instr.Chan, v, Step{instr.Pos(), "send"})
+			}
+
+		case *ssa.Select:
+			for _, st := range instr.States {
+				if st.Send == v {
+					a.taintObjects(st.Chan, v, Step{st.Pos, "send"})
+				}
+			}
+
+		case *ssa.Return:
+			fn := instr.Parent()
+			for _, site := range a.index.Callers[fn] {
+				if res := site.Value(); res != nil {
+					a.taint(res, v, Step{instr.Pos(), "return from " + fn.String()})
+				}
+			}
+
+		case *ssa.MakeClosure:
+			fn := instr.Fn.(*ssa.Function)
+			for i, b := range instr.Bindings {
+				if b == v {
+					a.taint(fn.FreeVars[i], v, Step{instr.Pos(), "capture by " + fn.String()})
+				}
+			}
+
+		case ssa.CallInstruction:
+			a.propagateCall(instr, v)
+
+		case *ssa.ChangeType, *ssa.Convert:
+			// A conversion to a sink type?
+			if nt, ok := instr.(ssa.Value).Type().(*types.Named); ok {
+				obj := nt.Obj()
+				if obj.Pkg() != nil {
+					name := obj.Pkg().Path() + "." + obj.Name()
+					a.sink(instr, v, name, 0, "conversion to "+name)
+				}
+			}
+			a.taint(instr.(ssa.Value), v, Step{instr.Pos(), "conversion"})
+
+		case ssa.Value:
+			// Computation, including a load through a tainted pointer.
+			a.taint(instr, v, Step{instr.Pos(), "computation"})
+		}
+	}
+}
+
+// propagateCall propagates taint from v to the callees of the call
+// instruction instr, of which v is an argument (or the receiver).
+func (a *analysis) propagateCall(instr ssa.CallInstruction, v ssa.Value) {
+	common := instr.Common()
+	res := instr.Value() // nil for go and defer
+
+	if b, ok := common.Value.(*ssa.Builtin); ok {
+		switch b.Object().Name() {
+		case "append":
+			if res != nil {
+				a.taint(res, v, Step{instr.Pos(), "append"})
+			}
+		case "copy":
+			if common.Args[1] == v {
+				a.taintObjects(common.Args[0], v, Step{instr.Pos(), "copy"})
+			}
+		}
+		return
+	}
+
+	args := common.Args
+	if common.IsInvoke() {
+		args = append([]ssa.Value{common.Value}, args...)
+	}
+	callees := a.index.Callees[instr]
+	for i, arg := range args {
+		if arg != v {
+			continue
+		}
+		if callees == nil && res != nil {
+			// Unknown callee.
+			a.taint(res, v, Step{instr.Pos(), common.Description()})
+		}
+		for _, fn := range callees {
+			name := fn.String()
+			a.sink(instr, v, name, i, "call to "+name)
+			if a.isSanitizer(name, i) {
+				continue
+			}
+			if fn.Blocks != nil && i < len(fn.Params) {
+				p := fn.Params[i]
+				a.taint(p, v, Step{instr.Pos(), "call to " + name})
+			} else if res != nil {
+				// No Go body: assume results depend on arguments.
+				a.taint(res, v, Step{instr.Pos(), "call to " + name})
+			}
+		}
+	}
+}
+
+func (a *analysis) isSanitizer(name string, index int) bool {
+	for _, e := range a.spec.Sanitizers {
+		if e.Name == name && e.Index == index {
+			return true
+		}
+	}
+	return false
+}
+
+// sink records a flow if (name, index) is a sink of the spec.  The
+// tainted item v, a value or an object to which the argument may
+// point, reaches it at instruction instr.
+func (a *analysis) sink(instr ssa.Instruction, v item, name string, index int, desc string) {
+	for i := range a.spec.Sinks {
+		snk := &a.spec.Sinks[i]
+		if snk.Name != name || snk.Index != index {
+			continue
+		}
+
+		// Reconstruct the path back to the source.
+		path := []Step{{instr.Pos(), desc}}
+		var src *Endpoint
+		var root item
+		for x := v; x != nil; {
+			p := a.preds[x]
+			if p.from == nil || p.step.Pos != token.NoPos {
+				path = append(path, p.step)
+			}
+			root, src, x = x, p.source, p.from
+		}
+		key := [2]interface{}{instr, root}
+		if a.seenFlows[key] {
+			continue
+		}
+		a.seenFlows[key] = true
+
+		// Reverse the path.
+		for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+			path[i], path[j] = path[j], path[i]
+		}
+		a.flows = append(a.flows, &Flow{Source: *src, Sink: *snk, Path: path})
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package taint defines a taint (information flow) analysis for Go
+// programs, built upon the pointer analysis.
+//
+// A client provides a specification (Spec) of the sources of
+// untrusted data in the program, the sinks that must not receive such
+// data, and the sanitizers through which data may pass safely.  The
+// analysis reports each flow of data from a source to a sink that
+// does not pass through a sanitizer, as a path of source positions.
+//
+// Sources, sinks and sanitizers are designated by Endpoints, each
+// of which identifies a function by its full name (as defined by
+// ssa.Function.String) and a parameter of it.  For example:
+//
+//	Endpoint{"os/exec.Command", 0}                  // the name parameter of exec.Command
+//	Endpoint{"(*database/sql.DB).Query", 1}         // the query parameter (the receiver is 0)
+//	Endpoint{"net/http.ReadRequest", taint.Return}  // the results of calls to http.ReadRequest
+//
+// A source Endpoint may also name a package-level variable, e.g.
+// "os.Args", in which case all data reachable from it is a source, or
+// a named type T, e.g. "net/http.Request", in which case each
+// parameter of type T or *T of a function outside T's package, such
+// as the request parameter of an HTTP handler, is a source, along with
+// all data reachable from it; Index is ignored.  A sink Endpoint may
+// also name a type, e.g. "html/template.HTML", in which case the
+// operand of any conversion to that type is a sink.
+//
+// A sink parameter of slice type, such as the variadic arg parameter
+// of exec.Command, is also reached by tainted data stored in the
+// elements of the slice.
+//
+// The analysis is flow-insensitive and context-insensitive.  Data is
+// tainted if it is computed from tainted data, loaded through a
+// tainted pointer, or loaded from an object (of any field) into which
+// tainted data may have been stored, according to the pointer
+// analysis.  Calls to functions with no Go body propagate taint from
+// each argument to the result.  Implicit flows, i.e. those due to
+// control dependence, are not tracked.
+//
+package taint
+
+import (
+	"fmt"
+	"go/token"
+)
+
+// Return is the parameter index of an Endpoint denoting the results of
+// a function, rather than one of its parameters.
+const Return = -1
+
+// An Endpoint identifies a parameter or the results of a function.
+type Endpoint struct {
+	Name  string // full name of a function, or a global (source) or type (sink)
+	Index int    // parameter index, counting the receiver, if any; or Return
+}
+
+func (e Endpoint) String() string {
+	if e.Index == Return {
+		return fmt.Sprintf("results of %s", e.Name)
+	}
+	return fmt.Sprintf("parameter %d of %s", e.Index, e.Name)
+}
+
+// A Spec specifies the sources, sinks and sanitizers of a taint analysis.
+type Spec struct {
+	// Sources are the origins of untrusted data: the designated
+	// parameters within the function, or the results of each
+	// call to it; or a global or the parameters of a type.
+	Sources []Endpoint
+
+	// Sinks are the destinations that must not receive
+	// untrusted data: the designated parameters of each call to
+	// the function.  (Index must not be Return.)
+	Sinks []Endpoint
+
+	// Sanitizers designate parameters through which untrusted
+	// data ceases to be tainted: taint is not propagated from the
+	// argument to the callee, nor to the result of the call.
+	Sanitizers []Endpoint
+}
+
+// DefaultSpec is a specification of some common sources of untrusted
+// data (HTTP requests and command-line arguments) and sinks that may
+// be vulnerable to injection (commands, SQL queries and HTML).
+var DefaultSpec = &Spec{
+	Sources: []Endpoint{
+		{"net/http.Request", 0},
+		{"net/http.ReadRequest", Return},
+		{"(*net/http.Request).FormValue", Return},
+		{"os.Args", 0},
+		{"os.Getenv", Return},
+	},
+	Sinks: []Endpoint{
+		{"os/exec.Command", 0},
+		{"os/exec.Command", 1},
+		{"(*database/sql.DB).Exec", 1},
+		{"(*database/sql.DB).Query", 1},
+		{"(*database/sql.DB).QueryRow", 1},
+		{"html/template.HTML", 0},
+		{"html/template.JS", 0},
+	},
+	Sanitizers: []Endpoint{
+		{"html/template.HTMLEscapeString", 0},
+		{"html/template.JSEscapeString", 0},
+		{"net/url.QueryEscape", 0},
+		{"strconv.Atoi", 0},
+	},
+}
+
+// A Step is one step along a Flow.
+type Step struct {
+	Pos  token.Pos // position of the step, if known
+	Desc string    // description of the step, e.g. "store"
+}
+
+// A Flow is a flow of tainted data from a source to a sink.
+type Flow struct {
+	Source Endpoint
+	Sink   Endpoint
+	// Path is the sequence of steps by which the data flows from
+	// source to sink.  The first step is the source and the last
+	// is the sink.  Intermediate steps without positions are omitted.
+	Path []Step
+}
+
+func (f *Flow) String() string {
+	return fmt.Sprintf("%s reaches %s", f.Path[0].Desc, f.Sink)
+}
@@ -0,0 +1,113 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint_test
+
+import (
+	"go/build"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"code.google.com/p/go.tools/importer"
+	"code.google.com/p/go.tools/pointer"
+	"code.google.com/p/go.tools/ssa"
+	"code.google.com/p/go.tools/taint"
+)
+
+func TestTaint(t *testing.T) {
+	const input = "testdata/flows.go"
+	buildContext := build.Default
+	buildContext.GOPATH = "testdata"
+	imp := importer.New(&importer.Config{Build: &buildContext})
+	infos, _, err := imp.LoadInitialPackages([]string{input})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := ssa.NewProgram(imp.Fset, 0)
+	if err := prog.CreatePackages(imp); err != nil {
+		t.Fatal(err)
+	}
+	prog.BuildAll()
+	main := prog.Package(infos[0].Pkg)
+
+	spec := &taint.Spec{
+		Sources: []taint.Endpoint{
+			{"main.source", taint.Return},
+			{"main.input", 0},
+			{"req.Request", 0},
+		},
+		Sinks: []taint.Endpoint{
+			{"main.sink", 0},
+			{"os/exec.Command", 0},
+			{"os/exec.Command", 1},
+		},
+		Sanitizers: []taint.Endpoint{{"main.sanitize", 0}},
+	}
+	config := &pointer.Config{Mains: []*ssa.Package{main}}
+	flows := taint.Analyze(config, spec)
+	if config.Queries != nil || config.BuildCallGraph {
+		t.Errorf("Analyze modified its config: %+v", config)
+	}
+
+	// Map each line to the sorted list of sources reaching it.
+	got := make(map[int][]string)
+	for _, f := range flows {
+		if len(f.Path) < 2 {
+			t.Errorf("%s: path too short: %v", f, f.Path)
+			continue
+		}
+		var src string
+		switch f.Source.Name {
+		case "main.source":
+			src = "source"
+		case "main.input":
+			src = "global"
+		case "req.Request":
+			src = "request"
+		}
+		line := prog.Fset.Position(f.Path[len(f.Path)-1].Pos).Line
+		got[line] = append(got[line], src)
+	}
+
+	// Check the expectations, which use the grammar of
+	// go.tools/pointer/pointer_test.go:
+	//
+	// @flows src...
+	//
+	//   A 'flows' expectation asserts that exactly the named sources
+	//   reach the sinks of its line, or none if the list is "none".
+	//
+	data, err := ioutil.ReadFile(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	re := regexp.MustCompile("// *@([a-z]*) *(.*)$")
+	for linenum, line := range strings.Split(string(data), "\n") {
+		linenum++ // make it 1-based
+		m := re.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		kind, rest := m[1], m[2]
+		if kind != "flows" {
+			t.Errorf("line %d: unknown expectation kind: @%s", linenum, kind)
+			continue
+		}
+		sort.Strings(got[linenum])
+		gotSrcs := strings.Join(got[linenum], " ")
+		if gotSrcs == "" {
+			gotSrcs = "none"
+		}
+		if gotSrcs != rest {
+			t.Errorf("line %d: got flows from %s, want %s", linenum, gotSrcs, rest)
+		}
+		delete(got, linenum)
+	}
+	for line, srcs := range got {
+		t.Errorf("line %d: unexpected flows from %s", line, srcs)
+	}
+}
@@ -0,0 +1,107 @@
+// +build ignore
+
+package main
+
+// Test of the taint analysis.  Each call to a sink is annotated with
+// the sources whose data reaches it, or "none".
+
+import (
+	"os/exec"
+	"req"
+)
+
+var input string // a source
+
+func source() string { return "untrusted" }
+
+func sink(s string) {}
+
+func sanitize(s string) string { return s }
+
+func id(s string) string { return s }
+
+type T struct {
+	f string
+	g *string
+}
+
+func direct() {
+	sink(source()) // @flows source
+}
+
+func sanitized() {
+	sink(sanitize(source())) // @flows none
+}
+
+func interprocedural() {
+	sink(id(source())) // @flows source
+}
+
+func heap() {
+	t := &T{}
+	t.f = source()
+	sink(t.f) // @flows source
+
+	s := source()
+	u := &T{g: &s}
+	sink(*u.g) // @flows source
+}
+
+func maps() {
+	m := make(map[int]string)
+	m[0] = source()
+	sink(m[1]) // @flows source
+}
+
+func channels() {
+	ch := make(chan string, 1)
+	ch <- source()
+	sink(<-ch) // @flows source
+}
+
+func closures() {
+	s := source()
+	f := func() { sink(s) } // @flows source
+	f()
+}
+
+func dynamic() {
+	f := id
+	sink(f(source())) // @flows source
+}
+
+func global() {
+	sink(input + "!") // @flows global
+}
+
+func clean() {
+	sink("trusted") // @flows none
+}
+
+func handler(r *req.Request) {
+	sink(r.Query)          // @flows request
+	sink(r.Header["X"][0]) // @flows request
+	sink(req.Get(r, "X"))  // @flows request
+}
+
+func command() {
+	exec.Command("sh", "-c", source()) // @flows source
+	exec.Command("ls", "-l")           // @flows none
+	args := []string{"-c", source()}
+	exec.Command("sh", args...) // @flows source
+}
+
+func main() {
+	direct()
+	sanitized()
+	interprocedural()
+	heap()
+	maps()
+	channels()
+	closures()
+	dynamic()
+	global()
+	clean()
+	handler(&req.Request{})
+	command()
+}
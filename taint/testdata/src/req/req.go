@@ -0,0 +1,17 @@
+// Package req is imported by testdata/flows.go.  Its Request type is
+// a source, like net/http.Request.
+package req
+
+type Request struct {
+	Query  string
+	Header map[string][]string
+}
+
+// Get returns a header of r.  Its parameter is not a source, since it
+// is declared in the package of Request.
+func Get(r *Request, key string) string {
+	if h := r.Header[key]; len(h) > 0 {
+		return h[0]
+	}
+	return ""
+}
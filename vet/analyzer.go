@@ -0,0 +1,95 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vet
+
+import (
+	"go/ast"
+	"reflect"
+)
+
+// An Analyzer describes a check performed by vet.
+//
+// Each enabled analyzer's Run function is called for every node of
+// the types listed in Nodes, in the order in which ast.Walk visits
+// them; its RunPackage function, if any, is then called once for the
+// package.  Analyzers report their findings using the Bad and Warn
+// methods of File; each Diagnostic bears the name of the analyzer that
+// reported it.
+//
+type Analyzer struct {
+	Name string // name of the check, used as its command-line flag
+	Doc  string // one-line description, used as the flag's usage
+
+	// Experimental analyzers are run only if requested explicitly,
+	// not by -all.
+	Experimental bool
+
+	// Nodes holds a nil value of each type of node of interest,
+	// e.g. (*ast.CallExpr)(nil).
+	Nodes []ast.Node
+
+	// Run is called for each node of the types in Nodes, with the
+	// file that contains it.
+	Run func(f *File, node ast.Node)
+
+	// RunPackage, if non-nil, is called once for each package, after
+	// Run has been applied to all of its files.  The package may
+	// include files other than Go source files, such as assembly.
+	RunPackage func(pkg *Package)
+
+	// Init, if non-nil, is called once, after the command-line flags
+	// have been parsed and before any package is checked.
+	Init func()
+}
+
+// Analyzers lists the standard analyzers, in the order in which they
+// are applied.
+var Analyzers = []*Analyzer{
+	asmdeclAnalyzer,
+	assignAnalyzer,
+	atomicAnalyzer,
+	boolsAnalyzer,
+	buildtagsAnalyzer,
+	compositesAnalyzer,
+	copylocksAnalyzer,
+	errcheckAnalyzer,
+	unreachableAnalyzer,
+	methodsAnalyzer,
+	nilfuncAnalyzer,
+	printfAnalyzer,
+	rangeloopsAnalyzer,
+	shadowAnalyzer,
+	structtagsAnalyzer,
+	unusedresultAnalyzer,
+}
+
+// A visitor applies a set of analyzers to the nodes of a file.
+type visitor struct {
+	f        *File
+	analyzer map[reflect.Type][]*Analyzer // analyzers interested in each node type
+}
+
+// newVisitor returns a visitor that applies the given analyzers to f.
+func newVisitor(f *File, analyzers []*Analyzer) *visitor {
+	v := &visitor{f: f, analyzer: make(map[reflect.Type][]*Analyzer)}
+	for _, a := range analyzers {
+		for _, n := range a.Nodes {
+			t := reflect.TypeOf(n)
+			v.analyzer[t] = append(v.analyzer[t], a)
+		}
+	}
+	return v
+}
+
+// Visit implements the ast.Visitor interface.
+func (v *visitor) Visit(node ast.Node) ast.Visitor {
+	if node != nil {
+		for _, a := range v.analyzer[reflect.TypeOf(node)] {
+			v.f.Pkg.analyzer = a
+			a.Run(v.f, node)
+		}
+	}
+	return v
+}
@@ -4,13 +4,12 @@
 
 // Identify mismatches between assembly files and Go func declarations.
 
-package main
+package vet
 
 import (
 	"bytes"
 	"fmt"
 	"go/ast"
-	"go/token"
 	"regexp"
 	"strconv"
 	"strings"
@@ -76,11 +75,13 @@ var (
 	asmOpcode    = re(`^\s*(?:[A-Z0-9a-z_]+:)?\s*([A-Z]+)\s*([^,]*)(?:,\s*(.*))?`)
 )
 
-func asmCheck(pkg *Package) {
-	if !vet("asmdecl") {
-		return
-	}
+var asmdeclAnalyzer = &Analyzer{
+	Name:       "asmdecl",
+	Doc:        "check assembly against Go declarations",
+	RunPackage: asmCheck,
+}
 
+func asmCheck(pkg *Package) {
 	// No work if no assembly files.
 	if !pkg.hasFileWithSuffix(".s") {
 		return
@@ -89,9 +90,9 @@ func asmCheck(pkg *Package) {
 	// Gather declarations. knownFunc[name][arch] is func description.
 	knownFunc := make(map[string]map[string]*asmFunc)
 
-	for _, f := range pkg.files {
-		if f.file != nil {
-			for _, decl := range f.file.Decls {
+	for _, f := range pkg.Files {
+		if f.AST != nil {
+			for _, decl := range f.AST.Decls {
 				if decl, ok := decl.(*ast.FuncDecl); ok && decl.Body == nil {
 					knownFunc[decl.Name.Name] = f.asmParseDecl(decl)
 				}
@@ -100,27 +101,27 @@ func asmCheck(pkg *Package) {
 	}
 
 	var fn *asmFunc
-	for _, f := range pkg.files {
-		if !strings.HasSuffix(f.name, ".s") {
+	for _, f := range pkg.Files {
+		if !strings.HasSuffix(f.Name, ".s") {
 			continue
 		}
-		Println("Checking file", f.name)
+		Println("Checking file", f.Name)
 
 		// Determine architecture from file name if possible.
 		var arch string
 		for _, a := range arches {
-			if strings.HasSuffix(f.name, "_"+a.name+".s") {
+			if strings.HasSuffix(f.Name, "_"+a.name+".s") {
 				arch = a.name
 				break
 			}
 		}
 
-		lines := strings.SplitAfter(string(f.content), "\n")
+		lines := strings.SplitAfter(string(f.Content), "\n")
 		for lineno, line := range lines {
 			lineno++
 
 			warnf := func(format string, args ...interface{}) {
-				f.Warnf(token.NoPos, "%s:%d: [%s] %s", f.name, lineno, arch, fmt.Sprintf(format, args...))
+				f.report(f.linePosition(lineno), SeverityWarning, fmt.Sprintf("[%s] %s", arch, fmt.Sprintf(format, args...)), nil)
 			}
 
 			if arch == "" {
@@ -140,7 +141,7 @@ func asmCheck(pkg *Package) {
 
 			if m := asmTEXT.FindStringSubmatch(line); m != nil {
 				if arch == "" {
-					f.Warnf(token.NoPos, "%s: cannot determine architecture for assembly file", f.name)
+					f.report(f.linePosition(0), SeverityWarning, "cannot determine architecture for assembly file", nil)
 					return
 				}
 				fn = knownFunc[m[1]][arch]
@@ -6,7 +6,7 @@
 This file contains the code to check for useless assignments.
 */
 
-package main
+package vet
 
 import (
 	"go/ast"
@@ -14,15 +14,21 @@ import (
 	"reflect"
 )
 
+var assignAnalyzer = &Analyzer{
+	Name:  "assign",
+	Doc:   "check for useless assignments",
+	Nodes: []ast.Node{(*ast.AssignStmt)(nil)},
+	Run:   checkAssignStmt,
+}
+
 // TODO: should also check for assignments to struct fields inside methods
 // that are on T instead of *T.
 
-// checkAssignStmt checks for assignments of the form "<expr> = <expr>".
+// checkAssignStmt checks for assignments of the form "<expr> = <expr>",
+// ignoring parentheses, in which <expr> has no side effects.
 // These are almost always useless, and even when they aren't they are usually a mistake.
-func (f *File) checkAssignStmt(stmt *ast.AssignStmt) {
-	if !vet("assign") {
-		return
-	}
+func checkAssignStmt(f *File, node ast.Node) {
+	stmt := node.(*ast.AssignStmt)
 	if stmt.Tok != token.ASSIGN {
 		return // ignore :=
 	}
@@ -31,10 +37,13 @@ func (f *File) checkAssignStmt(stmt *ast.AssignStmt) {
 		return
 	}
 	for i, lhs := range stmt.Lhs {
-		rhs := stmt.Rhs[i]
+		lhs, rhs := unparen(lhs), unparen(stmt.Rhs[i])
 		if reflect.TypeOf(lhs) != reflect.TypeOf(rhs) {
 			continue // short-circuit the heavy-weight gofmt check
 		}
+		if f.hasSideEffects(lhs) || f.hasSideEffects(rhs) {
+			continue // e.g. a[next()] = a[next()]
+		}
 		le := f.gofmt(lhs)
 		re := f.gofmt(rhs)
 		if le == re {
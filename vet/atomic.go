@@ -2,20 +2,24 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package vet
 
 import (
 	"go/ast"
 	"go/token"
 )
 
+var atomicAnalyzer = &Analyzer{
+	Name:  "atomic",
+	Doc:   "check for common mistaken usages of the sync/atomic package",
+	Nodes: []ast.Node{(*ast.AssignStmt)(nil)},
+	Run:   checkAtomicAssignment,
+}
+
 // checkAtomicAssignment walks the assignment statement checking for common
 // mistaken usage of atomic package, such as: x = atomic.AddUint64(&x, 1)
-func (f *File) checkAtomicAssignment(n *ast.AssignStmt) {
-	if !vet("atomic") {
-		return
-	}
-
+func checkAtomicAssignment(f *File, node ast.Node) {
+	n := node.(*ast.AssignStmt)
 	if len(n.Lhs) != len(n.Rhs) {
 		return
 	}
@@ -0,0 +1,183 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains the checks for mistakes in boolean and comparison
+// expressions.
+
+package vet
+
+import (
+	"go/ast"
+	"go/token"
+
+	"code.google.com/p/go.tools/go/exact"
+	"code.google.com/p/go.tools/go/types"
+)
+
+var boolsAnalyzer = &Analyzer{
+	Name:  "bools",
+	Doc:   "check for redundant or impossible boolean expressions and self-comparisons",
+	Nodes: []ast.Node{(*ast.BinaryExpr)(nil)},
+	Run:   checkBool,
+}
+
+// checkBool checks a || or && expression, together with the operands
+// of the chain of the same operator to which it belongs, and checks
+// a comparison for comparing an expression with itself.
+func checkBool(f *File, node ast.Node) {
+	e := node.(*ast.BinaryExpr)
+	switch e.Op {
+	case token.LOR, token.LAND:
+	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+		f.checkSelfComparison(e)
+		return
+	default:
+		return
+	}
+	if f.boolSeen[e] {
+		return // part of a chain already checked
+	}
+	if f.boolSeen == nil {
+		f.boolSeen = make(map[*ast.BinaryExpr]bool)
+	}
+	operands := f.boolOperands(e, e.Op)
+	f.checkRedundant(e.Op, operands)
+	f.checkSuspect(e.Op, operands)
+}
+
+// boolOperands returns the operands of the chain of op expressions
+// rooted at e, without parentheses, such as a, b and c for
+// (a || b) || (c), recording the inner expressions of the chain as
+// seen.
+func (f *File) boolOperands(e ast.Expr, op token.Token) []ast.Expr {
+	e = unparen(e)
+	b, ok := e.(*ast.BinaryExpr)
+	if !ok || b.Op != op {
+		return []ast.Expr{e}
+	}
+	f.boolSeen[b] = true
+	return append(f.boolOperands(b.X, op), f.boolOperands(b.Y, op)...)
+}
+
+// checkRedundant reports an operand that is repeated in a chain of ||
+// or && expressions, as in x == a || x == a.
+func (f *File) checkRedundant(op token.Token, operands []ast.Expr) {
+	seen := make(map[string]bool)
+	for _, x := range operands {
+		if f.hasSideEffects(x) {
+			continue
+		}
+		s := f.gofmt(x)
+		if seen[s] {
+			f.Badf(x.Pos(), "redundant %s: %s %s %s", boolOpName(op), s, op, s)
+		}
+		seen[s] = true
+	}
+}
+
+// checkSuspect reports a chain of || expressions that compares an
+// expression as unequal to two distinct constants, which is always
+// true, or a chain of && expressions that compares it as equal to two
+// distinct constants, which is always false.
+func (f *File) checkSuspect(op token.Token, operands []ast.Expr) {
+	cmp := token.NEQ // x != a || x != b
+	if op == token.LAND {
+		cmp = token.EQL // x == a && x == b
+	}
+	seen := make(map[string]ast.Expr) // first constant compared to each expression
+	for _, operand := range operands {
+		b, ok := unparen(operand).(*ast.BinaryExpr)
+		if !ok || b.Op != cmp {
+			continue
+		}
+		x, c := b.X, b.Y
+		if f.Pkg.Info.Values[c] == nil {
+			x, c = c, x
+		}
+		if f.Pkg.Info.Values[c] == nil || f.Pkg.Info.Values[x] != nil || f.hasSideEffects(x) {
+			continue
+		}
+		s := f.gofmt(x)
+		prev, ok := seen[s]
+		if !ok {
+			seen[s] = c
+			continue
+		}
+		if distinctValues(f.Pkg.Info.Values[prev], f.Pkg.Info.Values[c]) {
+			f.Badf(operand.Pos(), "suspect %s: %s %s %s %s %s %s %s", boolOpName(op), s, cmp, f.gofmt(prev), op, s, cmp, f.gofmt(c))
+		}
+	}
+}
+
+// checkSelfComparison reports a comparison of an expression with
+// itself, as in x == x.  Comparisons of floating-point values are
+// allowed, since x != x is the usual test for NaN.
+func (f *File) checkSelfComparison(e *ast.BinaryExpr) {
+	if f.hasSideEffects(e.X) || f.hasSideEffects(e.Y) {
+		return
+	}
+	typ := f.Pkg.Info.Types[e.X]
+	if typ == nil || f.Pkg.Info.Values[e.X] != nil {
+		return // no type information, so it may be a float; or a constant
+	}
+	if t, ok := typ.Underlying().(*types.Basic); ok && t.Info()&(types.IsFloat|types.IsComplex) != 0 {
+		return
+	}
+	if x, y := f.gofmt(e.X), f.gofmt(e.Y); x == y {
+		f.Badf(e.Pos(), "suspicious self-comparison: %s %s %s", x, e.Op, y)
+	}
+}
+
+// hasSideEffects reports whether evaluating e may have side effects:
+// whether it contains a call that is not a conversion or of a pure
+// built-in function, or a receive.
+func (f *File) hasSideEffects(e ast.Expr) bool {
+	effects := false
+	ast.Inspect(e, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.CallExpr:
+			switch f.calleeName(n) {
+			case "len", "cap", "real", "imag", "complex":
+				return true
+			}
+			if _, ok := f.Pkg.Info.Types[n.Fun].(*types.Signature); ok || f.Pkg.Info.Types[n.Fun] == nil {
+				// A call, or possibly one.
+				effects = true
+				return false
+			}
+		case *ast.UnaryExpr:
+			if n.Op == token.ARROW {
+				effects = true
+				return false
+			}
+		case *ast.FuncLit:
+			return false // its body is not evaluated here
+		}
+		return true
+	})
+	return effects
+}
+
+// distinctValues reports whether the constants x and y are known to
+// differ.
+func distinctValues(x, y exact.Value) bool {
+	if x.Kind() != y.Kind() && !(isNumericKind(x.Kind()) && isNumericKind(y.Kind())) {
+		return false
+	}
+	if x.Kind() == exact.Unknown {
+		return false
+	}
+	return exact.Compare(x, token.NEQ, y)
+}
+
+func isNumericKind(k exact.Kind) bool {
+	return k == exact.Int || k == exact.Float || k == exact.Complex
+}
+
+func boolOpName(op token.Token) string {
+	if op == token.LAND {
+		return "and"
+	}
+	return "or"
+}
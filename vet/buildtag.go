@@ -2,28 +2,38 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package vet
 
 import (
 	"bytes"
 	"fmt"
-	"os"
 	"strings"
 	"unicode"
 )
 
+var buildtagsAnalyzer = &Analyzer{
+	Name:       "buildtags",
+	Doc:        "check that +build tags are valid",
+	RunPackage: checkBuildTags,
+}
+
 var (
 	nl         = []byte("\n")
 	slashSlash = []byte("//")
 	plusBuild  = []byte("+build")
 )
 
-// checkBuildTag checks that build tags are in the correct location and well-formed.
-func checkBuildTag(name string, data []byte) {
-	if !vet("buildtags") {
-		return
+// checkBuildTags checks the build tags of each file of the package,
+// including assembly files.
+func checkBuildTags(pkg *Package) {
+	for _, f := range pkg.Files {
+		f.checkBuildTag()
 	}
-	lines := bytes.SplitAfter(data, nl)
+}
+
+// checkBuildTag checks that build tags are in the correct location and well-formed.
+func (f *File) checkBuildTag() {
+	lines := bytes.SplitAfter(f.Content, nl)
 
 	// Determine cutpoint where +build comments are no longer valid.
 	// They are valid in leading // comments in the file followed by
@@ -51,12 +61,11 @@ func checkBuildTag(name string, data []byte) {
 			fields := bytes.Fields(text)
 			if !bytes.Equal(fields[0], plusBuild) {
 				// Comment is something like +buildasdf not +build.
-				fmt.Fprintf(os.Stderr, "%s:%d: possible malformed +build comment\n", name, i+1)
+				f.report(f.linePosition(i+1), SeverityWarning, "possible malformed +build comment", nil)
 				continue
 			}
 			if i >= cutoff {
-				fmt.Fprintf(os.Stderr, "%s:%d: +build comment appears too late in file\n", name, i+1)
-				setExit(1)
+				f.report(f.linePosition(i+1), SeverityError, "+build comment appears too late in file", nil)
 				continue
 			}
 			// Check arguments.
@@ -64,8 +73,7 @@ func checkBuildTag(name string, data []byte) {
 			for _, arg := range fields[1:] {
 				for _, elem := range strings.Split(string(arg), ",") {
 					if strings.HasPrefix(elem, "!!") {
-						fmt.Fprintf(os.Stderr, "%s:%d: invalid double negative in build constraint: %s\n", name, i+1, arg)
-						setExit(1)
+						f.report(f.linePosition(i+1), SeverityError, fmt.Sprintf("invalid double negative in build constraint: %s", arg), nil)
 						break Args
 					}
 					if strings.HasPrefix(elem, "!") {
@@ -73,8 +81,7 @@ func checkBuildTag(name string, data []byte) {
 					}
 					for _, c := range elem {
 						if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '.' {
-							fmt.Fprintf(os.Stderr, "%s:%d: invalid non-alphanumeric build constraint: %s\n", name, i+1, arg)
-							setExit(1)
+							f.report(f.linePosition(i+1), SeverityError, fmt.Sprintf("invalid non-alphanumeric build constraint: %s", arg), nil)
 							break Args
 						}
 					}
@@ -84,7 +91,7 @@ func checkBuildTag(name string, data []byte) {
 		}
 		// Comment with +build but not at beginning.
 		if bytes.Contains(line, plusBuild) && i < cutoff {
-			fmt.Fprintf(os.Stderr, "%s:%d: possible malformed +build comment\n", name, i+1)
+			f.report(f.linePosition(i+1), SeverityWarning, "possible malformed +build comment", nil)
 			continue
 		}
 	}
@@ -4,7 +4,7 @@
 
 // This file contains the test for unkeyed struct literals.
 
-package main
+package vet
 
 import (
 	"flag"
@@ -12,16 +12,22 @@ import (
 	"strings"
 
 	"code.google.com/p/go.tools/cmd/vet/whitelist"
+	"code.google.com/p/go.tools/go/types"
 )
 
 var compositeWhiteList = flag.Bool("compositewhitelist", true, "use composite white list; for testing only")
 
+var compositesAnalyzer = &Analyzer{
+	Name:  "composites",
+	Doc:   "check that composite literals used field-keyed elements",
+	Nodes: []ast.Node{(*ast.CompositeLit)(nil)},
+	Run:   checkUnkeyedLiteral,
+}
+
 // checkUnkeyedLiteral checks if a composite literal is a struct literal with
 // unkeyed fields.
-func (f *File) checkUnkeyedLiteral(c *ast.CompositeLit) {
-	if !vet("composites") {
-		return
-	}
+func checkUnkeyedLiteral(f *File, node ast.Node) {
+	c := node.(*ast.CompositeLit)
 
 	typ := c.Type
 	for {
@@ -50,7 +56,7 @@ func (f *File) checkUnkeyedLiteral(c *ast.CompositeLit) {
 
 	// Otherwise the type is a selector like pkg.Name.
 	// We only care if pkg.Name is a struct, not if it's a map, array, or slice.
-	isStruct, typeString := f.pkg.isStruct(c)
+	isStruct, typeString := f.Pkg.isStruct(c)
 	if !isStruct {
 		return
 	}
@@ -94,7 +100,30 @@ func (f *File) checkUnkeyedLiteral(c *ast.CompositeLit) {
 		return
 	}
 
-	f.Warn(c.Pos(), typeString+" composite literal uses unkeyed fields")
+	f.WarnFixf(c.Pos(), f.keyedLiteralFix(c), "%s composite literal uses unkeyed fields", typeString)
+}
+
+// keyedLiteralFix returns the edits that add field names to the
+// elements of the unkeyed struct literal c, or nil if the type of the
+// literal is unknown.
+//
+func (f *File) keyedLiteralFix(c *ast.CompositeLit) []TextEdit {
+	typ := f.Pkg.Info.Types[c]
+	if typ == nil {
+		return nil
+	}
+	st, ok := typ.Underlying().(*types.Struct)
+	if !ok || st.NumFields() != len(c.Elts) {
+		return nil
+	}
+	var edits []TextEdit
+	for i, e := range c.Elts {
+		if _, ok := e.(*ast.KeyValueExpr); ok {
+			return nil
+		}
+		edits = append(edits, f.Edit(e.Pos(), e.Pos(), st.Field(i).Name()+": "))
+	}
+	return edits
 }
 
 // pkgPath returns the import path "image/png" for the package name "png".
@@ -103,7 +132,7 @@ func (f *File) checkUnkeyedLiteral(c *ast.CompositeLit) {
 // package's contents. It will be incorrect if a package name differs from the
 // leaf element of the import path, or if the package was a dot import.
 func pkgPath(f *File, pkgName string) (path string) {
-	for _, x := range f.file.Imports {
+	for _, x := range f.AST.Imports {
 		s := strings.Trim(x.Path.Value, `"`)
 		if x.Name != nil {
 			// Catch `import pkgName "foo/bar"`.
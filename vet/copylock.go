@@ -0,0 +1,228 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains the code to check that locks are not passed by value.
+
+package vet
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+var copylocksAnalyzer = &Analyzer{
+	Name: "copylocks",
+	Doc:  "check that locks are not passed by value",
+	Nodes: []ast.Node{
+		(*ast.FuncDecl)(nil),
+		(*ast.FuncLit)(nil),
+		(*ast.RangeStmt)(nil),
+		(*ast.AssignStmt)(nil),
+		(*ast.GenDecl)(nil),
+		(*ast.CompositeLit)(nil),
+	},
+	Run: checkCopyLocks,
+}
+
+// checkCopyLocks checks whether node might
+// inadvertently copy a lock.
+func checkCopyLocks(f *File, node ast.Node) {
+	switch node := node.(type) {
+	case *ast.FuncDecl:
+		checkCopyLocksFunc(f, node.Name.Name, node.Recv, node.Type)
+	case *ast.FuncLit:
+		checkCopyLocksFunc(f, "func", nil, node.Type)
+	case *ast.RangeStmt:
+		checkCopyLocksRange(f, node)
+	case *ast.AssignStmt:
+		checkCopyLocksAssign(f, node)
+	case *ast.GenDecl:
+		checkCopyLocksGenDecl(f, node)
+	case *ast.CompositeLit:
+		checkCopyLocksCompositeLit(f, node)
+	}
+}
+
+// checkCopyLocksAssign checks whether an assignment
+// copies a lock.  Assignments to the blank identifier are ignored.
+func checkCopyLocksAssign(f *File, as *ast.AssignStmt) {
+	if len(as.Lhs) != len(as.Rhs) {
+		return
+	}
+	for i, x := range as.Rhs {
+		if id, ok := as.Lhs[i].(*ast.Ident); ok && id.Name == "_" {
+			continue
+		}
+		if path := lockPathRhs(f, x); path != nil {
+			f.Badf(x.Pos(), "assignment copies lock value to %v: %v", f.gofmt(as.Lhs[i]), path)
+		}
+	}
+}
+
+// checkCopyLocksGenDecl checks whether a variable
+// declaration copies a lock.
+func checkCopyLocksGenDecl(f *File, gd *ast.GenDecl) {
+	if gd.Tok != token.VAR {
+		return
+	}
+	for _, spec := range gd.Specs {
+		valueSpec := spec.(*ast.ValueSpec)
+		for i, x := range valueSpec.Values {
+			if path := lockPathRhs(f, x); path != nil {
+				f.Badf(x.Pos(), "variable declaration copies lock value to %v: %v", valueSpec.Names[i].Name, path)
+			}
+		}
+	}
+}
+
+// checkCopyLocksCompositeLit detects lock copy inside a composite literal.
+func checkCopyLocksCompositeLit(f *File, cl *ast.CompositeLit) {
+	for _, x := range cl.Elts {
+		if node, ok := x.(*ast.KeyValueExpr); ok {
+			x = node.Value
+		}
+		if path := lockPathRhs(f, x); path != nil {
+			f.Badf(x.Pos(), "literal copies lock value from %v: %v", f.gofmt(x), path)
+		}
+	}
+}
+
+// checkCopyLocksFunc checks whether a function might
+// inadvertently copy a lock, by checking whether
+// its receiver or parameters are locks.
+func checkCopyLocksFunc(f *File, name string, recv *ast.FieldList, typ *ast.FuncType) {
+	if recv != nil && len(recv.List) > 0 {
+		expr := recv.List[0].Type
+		if path := lockPath(f.Pkg.Info.Types[expr]); path != nil {
+			f.Badf(expr.Pos(), "%s passes lock by value: %v", name, path)
+		}
+	}
+
+	if typ.Params != nil {
+		for _, field := range typ.Params.List {
+			expr := field.Type
+			if path := lockPath(f.Pkg.Info.Types[expr]); path != nil {
+				f.Badf(expr.Pos(), "%s passes lock by value: %v", name, path)
+			}
+		}
+	}
+}
+
+// checkCopyLocksRange checks whether a range statement
+// might inadvertently copy a lock by checking whether
+// any of the range variables are locks.
+func checkCopyLocksRange(f *File, r *ast.RangeStmt) {
+	checkCopyLocksRangeVar(f, r.Tok, r.Key)
+	checkCopyLocksRangeVar(f, r.Tok, r.Value)
+}
+
+func checkCopyLocksRangeVar(f *File, rtok token.Token, e ast.Expr) {
+	if e == nil {
+		return
+	}
+	id, isId := e.(*ast.Ident)
+	if isId && id.Name == "_" {
+		return
+	}
+
+	var typ types.Type
+	if rtok == token.DEFINE {
+		if !isId {
+			return
+		}
+		obj := f.Pkg.Info.Objects[id]
+		if obj == nil {
+			return
+		}
+		typ = obj.Type()
+	} else {
+		typ = f.Pkg.Info.Types[e]
+	}
+
+	if path := lockPath(typ); path != nil {
+		f.Badf(e.Pos(), "range var %s copies lock: %v", f.gofmt(e), path)
+	}
+}
+
+// A typePath is the sequence of types, each containing the next, that
+// leads to a lock.
+type typePath []types.Type
+
+// String pretty-prints a typePath.
+func (path typePath) String() string {
+	var buf bytes.Buffer
+	for i, typ := range path {
+		if i > 0 {
+			fmt.Fprint(&buf, " contains ")
+		}
+		fmt.Fprint(&buf, typ.String())
+	}
+	return buf.String()
+}
+
+// lockPathRhs returns the path to a lock copied by evaluating x, or
+// nil if there is none.  Composite literals and function calls yield
+// new values, so they copy nothing.
+func lockPathRhs(f *File, x ast.Expr) typePath {
+	switch x.(type) {
+	case *ast.CompositeLit, *ast.CallExpr:
+		return nil
+	}
+	if star, ok := x.(*ast.StarExpr); ok {
+		if _, ok := star.X.(*ast.CallExpr); ok {
+			// A call may return a pointer to a zero value.
+			return nil
+		}
+	}
+	return lockPath(f.Pkg.Info.Types[x])
+}
+
+// lockPath returns a typePath describing the location of a lock value
+// contained in typ. If there is no contained lock, it returns nil.
+func lockPath(typ types.Type) typePath {
+	if typ == nil {
+		return nil
+	}
+
+	// Copying an array copies its elements.
+	if atyp, ok := typ.Underlying().(*types.Array); ok {
+		if subpath := lockPath(atyp.Elem()); subpath != nil {
+			return append(typePath{typ}, subpath...)
+		}
+		return nil
+	}
+
+	// We're only interested in the case in which the underlying
+	// type is a struct. (Interfaces and pointers are safe to copy.)
+	styp, ok := typ.Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+
+	// We're looking for cases in which a reference to this type
+	// can be locked, but a value cannot. This differentiates
+	// embedded interfaces from embedded values.
+	if hasLockMethods(types.NewPointer(typ)) && !hasLockMethods(typ) {
+		return typePath{typ}
+	}
+
+	for i := 0; i < styp.NumFields(); i++ {
+		ftyp := styp.Field(i).Type()
+		if subpath := lockPath(ftyp); subpath != nil {
+			return append(typePath{typ}, subpath...)
+		}
+	}
+	return nil
+}
+
+// hasLockMethods reports whether the method set of typ includes both
+// Lock and Unlock.
+func hasLockMethods(typ types.Type) bool {
+	mset := types.NewMethodSet(typ)
+	return mset.Lookup(nil, "Lock") != nil && mset.Lookup(nil, "Unlock") != nil
+}
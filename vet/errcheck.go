@@ -0,0 +1,172 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains the check for errors returned by calls that are
+// not checked.
+
+package vet
+
+import (
+	"flag"
+	"go/ast"
+	"strings"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+var errcheckExclude = flag.String("errcheckexclude", "", "comma-separated list of functions whose errors need not be checked")
+var errcheckDefer = flag.String("errcheckdefer", "noclose", "which deferred calls to check for unchecked errors: none, noclose or all")
+
+var errcheckAnalyzer = &Analyzer{
+	Name:         "errcheck",
+	Doc:          "check for errors returned by calls that are not checked",
+	Experimental: true,
+	Nodes: []ast.Node{
+		(*ast.ExprStmt)(nil),
+		(*ast.DeferStmt)(nil),
+		(*ast.AssignStmt)(nil),
+	},
+	Init: initErrcheck,
+	Run:  checkUncheckedErrors,
+}
+
+// errcheckExcluded records the functions whose errors need not be
+// checked, named as in unusedFuncs.  A name ending in "*" stands for
+// all the names of which the rest is a prefix.  The functions of
+// unusedFuncs, such as errors.New, are excluded too: discarding their
+// results is reported by the unusedresult check.
+var errcheckExcluded = []string{
+	"fmt.Fprint*",
+	"fmt.Print*",
+	"(*bytes.Buffer).Write",
+	"(*bytes.Buffer).WriteByte",
+	"(*bytes.Buffer).WriteRune",
+	"(*bytes.Buffer).WriteString",
+}
+
+// The levels of -errcheckdefer.
+const (
+	deferNone    = "none"    // deferred calls are not checked
+	deferNoClose = "noclose" // deferred calls are checked, except of Close methods
+	deferAll     = "all"     // all deferred calls are checked
+)
+
+// universeError is the predeclared type error.
+var universeError = types.Universe.Lookup("error").Type()
+
+// initErrcheck adds the functions named by the -errcheckexclude flag
+// to errcheckExcluded and validates -errcheckdefer.
+func initErrcheck() {
+	switch *errcheckDefer {
+	case deferNone, deferNoClose, deferAll:
+	default:
+		errorf("illegal value %q for -errcheckdefer; must be none, noclose or all", *errcheckDefer)
+	}
+	if *errcheckExclude == "" {
+		return
+	}
+	for _, name := range strings.Split(*errcheckExclude, ",") {
+		if len(name) == 0 {
+			flag.Usage()
+		}
+		errcheckExcluded = append(errcheckExcluded, name)
+	}
+}
+
+// checkUncheckedErrors reports calls whose error results are discarded,
+// either because the call is a statement or because the errors are
+// assigned to the blank identifier.
+func checkUncheckedErrors(f *File, node ast.Node) {
+	switch n := node.(type) {
+	case *ast.ExprStmt:
+		if call, ok := unparen(n.X).(*ast.CallExpr); ok {
+			if f.errorResults(call) != nil {
+				f.reportUncheckedError(call)
+			}
+		}
+	case *ast.DeferStmt:
+		if *errcheckDefer == deferNone || f.errorResults(n.Call) == nil {
+			return
+		}
+		if *errcheckDefer == deferNoClose {
+			if sel, ok := unparen(n.Call.Fun).(*ast.SelectorExpr); ok && sel.Sel.Name == "Close" {
+				return
+			}
+		}
+		f.reportUncheckedError(n.Call)
+	case *ast.AssignStmt:
+		if len(n.Rhs) == 1 {
+			// x, _ := f()
+			call, ok := unparen(n.Rhs[0]).(*ast.CallExpr)
+			if !ok {
+				return
+			}
+			for _, i := range f.errorResults(call) {
+				if i < len(n.Lhs) && isBlank(n.Lhs[i]) {
+					f.reportUncheckedError(call)
+					return
+				}
+			}
+			return
+		}
+		// _, y = f(), g()
+		for i, rhs := range n.Rhs {
+			call, ok := unparen(rhs).(*ast.CallExpr)
+			if ok && i < len(n.Lhs) && isBlank(n.Lhs[i]) && f.errorResults(call) != nil {
+				f.reportUncheckedError(call)
+			}
+		}
+	}
+}
+
+// errorResults returns the indices of the results of type error of
+// the function called by call.  It returns nil if there are none, if
+// the callee is excluded, or if there is no type information.
+func (f *File) errorResults(call *ast.CallExpr) []int {
+	sig, ok := f.Pkg.Info.Types[call.Fun].(*types.Signature)
+	if !ok {
+		return nil // a conversion or a built-in, or no type information
+	}
+	if name := f.calleeName(call); name != "" && errcheckIsExcluded(name) {
+		return nil
+	}
+	var indices []int
+	results := sig.Results()
+	for i := 0; results != nil && i < results.Len(); i++ {
+		if types.IsIdentical(results.At(i).Type(), universeError) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// reportUncheckedError reports that the error returned by call is not
+// checked.
+func (f *File) reportUncheckedError(call *ast.CallExpr) {
+	name := f.calleeName(call)
+	if name == "" {
+		name = f.gofmt(call.Fun)
+	}
+	f.Badf(call.Pos(), "unchecked error returned by %s", name)
+}
+
+// errcheckIsExcluded reports whether the named function is listed in
+// errcheckExcluded or in unusedFuncs.
+func errcheckIsExcluded(name string) bool {
+	if unusedFuncs[name] {
+		return true
+	}
+	for _, x := range errcheckExcluded {
+		if x == name || strings.HasSuffix(x, "*") && strings.HasPrefix(name, x[:len(x)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBlank reports whether x is the blank identifier.
+func isBlank(x ast.Expr) bool {
+	id, ok := x.(*ast.Ident)
+	return ok && id.Name == "_"
+}
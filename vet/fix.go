@@ -0,0 +1,138 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vet
+
+// This file applies the fixes suggested by diagnostics.
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"code.google.com/p/go.tools/refactor/util"
+)
+
+var fixFlag = flag.Bool("fix", false, "apply the fixes suggested for problems, rewriting the files")
+var diffFlag = flag.Bool("d", false, "with -fix, display diffs instead of rewriting files")
+
+// applyFixes applies the edits suggested for each of the files, then
+// rewrites the file or, with -d, prints a diff of the change.
+//
+func applyFixes(files []*File) {
+	for _, f := range files {
+		if f.fixes == nil {
+			continue
+		}
+		content, err := f.applyEdits()
+		if err != nil {
+			warnf("%s: cannot apply fixes: %s", f.Name, err)
+			continue
+		}
+		if *diffFlag {
+			err = util.Diff(os.Stdout, f.Name, content)
+		} else {
+			err = writeFile(f.Name, content)
+		}
+		if err != nil {
+			warnf("%s: %s", f.Name, err)
+		}
+	}
+}
+
+// applyEdits returns the content of the file after applying the
+// suggested fixes.  A fix is skipped if any of its edits conflicts
+// with those of a fix already accepted.  The result is formatted with
+// go/printer if it is a Go source file.
+//
+func (f *File) applyEdits() ([]byte, error) {
+	var edits []TextEdit
+	for _, fix := range f.fixes {
+		if !conflicts(edits, fix) {
+			for _, e := range fix {
+				if !containsEdit(edits, e) {
+					edits = append(edits, e)
+				}
+			}
+		}
+	}
+	sort.Sort(editsByPos(edits))
+
+	var buf bytes.Buffer
+	offset := 0
+	for _, e := range edits {
+		if e.Pos < offset || e.End < e.Pos || e.End > len(f.Content) {
+			return nil, fmt.Errorf("invalid edit %+v", e)
+		}
+		buf.Write(f.Content[offset:e.Pos])
+		buf.WriteString(e.NewText)
+		offset = e.End
+	}
+	buf.Write(f.Content[offset:])
+	if !strings.HasSuffix(f.Name, ".go") {
+		return buf.Bytes(), nil
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, f.Name, buf.Bytes(), parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("fixes produced invalid code: %s", err)
+	}
+	var out bytes.Buffer
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	if err := cfg.Fprint(&out, fset, file); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// conflicts reports whether any edit of fix overlaps one of edits,
+// other than an identical one.  Insertions at the same offset overlap.
+//
+func conflicts(edits, fix []TextEdit) bool {
+	for _, x := range fix {
+		for _, y := range edits {
+			if x != y && (x.Pos < y.End && y.Pos < x.End || x.Pos == y.Pos) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsEdit(edits []TextEdit, e TextEdit) bool {
+	for _, x := range edits {
+		if x == e {
+			return true
+		}
+	}
+	return false
+}
+
+// writeFile replaces the contents of the named file, preserving its mode.
+func writeFile(filename string, content []byte) error {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, content, fi.Mode().Perm())
+}
+
+type editsByPos []TextEdit
+
+func (s editsByPos) Len() int      { return len(s) }
+func (s editsByPos) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s editsByPos) Less(i, j int) bool {
+	if s[i].Pos != s[j].Pos {
+		return s[i].Pos < s[j].Pos
+	}
+	return s[i].End < s[j].End
+}
@@ -4,7 +4,7 @@
 
 // This file contains the code to check canonical methods.
 
-package main
+package vet
 
 import (
 	"fmt"
@@ -55,10 +55,31 @@ var canonicalMethods = map[string]MethodSig{
 	"WriteTo":       {[]string{"=io.Writer"}, []string{"int64", "error"}}, // io.WriterTo
 }
 
-func (f *File) checkCanonicalMethod(id *ast.Ident, t *ast.FuncType) {
-	if !vet("methods") {
-		return
+var methodsAnalyzer = &Analyzer{
+	Name:  "methods",
+	Doc:   "check that canonically named methods are canonically defined",
+	Nodes: []ast.Node{(*ast.FuncDecl)(nil), (*ast.InterfaceType)(nil)},
+	Run:   checkCanonicalMethods,
+}
+
+// checkCanonicalMethods checks the signatures of a method declaration
+// or of the methods of an interface type.
+func checkCanonicalMethods(f *File, node ast.Node) {
+	switch n := node.(type) {
+	case *ast.FuncDecl:
+		if n.Recv != nil {
+			f.checkCanonicalMethod(n.Name, n.Type)
+		}
+	case *ast.InterfaceType:
+		for _, field := range n.Methods.List {
+			for _, id := range field.Names {
+				f.checkCanonicalMethod(id, field.Type.(*ast.FuncType))
+			}
+		}
 	}
+}
+
+func (f *File) checkCanonicalMethod(id *ast.Ident, t *ast.FuncType) {
 	// Expected input/output.
 	expect, ok := canonicalMethods[id.Name]
 	if !ok {
@@ -87,7 +108,7 @@ func (f *File) checkCanonicalMethod(id *ast.Ident, t *ast.FuncType) {
 		}
 
 		f.b.Reset()
-		if err := printer.Fprint(&f.b, f.fset, t); err != nil {
+		if err := printer.Fprint(&f.b, f.Fset, t); err != nil {
 			fmt.Fprintf(&f.b, "<%s>", err)
 		}
 		actual := f.b.String()
@@ -152,12 +173,12 @@ func (f *File) matchParamType(expect string, actual ast.Expr) bool {
 		expect = expect[1:]
 	}
 	// Strip package name if we're in that package.
-	if n := len(f.file.Name.Name); len(expect) > n && expect[:n] == f.file.Name.Name && expect[n] == '.' {
+	if n := len(f.AST.Name.Name); len(expect) > n && expect[:n] == f.AST.Name.Name && expect[n] == '.' {
 		expect = expect[n+1:]
 	}
 
 	// Overkill but easy.
 	f.b.Reset()
-	printer.Fprint(&f.b, f.fset, actual)
+	printer.Fprint(&f.b, f.Fset, actual)
 	return f.b.String() == expect
 }
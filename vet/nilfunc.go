@@ -7,7 +7,7 @@ This file contains the code to check for useless function comparisons.
 A useless comparison is one like f == nil as opposed to f() == nil.
 */
 
-package main
+package vet
 
 import (
 	"go/ast"
@@ -16,10 +16,15 @@ import (
 	"code.google.com/p/go.tools/go/types"
 )
 
-func (f *File) checkNilFuncComparison(e *ast.BinaryExpr) {
-	if !vet("nilfunc") {
-		return
-	}
+var nilfuncAnalyzer = &Analyzer{
+	Name:  "nilfunc",
+	Doc:   "check for comparisons between functions and nil",
+	Nodes: []ast.Node{(*ast.BinaryExpr)(nil)},
+	Run:   checkNilFuncComparison,
+}
+
+func checkNilFuncComparison(f *File, node ast.Node) {
+	e := node.(*ast.BinaryExpr)
 
 	// Only want == or != comparisons.
 	if e.Op != token.EQL && e.Op != token.NEQ {
@@ -41,9 +46,9 @@ func (f *File) checkNilFuncComparison(e *ast.BinaryExpr) {
 	var obj types.Object
 	switch v := e2.(type) {
 	case *ast.Ident:
-		obj = f.pkg.idents[v]
+		obj = f.Pkg.Info.Objects[v]
 	case *ast.SelectorExpr:
-		obj = f.pkg.idents[v.Sel]
+		obj = f.Pkg.Info.Objects[v.Sel]
 	default:
 		return
 	}
@@ -59,5 +64,5 @@ func (f *File) checkNilFuncComparison(e *ast.BinaryExpr) {
 // isNil reports whether the provided expression is the built-in nil
 // identifier.
 func (f *File) isNil(e ast.Expr) bool {
-	return f.pkg.types[e] == types.Typ[types.UntypedNil]
+	return f.Pkg.Info.Types[e] == types.Typ[types.UntypedNil]
 }
@@ -4,7 +4,7 @@
 
 // This file contains the printf-checker.
 
-package main
+package vet
 
 import (
 	"bytes"
@@ -17,10 +17,47 @@ import (
 	"unicode/utf8"
 
 	"code.google.com/p/go.tools/go/exact"
+	"code.google.com/p/go.tools/go/types"
 )
 
 var printfuncs = flag.String("printfuncs", "", "comma-separated list of print function names to check")
 
+var printfAnalyzer = &Analyzer{
+	Name:  "printf",
+	Doc:   "check printf-like invocations",
+	Nodes: []ast.Node{(*ast.FuncDecl)(nil), (*ast.CallExpr)(nil)},
+	Init:  initPrintFuncs,
+	Run:   checkFmtPrintfCall,
+}
+
+// initPrintFuncs adds the functions named by the -printfuncs flag to
+// printfList and printList.
+func initPrintFuncs() {
+	if *printfuncs == "" {
+		return
+	}
+	for _, name := range strings.Split(*printfuncs, ",") {
+		if len(name) == 0 {
+			flag.Usage()
+		}
+		skip := 0
+		if colon := strings.LastIndex(name, ":"); colon > 0 {
+			var err error
+			skip, err = strconv.Atoi(name[colon+1:])
+			if err != nil {
+				errorf(`illegal format for "Func:N" argument %q; %s`, name, err)
+			}
+			name = name[:colon]
+		}
+		name = strings.ToLower(name)
+		if name[len(name)-1] == 'f' {
+			printfList[name] = skip
+		} else {
+			printList[name] = skip
+		}
+	}
+}
+
 // printfList records the formatted-print functions. The value is the location
 // of the format parameter. Names are lower-cased so the lookup is
 // case insensitive.
@@ -45,20 +82,66 @@ var printList = map[string]int{
 	"sprint": 0, "sprintln": 0,
 }
 
-// checkCall triggers the print-specific checks if the call invokes a print function.
-func (f *File) checkFmtPrintfCall(call *ast.CallExpr, Name string) {
-	if !vet("printf") {
+// checkFmtPrintfCall triggers the print-specific checks if the call
+// invokes a print function, including a print wrapper inferred by
+// printWrappersOf.  It also records the receivers of String
+// methods, for use by recursiveStringer.
+func checkFmtPrintfCall(f *File, node ast.Node) {
+	if d, ok := node.(*ast.FuncDecl); ok {
+		f.prepStringerReceiver(d)
 		return
 	}
-	name := strings.ToLower(Name)
-	if skip, ok := printfList[name]; ok {
-		f.checkPrintf(call, Name, skip)
+	call := node.(*ast.CallExpr)
+	var Name string
+	switch x := call.Fun.(type) {
+	case *ast.Ident:
+		Name = x.Name
+	case *ast.SelectorExpr:
+		Name = x.Sel.Name
+	default:
+		return
+	}
+
+	if _, ok := printWrappers[f.Pkg.Types]; !ok && f.Pkg.Types != nil {
+		var files []*ast.File
+		for _, file := range f.Pkg.Files {
+			if file.AST != nil {
+				files = append(files, file.AST)
+			}
+		}
+		printWrappersOf(f.Pkg.Types, files, f.Pkg.Info)
+	}
+	wrapper, ok := calleePrintWrapper(f.Pkg.Types, f.Pkg.Info, call)
+	if !ok {
 		return
 	}
-	if skip, ok := printList[name]; ok {
-		f.checkPrint(call, Name, skip)
+	if wrapper.isPrintf {
+		f.checkPrintf(call, Name, wrapper.skip)
+	} else {
+		f.checkPrint(call, Name, wrapper.skip)
+	}
+}
+
+// prepStringerReceiver checks whether the given declaration is a fmt.Stringer
+// implementation, and if so sets the File's lastStringerReceiver field to the
+// declaration's receiver object.
+func (f *File) prepStringerReceiver(d *ast.FuncDecl) {
+	if !f.isStringer(d) {
 		return
 	}
+	if l := d.Recv.List; len(l) == 1 {
+		if n := l[0].Names; len(n) == 1 {
+			f.lastStringerReceiver = n[0].Obj
+		}
+	}
+}
+
+// isStringer returns true if the provided declaration is a "String() string"
+// method; an implementation of fmt.Stringer.
+func (f *File) isStringer(d *ast.FuncDecl) bool {
+	return d.Recv != nil && d.Name.Name == "String" &&
+		len(d.Type.Params.List) == 0 && len(d.Type.Results.List) == 1 &&
+		f.Pkg.Info.Types[d.Type.Results.List[0].Type] == types.Typ[types.String]
 }
 
 // formatState holds the parsed representation of a printf directive such as "%3.*[4]d".
@@ -86,7 +169,7 @@ func (f *File) checkPrintf(call *ast.CallExpr, name string, formatIndex int) {
 		f.Warn(call.Pos(), "too few arguments in call to", name)
 		return
 	}
-	lit := f.pkg.values[call.Args[formatIndex]]
+	lit := f.Pkg.Info.Values[call.Args[formatIndex]]
 	if lit == nil {
 		if *verbose {
 			f.Warn(call.Pos(), "can't check non-constant format in call to", name)
@@ -380,7 +463,7 @@ func (f *File) okPrintfArg(call *ast.CallExpr, state *formatState) (ok bool) {
 	arg := call.Args[argNum]
 	if !f.matchArgType(v.typ, nil, arg) {
 		typeString := ""
-		if typ := f.pkg.types[arg]; typ != nil {
+		if typ := f.Pkg.Info.Types[arg]; typ != nil {
 			typeString = typ.String()
 		}
 		f.Badf(call.Pos(), "arg %s for printf verb %%%c of wrong type: %s", f.gofmt(arg), state.verb, typeString)
@@ -478,7 +561,7 @@ func (f *File) checkPrint(call *ast.CallExpr, name string, firstArg int) {
 	arg := args[firstArg]
 	if lit, ok := arg.(*ast.BasicLit); ok && lit.Kind == token.STRING {
 		if strings.Contains(lit.Value, "%") {
-			f.Badf(call.Pos(), "possible formatting directive in %s call", name)
+			f.BadFixf(call.Pos(), f.printfFix(call, name, firstArg, lit), "possible formatting directive in %s call", name)
 		}
 	}
 	if isLn {
@@ -496,3 +579,98 @@ func (f *File) checkPrint(call *ast.CallExpr, name string, firstArg int) {
 		}
 	}
 }
+
+// printfFix returns the edits that turn a call to the print function
+// name, whose first printed argument, call.Args[firstArg], is the
+// string literal lit, into a call to the corresponding formatted-print
+// function, e.g. Println into Printf, or nil if there is none.  There
+// is none unless lit is a format whose verbs consume exactly the
+// remaining arguments: Println("100%", x) is not meant as a Printf.
+//
+func (f *File) printfFix(call *ast.CallExpr, name string, firstArg int, lit *ast.BasicLit) []TextEdit {
+	if call.Ellipsis.IsValid() {
+		return nil
+	}
+	format, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return nil
+	}
+	if n, ok := formatArgCount(format); !ok || n != len(call.Args)-firstArg-1 {
+		return nil
+	}
+	var id *ast.Ident
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	}
+	base := strings.TrimSuffix(name, "ln")
+	skip, ok := printfList[strings.ToLower(base+"f")]
+	if !ok || skip != printList[strings.ToLower(name)] {
+		return nil
+	}
+	edits := []TextEdit{f.Edit(id.Pos(), id.End(), base+"f")}
+	if base != name {
+		// Println adds a newline; so must the format.
+		if lit.Value[0] != '"' {
+			return nil // raw string
+		}
+		end := lit.End() - 1 // the closing quote
+		edits = append(edits, f.Edit(end, end, `\n`))
+	}
+	return edits
+}
+
+// formatArgCount returns the number of arguments consumed by the verbs
+// of format, and whether format is well formed: whether each % begins
+// a directive that ends with a known verb.  Formats with explicit
+// argument indexes are not counted.
+func formatArgCount(format string) (n int, ok bool) {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(format) && strings.ContainsRune("#0+- ", rune(format[i])) {
+			i++ // flags
+		}
+		num := func() { // a width or precision: digits or *
+			if i < len(format) && format[i] == '*' {
+				n++
+				i++
+				return
+			}
+			for i < len(format) && '0' <= format[i] && format[i] <= '9' {
+				i++
+			}
+		}
+		num()
+		if i < len(format) && format[i] == '.' {
+			i++
+			num()
+		}
+		if i == len(format) || format[i] == '[' {
+			return 0, false
+		}
+		verb, w := utf8.DecodeRuneInString(format[i:])
+		if !isPrintVerb(verb) {
+			return 0, false
+		}
+		i += w - 1
+		if verb != '%' {
+			n++
+		}
+	}
+	return n, true
+}
+
+// isPrintVerb reports whether verb is listed in printVerbs.
+func isPrintVerb(verb rune) bool {
+	for _, v := range printVerbs {
+		if v.verb == verb {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file infers which functions are print wrappers: functions that
+// pass their format and arguments on to a print function, such as
+//
+//	func Logf(format string, args ...interface{}) {
+//		log.Printf("app: "+format, args...)
+//	}
+//
+// Calls of a wrapper are checked as calls of the function it wraps.
+
+package vet
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"code.google.com/p/go.tools/go/exact"
+	"code.google.com/p/go.tools/go/types"
+)
+
+// A printWrapper describes how a function passes its arguments to a
+// print function.  If isPrintf, it is Printf-like and skip is the
+// index of its format parameter; otherwise it is Print-like and skip
+// is the index of its first printed parameter.
+type printWrapper struct {
+	isPrintf bool
+	skip     int
+}
+
+// printWrappers holds the facts inferred for each package: its print
+// wrappers, by their objects.  The facts for a package are computed
+// once, when it is checked or when the package of a call being checked
+// imports it, and kept for the rest of the run, so that they are
+// available to every package that imports it.
+var printWrappers = make(map[*types.Package]map[types.Object]printWrapper)
+
+// printWrappersOf returns the print wrappers of the package with the
+// given syntax and type information, inferring them if necessary.
+// Wrappers may wrap other wrappers of the same or of imported packages,
+// so the inference is repeated until it finds no new ones.
+func printWrappersOf(pkg *types.Package, files []*ast.File, info *types.Info) map[types.Object]printWrapper {
+	if w, ok := printWrappers[pkg]; ok {
+		return w
+	}
+	w := make(map[types.Object]printWrapper)
+	printWrappers[pkg] = w
+	for changed := true; changed; {
+		changed = false
+		for _, file := range files {
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || fn.Body == nil {
+					continue
+				}
+				obj := info.Objects[fn.Name]
+				if _, done := w[obj]; obj == nil || done {
+					continue
+				}
+				if wrapper, ok := inferPrintWrapper(pkg, info, fn, obj); ok {
+					w[obj] = wrapper
+					changed = true
+				}
+			}
+		}
+	}
+	return w
+}
+
+// importedPrintWrappers returns the print wrappers of an imported
+// package, which the importer has loaded from source, or nil if its
+// source is not available.
+func importedPrintWrappers(pkg *types.Package) map[types.Object]printWrapper {
+	if w, ok := printWrappers[pkg]; ok {
+		return w
+	}
+	if imp != nil {
+		for _, info := range imp.AllPackages() {
+			if info.Pkg == pkg {
+				return printWrappersOf(pkg, info.Files, &info.Info)
+			}
+		}
+	}
+	printWrappers[pkg] = nil
+	return nil
+}
+
+// inferPrintWrapper reports whether fn, whose object is obj, is a print
+// wrapper, and if so how: whether it ends with a "format string, args
+// ...interface{}" or an "args ...interface{}" parameter list, and
+// passes format and args... or just args... on to a print function as
+// the corresponding arguments.  The format may be passed on with
+// constant text added, as in "app: "+format.
+func inferPrintWrapper(pkg *types.Package, info *types.Info, fn *ast.FuncDecl, obj types.Object) (printWrapper, bool) {
+	sig, ok := obj.Type().(*types.Signature)
+	if !ok || !sig.IsVariadic() {
+		return printWrapper{}, false
+	}
+	params := sig.Params()
+	n := params.Len()
+	args := params.At(n - 1)
+	if !isEmptyInterfaceSlice(args.Type()) {
+		return printWrapper{}, false
+	}
+	var format *types.Var
+	if n >= 2 && types.IsIdentical(params.At(n-2).Type(), types.Typ[types.String]) {
+		format = params.At(n - 2)
+	}
+
+	var wrapper printWrapper
+	found := false
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if found || !ok || !call.Ellipsis.IsValid() {
+			return !found
+		}
+		last := len(call.Args) - 1
+		if id, ok := call.Args[last].(*ast.Ident); !ok || info.Objects[id] != args {
+			return true
+		}
+		callee, ok := calleePrintWrapper(pkg, info, call)
+		if !ok {
+			return true
+		}
+		if callee.isPrintf && format != nil && callee.skip == last-1 {
+			if isFormatArg(info, call.Args[last-1], format) {
+				wrapper, found = printWrapper{true, n - 2}, true
+			}
+		} else if !callee.isPrintf && callee.skip == last {
+			wrapper, found = printWrapper{false, n - 1}, true
+		}
+		return !found
+	})
+	return wrapper, found
+}
+
+// isFormatArg reports whether the format argument x passes on the
+// format parameter: whether it is format itself, or a concatenation of
+// format and constant strings that contain no verbs, so that the
+// arguments it consumes are those of format.
+func isFormatArg(info *types.Info, x ast.Expr, format *types.Var) bool {
+	switch x := unparen(x).(type) {
+	case *ast.Ident:
+		return info.Objects[x] == format
+	case *ast.BinaryExpr:
+		if x.Op != token.ADD {
+			return false
+		}
+		if isVerbless(info, x.Y) {
+			return isFormatArg(info, x.X, format)
+		}
+		return isVerbless(info, x.X) && isFormatArg(info, x.Y, format)
+	}
+	return false
+}
+
+// isVerbless reports whether x is a constant string without verbs; it
+// may contain %% only.
+func isVerbless(info *types.Info, x ast.Expr) bool {
+	v := info.Values[x]
+	if v == nil || v.Kind() != exact.String {
+		return false
+	}
+	return !strings.Contains(strings.Replace(exact.StringVal(v), "%%", "", -1), "%")
+}
+
+// calleePrintWrapper reports whether call, in the package with the
+// given type information, is a call of a print function, and if so
+// how the callee takes its arguments.  A function is a print function
+// if it is a wrapper inferred for its package or if its name is in
+// printfList or printList.
+func calleePrintWrapper(pkg *types.Package, info *types.Info, call *ast.CallExpr) (printWrapper, bool) {
+	var id *ast.Ident
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	default:
+		return printWrapper{}, false
+	}
+	if fn, ok := info.Objects[id].(*types.Func); ok && fn.Pkg() != nil {
+		w := printWrappers[pkg]
+		if fn.Pkg() != pkg {
+			w = importedPrintWrappers(fn.Pkg())
+		}
+		if wrapper, ok := w[fn]; ok {
+			return wrapper, true
+		}
+	}
+	name := strings.ToLower(id.Name)
+	if skip, ok := printfList[name]; ok {
+		return printWrapper{true, skip}, true
+	}
+	if skip, ok := printList[name]; ok {
+		return printWrapper{false, skip}, true
+	}
+	return printWrapper{}, false
+}
+
+// isEmptyInterfaceSlice reports whether typ is []interface{}.
+func isEmptyInterfaceSlice(typ types.Type) bool {
+	s, ok := typ.(*types.Slice)
+	if !ok {
+		return false
+	}
+	i, ok := s.Elem().Underlying().(*types.Interface)
+	return ok && i.NumMethods() == 0
+}
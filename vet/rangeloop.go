@@ -19,17 +19,22 @@ For example:
 See: http://golang.org/doc/go_faq.html#closures_and_goroutines
 */
 
-package main
+package vet
 
 import "go/ast"
 
+var rangeloopsAnalyzer = &Analyzer{
+	Name:  "rangeloops",
+	Doc:   "check that range loop variables are used correctly",
+	Nodes: []ast.Node{(*ast.RangeStmt)(nil)},
+	Run:   checkRangeLoop,
+}
+
 // checkRangeLoop walks the body of the provided range statement, checking if
 // its index or value variables are used unsafely inside goroutines or deferred
 // function literals.
-func checkRangeLoop(f *File, n *ast.RangeStmt) {
-	if !vet("rangeloops") {
-		return
-	}
+func checkRangeLoop(f *File, node ast.Node) {
+	n := node.(*ast.RangeStmt)
 	key, _ := n.Key.(*ast.Ident)
 	val, _ := n.Value.(*ast.Ident)
 	if key == nil && val == nil {
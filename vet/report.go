@@ -0,0 +1,182 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vet
+
+// This file defines the diagnostics reported by analyzers and their
+// output, as text or, with -json, as JSON.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"os"
+)
+
+// The writers to which diagnostics are printed: JSON ones to stdout and
+// text ones to stderr.  Tests replace them to capture the diagnostics.
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// Severities of diagnostics.
+const (
+	SeverityError   = "error"   // a problem; sets the exit code
+	SeverityWarning = "warning" // a possible problem
+)
+
+// A Diagnostic is a problem reported by an analyzer.
+// With -json, each one is printed as a JSON object on a line of its own.
+type Diagnostic struct {
+	Check    string   `json:"check"`    // name of the analyzer, e.g. "printf"
+	Posn     Position `json:"posn"`     // location of the problem
+	Severity string   `json:"severity"` // SeverityError or SeverityWarning
+	Message  string   `json:"message"`
+
+	// Edits, if any, are a suggested fix for the problem, applied by -fix.
+	Edits []TextEdit `json:"edits,omitempty"`
+}
+
+// A TextEdit is a suggested replacement of the text of a file between
+// the byte offsets Pos and End by NewText.
+//
+type TextEdit struct {
+	Pos     int    `json:"pos"`
+	End     int    `json:"end"`
+	NewText string `json:"newText"`
+}
+
+// A Position is the location of a diagnostic.
+// Line and Column are 1-based; they are zero if the diagnostic
+// concerns the file as a whole.
+type Position struct {
+	Filename string `json:"filename"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Offset   int    `json:"offset"` // byte offset within the file
+}
+
+func (p Position) String() string {
+	// Do not print columns. Because the pos often points to the start of an
+	// expression instead of the inner part with the actual error, the
+	// precision can mislead.
+	switch {
+	case p.Filename == "":
+		return ""
+	case p.Line == 0:
+		return p.Filename
+	}
+	return fmt.Sprintf("%s:%d", p.Filename, p.Line)
+}
+
+// Bad reports an error and sets the exit code..
+func (f *File) Bad(pos token.Pos, args ...interface{}) {
+	f.report(f.position(pos), SeverityError, sprintln(args...), nil)
+}
+
+// Badf reports a formatted error and sets the exit code.
+func (f *File) Badf(pos token.Pos, format string, args ...interface{}) {
+	f.report(f.position(pos), SeverityError, fmt.Sprintf(format, args...), nil)
+}
+
+// BadFixf is like Badf, but also suggests edits that fix the problem.
+// The edits may be nil.
+func (f *File) BadFixf(pos token.Pos, edits []TextEdit, format string, args ...interface{}) {
+	f.report(f.position(pos), SeverityError, fmt.Sprintf(format, args...), edits)
+}
+
+// Warn reports an error but does not set the exit code.
+func (f *File) Warn(pos token.Pos, args ...interface{}) {
+	f.report(f.position(pos), SeverityWarning, sprintln(args...), nil)
+}
+
+// Warnf reports a formatted error but does not set the exit code.
+func (f *File) Warnf(pos token.Pos, format string, args ...interface{}) {
+	f.report(f.position(pos), SeverityWarning, fmt.Sprintf(format, args...), nil)
+}
+
+// WarnFixf is like Warnf, but also suggests edits that fix the problem.
+// The edits may be nil.
+func (f *File) WarnFixf(pos token.Pos, edits []TextEdit, format string, args ...interface{}) {
+	f.report(f.position(pos), SeverityWarning, fmt.Sprintf(format, args...), edits)
+}
+
+// Edit returns a TextEdit replacing the text of the file between pos
+// and end by newText.
+func (f *File) Edit(pos, end token.Pos, newText string) TextEdit {
+	return TextEdit{f.Fset.Position(pos).Offset, f.Fset.Position(end).Offset, newText}
+}
+
+// position returns the Position of pos.
+func (f *File) position(pos token.Pos) Position {
+	if pos == token.NoPos {
+		return Position{}
+	}
+	posn := f.Fset.Position(pos)
+	return Position{posn.Filename, posn.Line, posn.Column, posn.Offset}
+}
+
+// linePosition returns the Position of the start of the given line
+// of the file, which need not be a Go source file, or of the file
+// itself if line is zero.
+//
+func (f *File) linePosition(line int) Position {
+	posn := Position{Filename: f.Name}
+	if line > 0 {
+		posn.Line = line
+		posn.Column = 1
+		for n := 1; n < line; n++ {
+			i := bytes.IndexByte(f.Content[posn.Offset:], '\n')
+			if i < 0 {
+				break
+			}
+			posn.Offset += i + 1
+		}
+	}
+	return posn
+}
+
+// report prints a diagnostic with the given position, severity,
+// message and suggested edits, attributing it to the analyzer being
+// applied, unless it is suppressed.  With -fix, it records the edits
+// for applyFixes.
+//
+func (f *File) report(posn Position, severity, msg string, edits []TextEdit) {
+	d := Diagnostic{Posn: posn, Severity: severity, Message: msg, Edits: edits}
+	if a := f.Pkg.analyzer; a != nil {
+		d.Check = a.Name
+	}
+	if f.suppressed(d) {
+		return
+	}
+	if *fixFlag && edits != nil {
+		f.fixes = append(f.fixes, edits)
+	}
+	printDiagnostic(d)
+}
+
+// printDiagnostic prints d, setting the exit code if it is an error.
+func printDiagnostic(d Diagnostic) {
+	if d.Severity == SeverityError {
+		setExit(1)
+	}
+	if *jsonFlag {
+		data, err := json.Marshal(d)
+		if err != nil {
+			errorf("JSON error: %s", err)
+		}
+		fmt.Fprintf(stdout, "%s\n", data)
+		return
+	}
+	fmt.Fprintf(stderr, "%s: %s\n", d.Posn, d.Message)
+}
+
+// sprintln is fmt.Sprintln without the final newline.
+func sprintln(args ...interface{}) string {
+	msg := fmt.Sprintln(args...)
+	return msg[:len(msg)-1]
+}
@@ -28,15 +28,37 @@ For example:
 
 */
 
-package main
+package vet
 
 import (
+	"flag"
 	"go/ast"
 	"go/token"
 
 	"code.google.com/p/go.tools/go/types"
 )
 
+var strictShadowing = flag.Bool("shadowstrict", false, "whether to be strict about shadowing; can be noisy")
+
+var shadowAnalyzer = &Analyzer{
+	Name:         "shadow",
+	Doc:          "check for shadowed variables (experimental; must be set explicitly)",
+	Experimental: true,
+	Nodes:        []ast.Node{(*ast.AssignStmt)(nil), (*ast.GenDecl)(nil)},
+	Run:          checkShadow,
+}
+
+// checkShadow checks for shadowing in a short variable declaration or
+// a general variable declaration.
+func checkShadow(f *File, node ast.Node) {
+	switch n := node.(type) {
+	case *ast.AssignStmt:
+		f.checkShadowAssignment(n)
+	case *ast.GenDecl:
+		f.checkShadowDecl(n)
+	}
+}
+
 // Span stores the minimum range of byte positions in the file in which a
 // given variable (types.Object) is mentioned. It is lexically defined: it spans
 // from the beginning of its first mention to the end of its last mention.
@@ -91,9 +113,6 @@ func (pkg *Package) growSpan(ident *ast.Ident, obj types.Object) {
 
 // checkShadowAssignment checks for shadowing in a short variable declaration.
 func (f *File) checkShadowAssignment(a *ast.AssignStmt) {
-	if !vet("shadow") {
-		return
-	}
 	if a.Tok != token.DEFINE {
 		return
 	}
@@ -164,9 +183,6 @@ func (f *File) idiomaticRedecl(d *ast.ValueSpec) bool {
 
 // checkShadowDecl checks for shadowing in a general variable declaration.
 func (f *File) checkShadowDecl(d *ast.GenDecl) {
-	if !vet("shadow") {
-		return
-	}
 	if d.Tok != token.VAR {
 		return
 	}
@@ -189,7 +205,7 @@ func (f *File) checkShadowDecl(d *ast.GenDecl) {
 
 // checkShadowing checks whether the identifier shadows an identifier in an outer scope.
 func (f *File) checkShadowing(ident *ast.Ident) {
-	obj := f.pkg.idents[ident]
+	obj := f.Pkg.Info.Objects[ident]
 	if obj == nil {
 		return
 	}
@@ -211,7 +227,7 @@ func (f *File) checkShadowing(ident *ast.Ident) {
 	} else {
 		// Don't complain if the span of validity of the shadowed variable doesn't include
 		// the shadowing variable.
-		span, ok := f.pkg.spans[shadowed]
+		span, ok := f.Pkg.spans[shadowed]
 		if !ok {
 			f.Badf(ident.Pos(), "internal error: no range for %s", ident.Name)
 			return
@@ -222,6 +238,6 @@ func (f *File) checkShadowing(ident *ast.Ident) {
 	}
 	// Don't complain if the types differ: that implies the programmer really wants two variables.
 	if types.IsIdentical(obj.Type(), shadowed.Type()) {
-		f.Badf(ident.Pos(), "declaration of %s shadows declaration at %s", obj.Name(), f.loc(shadowed.Pos()))
+		f.Badf(ident.Pos(), "declaration of %s shadows declaration at %s", obj.Name(), f.position(shadowed.Pos()))
 	}
 }
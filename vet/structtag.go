@@ -0,0 +1,123 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains the test for canonical struct tags.
+
+package vet
+
+import (
+	"go/ast"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var structtagsAnalyzer = &Analyzer{
+	Name:  "structtags",
+	Doc:   "check that struct field tags have canonical format",
+	Nodes: []ast.Node{(*ast.Field)(nil)},
+	Run:   checkCanonicalFieldTag,
+}
+
+// checkCanonicalFieldTag checks a struct field tag.
+func checkCanonicalFieldTag(f *File, node ast.Node) {
+	field := node.(*ast.Field)
+	if field.Tag == nil {
+		return
+	}
+
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		f.Badf(field.Pos(), "unable to read struct tag %s", field.Tag.Value)
+		return
+	}
+
+	if !validTag(tag) {
+		var edits []TextEdit
+		if canon, ok := canonicalTag(tag); ok {
+			lit := "`" + canon + "`"
+			if strings.Contains(canon, "`") {
+				lit = strconv.Quote(canon)
+			}
+			edits = []TextEdit{f.Edit(field.Tag.Pos(), field.Tag.End(), lit)}
+		}
+		f.BadFixf(field.Pos(), edits, "struct field tag %s not compatible with reflect.StructTag.Get", field.Tag.Value)
+		return
+	}
+}
+
+// validTag reports whether tag is well-formed.
+func validTag(tag string) bool {
+	// Check tag for validity by appending
+	// new key:value to end and checking that
+	// the tag parsing code can find it.
+	return reflect.StructTag(tag+` _gofix:"_magic"`).Get("_gofix") == "_magic"
+}
+
+// canonicalTag returns the canonical form of a malformed tag whose
+// key:value pairs are separated by commas or extra spaces, have
+// spaces around the colon, or have single-quoted or unquoted values.
+// ok is false if the tag cannot be interpreted in this way.
+//
+func canonicalTag(tag string) (canon string, ok bool) {
+	const space = " \t\n"
+	var pairs []string
+	for {
+		tag = strings.TrimLeft(tag, space+",")
+		if tag == "" {
+			break
+		}
+
+		// Scan the key.
+		i := 0
+		for i < len(tag) && !strings.ContainsRune(space+`:"'`, rune(tag[i])) {
+			i++
+		}
+		if i == 0 {
+			return "", false
+		}
+		key := tag[:i]
+		tag = strings.TrimLeft(tag[i:], space)
+		if tag == "" || tag[0] != ':' {
+			return "", false
+		}
+		tag = strings.TrimLeft(tag[1:], space)
+
+		// Scan the value.
+		var value string
+		switch {
+		case tag == "":
+			return "", false
+		case tag[0] == '"' || tag[0] == '\'':
+			i = 1
+			for i < len(tag) && tag[i] != tag[0] {
+				if tag[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(tag) {
+				return "", false
+			}
+			value = tag[1:i]
+			if tag[0] == '"' {
+				var err error
+				if value, err = strconv.Unquote(tag[:i+1]); err != nil {
+					return "", false
+				}
+			}
+			tag = tag[i+1:]
+		default:
+			i = strings.IndexAny(tag, space)
+			if i < 0 {
+				i = len(tag)
+			}
+			value = tag[:i]
+			tag = tag[i:]
+		}
+		pairs = append(pairs, key+":"+strconv.Quote(value))
+	}
+	canon = strings.Join(pairs, " ")
+	return canon, pairs != nil && validTag(canon)
+}
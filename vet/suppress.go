@@ -0,0 +1,262 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vet
+
+// This file implements the suppression of diagnostics, by
+// //vet:ignore comments in the source and by the file named by
+// -config.
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"code.google.com/p/go.tools/refactor/util"
+)
+
+var configFlag = flag.String("config", "", "name of a JSON file listing checks to suppress by package or file")
+var unusedFlag = flag.Bool("unusedsuppressions", false, "report //vet:ignore comments and -config suppressions that suppress nothing")
+
+// ignorePrefix introduces a comment that suppresses diagnostics.
+const ignorePrefix = "//vet:ignore"
+
+// A Config is the contents of the file named by -config, for example:
+//
+//	{"Suppress": [
+//		{"Checks": ["composites"], "Packages": ["example.com/legacy/..."]},
+//		{"Checks": ["printf", "shadow"], "Files": ["*_test.go"]}
+//	]}
+//
+type Config struct {
+	Suppress []*Suppression
+}
+
+// A Suppression suppresses the diagnostics of the named checks, or of
+// all checks if none is named, in the packages whose import paths
+// match one of Packages, in which "..." matches any string, and in the
+// files whose names match one of Files, as for filepath.Match.  A
+// pattern without a slash is matched against the base name of a file.
+//
+type Suppression struct {
+	Checks   []string
+	Packages []string
+	Files    []string
+
+	used bool // whether it has suppressed a diagnostic
+}
+
+// config is the configuration loaded from the file named by -config.
+var config *Config
+
+// loadConfig reads the configuration from the named file.
+func loadConfig(filename string) (*Config, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return &c, nil
+}
+
+// matches reports whether s suppresses diagnostics of the named check
+// in the named file of the package with the given import path.
+//
+func (s *Suppression) matches(check, importPath, filename string) bool {
+	if !matchCheck(s.Checks, check) {
+		return false
+	}
+	for _, pattern := range s.Packages {
+		if importPath != "" && util.MatchPackage(pattern, importPath) {
+			return true
+		}
+	}
+	for _, pattern := range s.Files {
+		name := filepath.ToSlash(filename)
+		if !strings.Contains(pattern, "/") {
+			name = filepath.Base(filename)
+		}
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// An ignore is a //vet:ignore comment.  It is followed by the names
+// of the checks whose diagnostics it suppresses, separated by commas,
+// or by nothing, to suppress all diagnostics.  A comment that follows
+// code applies to that line; otherwise it applies to the next line,
+// or, if it is part of the doc comment of a declaration, to the whole
+// declaration.
+//
+type ignore struct {
+	posn       Position // position of the comment
+	checks     []string // names of the checks suppressed; all if empty
+	start, end int      // range of lines to which it applies
+	used       bool     // whether it has suppressed a diagnostic
+}
+
+// findIgnores returns the //vet:ignore comments of the file.
+// Those of assembly files are found by searching their text.
+//
+func (f *File) findIgnores() []*ignore {
+	var ignores []*ignore
+	add := func(offset, line int, text string) *ignore {
+		lineStart := f.linePosition(line).Offset
+		ig := &ignore{
+			posn:   Position{f.Name, line, 1 + offset - lineStart, offset},
+			checks: parseIgnore(text),
+			start:  line,
+			end:    line,
+		}
+		if len(bytes.TrimSpace(f.Content[lineStart:offset])) == 0 {
+			// The comment is alone on its line.
+			ig.start++
+			ig.end++
+		}
+		ignores = append(ignores, ig)
+		return ig
+	}
+
+	if f.AST == nil {
+		for i, line := range bytes.SplitAfter(f.Content, nl) {
+			if j := bytes.Index(line, []byte(ignorePrefix)); j >= 0 {
+				add(f.linePosition(i+1).Offset+j, i+1, string(line[j:]))
+			}
+		}
+		return ignores
+	}
+
+	docs := make(map[*ast.CommentGroup]ast.Node)
+	for _, decl := range f.AST.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			docs[decl.Doc] = decl
+		case *ast.GenDecl:
+			docs[decl.Doc] = decl
+		}
+	}
+	for _, cg := range f.AST.Comments {
+		for _, c := range cg.List {
+			if !strings.HasPrefix(c.Text, ignorePrefix) {
+				continue
+			}
+			posn := f.Fset.Position(c.Pos())
+			ig := add(posn.Offset, posn.Line, c.Text)
+			if decl := docs[cg]; decl != nil {
+				ig.end = f.Fset.Position(decl.End()).Line
+			}
+		}
+	}
+	return ignores
+}
+
+// parseIgnore returns the names of the checks suppressed by the
+// //vet:ignore comment text.
+//
+func parseIgnore(text string) []string {
+	text = strings.TrimPrefix(text, ignorePrefix)
+	if text == "" || text[0] != ' ' && text[0] != '\t' {
+		return nil // e.g. "//vet:ignore" or "//vet:ignored"
+	}
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil
+	}
+	return strings.Split(fields[0], ",")
+}
+
+// suppressed reports whether d, a diagnostic reported for the file,
+// is suppressed by a //vet:ignore comment or by the configuration,
+// recording which ones suppressed it.
+//
+func (f *File) suppressed(d Diagnostic) bool {
+	suppressed := false
+	for _, ig := range f.ignores {
+		if ig.start <= d.Posn.Line && d.Posn.Line <= ig.end && matchCheck(ig.checks, d.Check) {
+			ig.used = true
+			suppressed = true
+		}
+	}
+	if config != nil {
+		for _, s := range config.Suppress {
+			if s.matches(d.Check, f.Pkg.ImportPath, f.Name) {
+				s.used = true
+				suppressed = true
+			}
+		}
+	}
+	return suppressed
+}
+
+// reportUnusedIgnores reports the //vet:ignore comments of the files
+// that suppressed nothing, but that name an enabled check.
+//
+func reportUnusedIgnores(files []*File) {
+	for _, f := range files {
+		for _, ig := range f.ignores {
+			if !ig.used && (ig.checks == nil || anyEnabled(ig.checks)) {
+				printDiagnostic(Diagnostic{
+					Check:    "unusedsuppressions",
+					Posn:     ig.posn,
+					Severity: SeverityWarning,
+					Message:  "//vet:ignore comment suppresses nothing",
+				})
+			}
+		}
+	}
+}
+
+// reportUnusedConfig reports the suppressions of the configuration
+// that suppressed nothing.
+//
+func reportUnusedConfig() {
+	if config == nil {
+		return
+	}
+	for i, s := range config.Suppress {
+		if !s.used && (s.Checks == nil || anyEnabled(s.Checks)) {
+			printDiagnostic(Diagnostic{
+				Check:    "unusedsuppressions",
+				Posn:     Position{Filename: *configFlag},
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("suppression %d (checks %v) suppresses nothing", i, s.Checks),
+			})
+		}
+	}
+}
+
+// matchCheck reports whether check is one of the names in checks, or
+// checks is empty.
+//
+func matchCheck(checks []string, check string) bool {
+	if len(checks) == 0 {
+		return true
+	}
+	for _, c := range checks {
+		if c == check {
+			return true
+		}
+	}
+	return false
+}
+
+// anyEnabled reports whether any of the named checks is enabled.
+func anyEnabled(checks []string) bool {
+	for _, a := range enabled {
+		if matchCheck(checks, a.Name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,15 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file is the input to TestAnalyzer.
+
+package testdata
+
+func f(s string) int {
+	return len(g(s))
+}
+
+func g(s string) []byte {
+	return []byte(s)
+}
@@ -0,0 +1,11 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file is used by TestImporter.
+
+package testdata
+
+import "geom"
+
+var origin = geom.Point{0, 0}
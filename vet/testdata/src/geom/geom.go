@@ -0,0 +1,11 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package geom is imported by testdata/importer.go.  It is not
+// installed, so it must be loaded from source.
+package geom
+
+type Point struct {
+	X, Y int
+}
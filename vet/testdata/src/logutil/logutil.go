@@ -0,0 +1,39 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package logutil is imported by testdata/wrappers.go.  It defines
+// print wrappers, which vet must infer from its source.
+package logutil
+
+// Printf and Println are print functions by name.
+func Printf(format string, args ...interface{}) {}
+func Println(args ...interface{})               {}
+
+func Logf(format string, args ...interface{}) {
+	Printf(format, args...)
+}
+
+func Debugf(level int, format string, args ...interface{}) {
+	if level > 0 {
+		Logf(format, args...)
+	}
+}
+
+func Prefixf(format string, args ...interface{}) {
+	Printf("logutil: "+format+"\n", args...)
+}
+
+func Log(args ...interface{}) {
+	Println(args...)
+}
+
+// Skipf is not a wrapper: it does not pass on all of its arguments.
+func Skipf(format string, args ...interface{}) {
+	Printf(format, args[1:]...)
+}
+
+// Levelf is not a wrapper: its prefix consumes an argument.
+func Levelf(format string, args ...interface{}) {
+	Printf("%d: "+format, args...)
+}
@@ -0,0 +1,23 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file is the input to TestSuppress.
+
+package testdata
+
+import "fmt"
+
+func f(x int) {
+	fmt.Println("a %d", x) //vet:ignore printf
+	//vet:ignore
+	fmt.Println("b %d", x)
+	fmt.Println("c %d", x) //vet:ignore shadow,printf
+	fmt.Println("d %d", x) //vet:ignore nilfunc
+	fmt.Println("e %d", x)
+}
+
+//vet:ignore printf -- legacy code
+func g(x int) {
+	fmt.Println("f %d", x)
+}
@@ -0,0 +1,22 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file is used by TestPrintWrappers.
+
+package testdata
+
+import "logutil"
+
+func warnf(format string, args ...interface{}) {
+	logutil.Debugf(1, format, args...)
+}
+
+func wrappers() {
+	warnf("%d", "x")
+	logutil.Debugf(1, "%s", 1)
+	logutil.Log("%d", 1)
+	logutil.Prefixf("%s %s", "x")
+	logutil.Levelf("%s", "x")
+	logutil.Skipf("%s", "x", "y")
+}
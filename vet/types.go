@@ -4,7 +4,7 @@
 
 // This file contains the pieces of the tool that use typechecking from the go/types package.
 
-package main
+package vet
 
 import (
 	"go/ast"
@@ -12,36 +12,89 @@ import (
 
 	"code.google.com/p/go.tools/go/exact"
 	"code.google.com/p/go.tools/go/types"
+	"code.google.com/p/go.tools/importer"
 )
 
+// imp loads the packages imported by those being checked, from source
+// files selected by buildContext, so that the type information is
+// complete even if they are not installed.  It is created on first use.
+var (
+	imp       *importer.Importer
+	impConfig *importer.Config
+)
+
+// sourceImporter returns imp, creating it if necessary.
+func sourceImporter() *importer.Importer {
+	if imp == nil {
+		impConfig = &importer.Config{Build: &buildContext}
+		imp = importer.New(impConfig)
+		// By providing our own error function, the type checker will
+		// continue past the first error. There is no need for that
+		// function to do anything.
+		impConfig.TypeChecker.Error = func(error) {}
+		// Files that import "C" cannot be processed by cgo here.
+		impConfig.TypeChecker.FakeImportC = true
+	}
+	return imp
+}
+
 func (pkg *Package) check(fs *token.FileSet, astFiles []*ast.File) error {
-	pkg.idents = make(map[*ast.Ident]types.Object)
 	pkg.spans = make(map[types.Object]Span)
-	pkg.types = make(map[ast.Expr]types.Type)
-	pkg.values = make(map[ast.Expr]exact.Value)
-	// By providing a Config with our own error function, it will continue
-	// past the first error. There is no need for that function to do anything.
-	config := types.Config{
-		Error: func(error) {},
+	pkg.Info = &types.Info{
+		Types:   make(map[ast.Expr]types.Type),
+		Values:  make(map[ast.Expr]exact.Value),
+		Objects: make(map[*ast.Ident]types.Object),
 	}
-	info := &types.Info{
-		Types:   pkg.types,
-		Values:  pkg.values,
-		Objects: pkg.idents,
-	}
-	_, err := config.Check(pkg.path, fs, astFiles, info)
+	sourceImporter() // initializes impConfig
+	config := impConfig.TypeChecker
+	var err error
+	pkg.Types, err = config.Check(pkg.Path, fs, astFiles, pkg.Info)
 	// update spans
-	for id, obj := range pkg.idents {
+	for id, obj := range pkg.Info.Objects {
 		pkg.growSpan(id, obj)
 	}
 	return err
 }
 
+// calleeName returns the name of the function called by call, in the
+// form returned by (*types.Func).FullName, or the plain name of a
+// built-in function.  It returns "" if the callee is not a declared
+// function, or is unknown.
+func (f *File) calleeName(call *ast.CallExpr) string {
+	var id *ast.Ident
+	switch fun := unparen(call.Fun).(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	default:
+		return ""
+	}
+	switch obj := f.Pkg.Info.Objects[id].(type) {
+	case *types.Func:
+		return obj.FullName()
+	case *types.Builtin:
+		return obj.Name()
+	}
+	return ""
+}
+
+// unparen returns e with any enclosing parentheses stripped.
+func unparen(e ast.Expr) ast.Expr {
+	for {
+		p, ok := e.(*ast.ParenExpr)
+		if !ok {
+			return e
+		}
+		e = p.X
+	}
+}
+
 // isStruct reports whether the composite literal c is a struct.
 // If it is not (probably a struct), it returns a printable form of the type.
 func (pkg *Package) isStruct(c *ast.CompositeLit) (bool, string) {
 	// Check that the CompositeLit's type is a slice or array (which needs no field keys), if possible.
-	typ := pkg.types[c]
+	typ := pkg.Info.Types[c]
 	// If it's a named type, pull out the underlying type. If it's not, the Underlying
 	// method returns the type itself.
 	actual := typ
@@ -90,7 +143,7 @@ func (f *File) matchArgTypeInternal(t printfArgType, typ types.Type, arg ast.Exp
 	}
 	if typ == nil {
 		// external call
-		typ = f.pkg.types[arg]
+		typ = f.Pkg.Info.Types[arg]
 		if typ == nil {
 			return true // probably a type check problem
 		}
@@ -249,7 +302,7 @@ func (f *File) matchStructArgType(t printfArgType, typ *types.Struct, arg ast.Ex
 // being called has.
 func (f *File) numArgsInSignature(call *ast.CallExpr) int {
 	// Check the type of the function or method declaration
-	typ := f.pkg.types[call.Fun]
+	typ := f.Pkg.Info.Types[call.Fun]
 	if typ == nil {
 		return 0
 	}
@@ -265,10 +318,10 @@ func (f *File) numArgsInSignature(call *ast.CallExpr) int {
 //	func Error() string
 // where "string" is the universe's string type. We know the method is called "Error".
 func (f *File) isErrorMethodCall(call *ast.CallExpr) bool {
-	typ := f.pkg.types[call]
+	typ := f.Pkg.Info.Types[call]
 	if typ != nil {
 		// We know it's called "Error", so just check the function signature.
-		return types.IsIdentical(f.pkg.types[call.Fun], stringerMethodType)
+		return types.IsIdentical(f.Pkg.Info.Types[call.Fun], stringerMethodType)
 	}
 	// Without types, we can still check by hand.
 	// Is it a selector expression? Otherwise it's a function call, not a method call.
@@ -281,7 +334,7 @@ func (f *File) isErrorMethodCall(call *ast.CallExpr) bool {
 		return false
 	}
 	// Check the type of the method declaration
-	typ = f.pkg.types[sel]
+	typ = f.Pkg.Info.Types[sel]
 	if typ == nil {
 		return false
 	}
@@ -0,0 +1,69 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains the check for unused results of calls to certain
+// pure functions.
+
+package vet
+
+import (
+	"flag"
+	"go/ast"
+	"strings"
+)
+
+var unusedfuncs = flag.String("unusedfuncs", "", "comma-separated list of functions whose results must be used")
+
+var unusedresultAnalyzer = &Analyzer{
+	Name:  "unusedresult",
+	Doc:   "check for unused results of calls to certain pure functions",
+	Nodes: []ast.Node{(*ast.ExprStmt)(nil)},
+	Init:  initUnusedFuncs,
+	Run:   checkUnusedResult,
+}
+
+// unusedFuncs records the functions whose results must be used, by
+// the names returned by (*types.Func).FullName, such as "fmt.Sprintf"
+// or "(*bytes.Buffer).String", or, for built-in functions, by their
+// plain names.
+var unusedFuncs = map[string]bool{
+	"append":                 true,
+	"bytes.TrimSpace":        true,
+	"errors.New":             true,
+	"fmt.Errorf":             true,
+	"fmt.Sprint":             true,
+	"fmt.Sprintf":            true,
+	"fmt.Sprintln":           true,
+	"sort.Reverse":           true,
+	"strings.Replace":        true,
+	"strings.TrimSpace":      true,
+	"(*bytes.Buffer).String": true,
+}
+
+// initUnusedFuncs adds the functions named by the -unusedfuncs flag to
+// unusedFuncs.
+func initUnusedFuncs() {
+	if *unusedfuncs == "" {
+		return
+	}
+	for _, name := range strings.Split(*unusedfuncs, ",") {
+		if len(name) == 0 {
+			flag.Usage()
+		}
+		unusedFuncs[name] = true
+	}
+}
+
+// checkUnusedResult reports a call statement that discards the result
+// of a function listed in unusedFuncs.
+func checkUnusedResult(f *File, node ast.Node) {
+	call, ok := unparen(node.(*ast.ExprStmt).X).(*ast.CallExpr)
+	if !ok {
+		return
+	}
+	name := f.calleeName(call)
+	if name != "" && unusedFuncs[name] {
+		f.Badf(call.Pos(), "result of %s call not used", name)
+	}
+}
@@ -0,0 +1,350 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package vet is a simple checker for static errors in Go source code.
+// The checks are performed by Analyzers; Main runs a set of them as
+// the vet command.  See code.google.com/p/go.tools/cmd/vet for the
+// documentation of the standard checks.
+package vet
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+var verbose = flag.Bool("v", false, "verbose")
+var testFlag = flag.Bool("test", false, "for testing only: sets -all and -shadow")
+var allFlag = flag.Bool("all", true, "check everything; disabled if any explicit check is requested")
+var jsonFlag = flag.Bool("json", false, "emit diagnostics as JSON on standard output")
+var tagsFlag = flag.String("tags", "", "space-separated list of build tags to satisfy when selecting files")
+var exitCode = 0
+
+// enabled holds the analyzers to apply, as determined by the flags.
+var enabled []*Analyzer
+
+// buildContext selects the files of the packages to check and locates
+// the packages they import.  Its GOOS and GOARCH are taken from the
+// environment and its build tags from -tags.
+var buildContext = build.Default
+
+// setExit sets the value for os.Exit when it is called, later.  It
+// remembers the highest value.
+func setExit(err int) {
+	if err > exitCode {
+		exitCode = err
+	}
+}
+
+// Usage is a replacement usage function for the flags package.
+func Usage() {
+	fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "\tvet [flags] directory...\n")
+	fmt.Fprintf(os.Stderr, "\tvet [flags] files... # Must be a single package\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+// File is a wrapper for the state of a file used in the parser.
+// It is passed to the Run function of each Analyzer.
+type File struct {
+	Pkg     *Package       // the package to which the file belongs
+	Fset    *token.FileSet // position information for AST
+	Name    string         // name of the file
+	Content []byte         // contents of the file
+	AST     *ast.File      // syntax tree; nil unless a Go source file
+	b       bytes.Buffer   // for use by methods
+	fixes   [][]TextEdit   // edits suggested by each diagnostic, with -fix
+	ignores []*ignore      // the file's //vet:ignore comments
+
+	// The last "String() string" method receiver we saw while walking.
+	// This is used by the recursiveStringer method in print.go.
+	lastStringerReceiver *ast.Object
+
+	// The || and && expressions that are part of a chain already
+	// checked.  This is used by checkBool in bool.go.
+	boolSeen map[*ast.BinaryExpr]bool
+}
+
+// Main is the vet command.  It defines a flag for each of the
+// analyzers, which must have distinct names, and applies those that
+// are enabled to the packages or files named on the command line.
+//
+// A command with additional analyzers may be built by passing them,
+// together with the standard ones, to Main:
+//
+//	vet.Main(append(vet.Analyzers, myAnalyzer)...)
+//
+func Main(analyzers ...*Analyzer) {
+	// Flags to control which checks to perform. "all" is set to true, and
+	// disabled if a flag is set explicitly.
+	report := make(map[*Analyzer]*bool)
+	for _, a := range analyzers {
+		report[a] = flag.Bool(a.Name, false, a.Doc)
+	}
+
+	flag.Usage = Usage
+	flag.Parse()
+	buildContext.BuildTags = strings.Fields(*tagsFlag)
+
+	// If a check is named explicitly, turn off the 'all' flag.
+	for _, ptr := range report {
+		if *ptr {
+			*allFlag = false
+			break
+		}
+	}
+
+	for _, a := range analyzers {
+		if *testFlag || *report[a] || *allFlag && !a.Experimental {
+			enabled = append(enabled, a)
+		}
+	}
+	for _, a := range enabled {
+		if a.Init != nil {
+			a.Init()
+		}
+	}
+	if *configFlag != "" {
+		var err error
+		if config, err = loadConfig(*configFlag); err != nil {
+			errorf("%s", err)
+		}
+	}
+
+	if flag.NArg() == 0 {
+		Usage()
+	}
+	dirs := false
+	files := false
+	for _, name := range flag.Args() {
+		// Is it a directory?
+		fi, err := os.Stat(name)
+		if err != nil {
+			warnf("error walking tree: %s", err)
+			continue
+		}
+		if fi.IsDir() {
+			dirs = true
+		} else {
+			files = true
+		}
+	}
+	if dirs && files {
+		Usage()
+	}
+	if dirs {
+		for _, name := range flag.Args() {
+			walkDir(name)
+		}
+		if *unusedFlag {
+			reportUnusedConfig()
+		}
+		return
+	}
+	if !doPackage(".", flag.Args()) {
+		warnf("no files checked")
+	}
+	if *unusedFlag {
+		reportUnusedConfig()
+	}
+	os.Exit(exitCode)
+}
+
+// prefixDirectory places the directory name on the beginning of each name in the list.
+func prefixDirectory(directory string, names []string) {
+	if directory != "." {
+		for i, name := range names {
+			names[i] = filepath.Join(directory, name)
+		}
+	}
+}
+
+// doPackageDir analyzes the single package found in the directory, if there is one,
+// plus a test package, if there is one.
+func doPackageDir(directory string) {
+	pkg, err := buildContext.ImportDir(directory, 0)
+	if err != nil {
+		// If it's just that there are no go source files, that's fine.
+		if _, nogo := err.(*build.NoGoError); nogo {
+			return
+		}
+		// Non-fatal: we are doing a recursive walk and there may be other directories.
+		warnf("cannot process directory %s: %s", directory, err)
+		return
+	}
+	var names []string
+	names = append(names, pkg.GoFiles...)
+	names = append(names, pkg.CgoFiles...)
+	names = append(names, pkg.TestGoFiles...) // These are also in the "foo" package.
+	names = append(names, pkg.SFiles...)
+	prefixDirectory(directory, names)
+	doPackage(directory, names)
+	// Is there also a "foo_test" package? If so, do that one as well.
+	if len(pkg.XTestGoFiles) > 0 {
+		names = pkg.XTestGoFiles
+		prefixDirectory(directory, names)
+		doPackage(directory, names)
+	}
+}
+
+// A Package is a package being checked.
+// It is passed to the RunPackage function of each Analyzer.
+type Package struct {
+	Path       string         // name of the package
+	ImportPath string         // import path of the package, if known
+	Files      []*File        // files of the package, including assembly files
+	Types      *types.Package // type information; may be incomplete
+	Info       *types.Info    // type information about the syntax trees
+	spans      map[types.Object]Span
+
+	analyzer *Analyzer // the analyzer being applied
+}
+
+// doPackage analyzes the single package constructed from the named files.
+// It returns whether any files were checked.
+func doPackage(directory string, names []string) bool {
+	var files []*File
+	var astFiles []*ast.File
+	fs := sourceImporter().Fset
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			// Warn but continue to next package.
+			warnf("%s: %s", name, err)
+			return false
+		}
+		defer f.Close()
+		data, err := ioutil.ReadAll(f)
+		if err != nil {
+			warnf("%s: %s", name, err)
+			return false
+		}
+		var parsedFile *ast.File
+		if strings.HasSuffix(name, ".go") {
+			parsedFile, err = parser.ParseFile(fs, name, bytes.NewReader(data), parser.ParseComments)
+			if err != nil {
+				warnf("%s: %s", name, err)
+				return false
+			}
+			astFiles = append(astFiles, parsedFile)
+		}
+		files = append(files, &File{Fset: fs, Content: data, Name: name, AST: parsedFile})
+	}
+	if len(astFiles) == 0 {
+		return false
+	}
+	pkg := new(Package)
+	pkg.Path = astFiles[0].Name.Name
+	pkg.Files = files
+	if bp, err := buildContext.ImportDir(filepath.Dir(names[0]), build.FindOnly); err == nil && bp.ImportPath != "." {
+		pkg.ImportPath = bp.ImportPath
+	}
+	// Type check the package.
+	err := pkg.check(fs, astFiles)
+	if err != nil && *verbose {
+		warnf("%s", err)
+	}
+	for _, file := range files {
+		file.Pkg = pkg
+		file.ignores = file.findIgnores()
+		if file.AST != nil {
+			file.walkFile(file.Name, file.AST)
+		}
+	}
+	for _, a := range enabled {
+		if a.RunPackage != nil {
+			pkg.analyzer = a
+			a.RunPackage(pkg)
+		}
+	}
+	if *unusedFlag {
+		reportUnusedIgnores(files)
+	}
+	if *fixFlag {
+		applyFixes(files)
+	}
+	return true
+}
+
+func visit(path string, f os.FileInfo, err error) error {
+	if err != nil {
+		warnf("walk error: %s", err)
+		return err
+	}
+	// One package per directory. Ignore the files themselves.
+	if !f.IsDir() {
+		return nil
+	}
+	doPackageDir(path)
+	return nil
+}
+
+func (pkg *Package) hasFileWithSuffix(suffix string) bool {
+	for _, f := range pkg.Files {
+		if strings.HasSuffix(f.Name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkDir recursively walks the tree looking for Go packages.
+func walkDir(root string) {
+	filepath.Walk(root, visit)
+}
+
+// errorf formats the error to standard error, adding program
+// identification and a newline, and exits.
+func errorf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "vet: "+format+"\n", args...)
+	os.Exit(2)
+}
+
+// warnf formats the error to standard error, adding program
+// identification and a newline, but does not exit.
+func warnf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "vet: "+format+"\n", args...)
+	setExit(1)
+}
+
+// Println is fmt.Println guarded by -v.
+func Println(args ...interface{}) {
+	if !*verbose {
+		return
+	}
+	fmt.Println(args...)
+}
+
+// Printf is fmt.Printf guarded by -v.
+func Printf(format string, args ...interface{}) {
+	if !*verbose {
+		return
+	}
+	fmt.Printf(format+"\n", args...)
+}
+
+// walkFile walks the file's tree, applying the enabled analyzers.
+func (f *File) walkFile(name string, file *ast.File) {
+	Println("Checking file", name)
+	ast.Walk(newVisitor(f, enabled), file)
+}
+
+// gofmt returns a string representation of the expression.
+func (f *File) gofmt(x ast.Expr) string {
+	f.b.Reset()
+	printer.Fprint(&f.b, f.Fset, x)
+	return f.b.String()
+}
@@ -0,0 +1,319 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func init() {
+	// Load imported packages from testdata/src rather than from the
+	// installation, so that the tests see the same packages everywhere.
+	dir, err := filepath.Abs("testdata")
+	if err != nil {
+		panic(err)
+	}
+	buildContext.GOROOT = dir
+	buildContext.GOPATH = dir
+}
+
+// TestAnalyzer checks that an analyzer is applied to the nodes of
+// interest and to the package, with type information.
+func TestAnalyzer(t *testing.T) {
+	var got []string
+	a := &Analyzer{
+		Name:  "calls",
+		Doc:   "report calls and their types",
+		Nodes: []ast.Node{(*ast.CallExpr)(nil)},
+		Run: func(f *File, node ast.Node) {
+			call := node.(*ast.CallExpr)
+			got = append(got, fmt.Sprintf("%s: %s", f.gofmt(call), f.Pkg.Info.Types[call]))
+		},
+		RunPackage: func(pkg *Package) {
+			got = append(got, fmt.Sprintf("package %s: %d file(s)", pkg.Types.Path(), len(pkg.Files)))
+		},
+	}
+	enabled = []*Analyzer{a}
+	defer func() { enabled = nil }()
+
+	if !doPackage(".", []string{"testdata/analyzer.go"}) {
+		t.Fatal("no files checked")
+	}
+	want := []string{
+		"len(g(s)): int",
+		"g(s): []byte",
+		"[]byte(s): []byte",
+		"package testdata: 1 file(s)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// TestImporter checks that imported packages are type-checked from
+// source, even if they are not installed.
+func TestImporter(t *testing.T) {
+	var got []string
+	a := &Analyzer{
+		Name:  "composites",
+		Doc:   "report composite literals and their types",
+		Nodes: []ast.Node{(*ast.CompositeLit)(nil)},
+		Run: func(f *File, node ast.Node) {
+			lit := node.(*ast.CompositeLit)
+			isStruct, _ := f.Pkg.isStruct(lit)
+			got = append(got, fmt.Sprintf("%s: %s, struct=%t", f.gofmt(lit), f.Pkg.Info.Types[lit], isStruct))
+		},
+	}
+	enabled = []*Analyzer{a}
+	defer func() { enabled = nil }()
+
+	if !doPackage(".", []string{"testdata/importer.go"}) {
+		t.Fatal("no files checked")
+	}
+	want := []string{"geom.Point{0, 0}: geom.Point, struct=true"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// TestPrintWrappers checks that print wrappers are inferred, in the
+// package being checked and in the packages it imports, and that their
+// calls are checked.
+func TestPrintWrappers(t *testing.T) {
+	enabled = []*Analyzer{printfAnalyzer}
+	var buf bytes.Buffer
+	stderr = &buf
+	defer func() {
+		enabled = nil
+		stderr = os.Stderr
+	}()
+
+	if !doPackage(".", []string{"testdata/wrappers.go"}) {
+		t.Fatal("no files checked")
+	}
+	got := make(map[string]printWrapper)
+	for pkg, wrappers := range printWrappers {
+		if pkg == nil || pkg.Path() != "logutil" && !strings.HasSuffix(pkg.Path(), "testdata") {
+			continue
+		}
+		for obj, wrapper := range wrappers {
+			got[pkg.Name()+"."+obj.Name()] = wrapper
+		}
+	}
+	want := map[string]printWrapper{
+		"logutil.Logf":    {true, 0},
+		"logutil.Debugf":  {true, 1},
+		"logutil.Prefixf": {true, 0},
+		"logutil.Log":     {false, 0},
+		"testdata.warnf":  {true, 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	gotDiags := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	wantDiags := []string{
+		"testdata/wrappers.go:16: arg \"x\" for printf verb %d of wrong type: string",
+		"testdata/wrappers.go:17: arg 1 for printf verb %s of wrong type: int",
+		"testdata/wrappers.go:18: possible formatting directive in Log call",
+		"testdata/wrappers.go:19: missing argument for Prefixf verb %s: need 2, have 1",
+	}
+	if !reflect.DeepEqual(gotDiags, wantDiags) {
+		t.Errorf("got diagnostics %q, want %q", gotDiags, wantDiags)
+	}
+}
+
+// TestJSON checks that, with -json, each diagnostic is printed to
+// stdout as a JSON object on a line of its own.
+func TestJSON(t *testing.T) {
+	enabled = []*Analyzer{printfAnalyzer}
+	var buf bytes.Buffer
+	stdout = &buf
+	*jsonFlag = true
+	defer func() {
+		enabled = nil
+		stdout = os.Stdout
+		*jsonFlag = false
+	}()
+
+	filename := "testdata/wrappers.go"
+	if !doPackage(".", []string{filename}) {
+		t.Fatal("no files checked")
+	}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if !strings.HasSuffix(out, "\n") {
+		t.Fatalf("output does not end with a newline: %q", out)
+	}
+	var diags []Diagnostic
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		var d Diagnostic
+		if err := json.Unmarshal([]byte(line), &d); err != nil {
+			t.Fatalf("invalid JSON line %q: %s", line, err)
+		}
+		diags = append(diags, d)
+	}
+	if len(diags) != 4 {
+		t.Fatalf("got %d diagnostics, want 4:\n%s", len(diags), out)
+	}
+
+	offset := bytes.Index(content, []byte(`warnf("%d", "x")`))
+	want := Diagnostic{
+		Check:    "printf",
+		Posn:     Position{Filename: filename, Line: 16, Column: 2, Offset: offset},
+		Severity: SeverityError,
+		Message:  `arg "x" for printf verb %d of wrong type: string`,
+	}
+	if !reflect.DeepEqual(diags[0], want) {
+		t.Errorf("got diagnostic %+v, want %+v", diags[0], want)
+	}
+	for i, d := range diags {
+		if line := 16 + i; d.Posn.Line != line {
+			t.Errorf("diagnostic %d: got line %d, want %d", i, d.Posn.Line, line)
+		}
+	}
+}
+
+func TestLinePosition(t *testing.T) {
+	f := &File{Name: "a.s", Content: []byte("one\ntwo\n\nfour")}
+	for _, test := range []struct {
+		line int
+		want Position
+	}{
+		{0, Position{"a.s", 0, 0, 0}},
+		{1, Position{"a.s", 1, 1, 0}},
+		{2, Position{"a.s", 2, 1, 4}},
+		{4, Position{"a.s", 4, 1, 9}},
+	} {
+		if got := f.linePosition(test.line); got != test.want {
+			t.Errorf("linePosition(%d) = %+v, want %+v", test.line, got, test.want)
+		}
+	}
+}
+
+// TestFix checks that -fix applies the fixes suggested by the
+// composites, structtags and printf checks.
+func TestFix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	input, err := ioutil.ReadFile("testdata/fix.input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "fix.go")
+	if err := ioutil.WriteFile(filename, input, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	enabled = []*Analyzer{compositesAnalyzer, printfAnalyzer, structtagsAnalyzer}
+	*fixFlag = true
+	defer func() {
+		enabled = nil
+		*fixFlag = false
+	}()
+	if !doPackage(".", []string{filename}) {
+		t.Fatal("no files checked")
+	}
+
+	got, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile("testdata/fix.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestSuppress checks which diagnostics are suppressed by the
+// //vet:ignore comments of testdata/suppress.go and by a Config.
+func TestSuppress(t *testing.T) {
+	filename := "testdata/suppress.go"
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, content, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &File{
+		Pkg:     &Package{ImportPath: "example.com/legacy/x"},
+		Fset:    fset,
+		Name:    filename,
+		Content: content,
+		AST:     file,
+	}
+	f.ignores = f.findIgnores()
+
+	// suppressed reports whether the diagnostic of the check on the
+	// line containing substr is suppressed.
+	suppressed := func(check, substr string) bool {
+		line := bytes.Count(content[:bytes.Index(content, []byte(substr))], []byte("\n")) + 1
+		return f.suppressed(Diagnostic{Check: check, Posn: Position{Filename: filename, Line: line}})
+	}
+	for _, test := range []struct {
+		check, substr string
+		want          bool
+	}{
+		{"printf", `"a %d"`, true},
+		{"nilfunc", `"a %d"`, false},
+		{"nilfunc", `"b %d"`, true},
+		{"printf", `"c %d"`, true},
+		{"printf", `"d %d"`, false},
+		{"printf", `"e %d"`, false},
+		{"printf", `"f %d"`, true},
+		{"printf", `func g`, true},
+		{"nilfunc", `"f %d"`, false},
+	} {
+		if got := suppressed(test.check, test.substr); got != test.want {
+			t.Errorf("%s diagnostic at %s: got suppressed=%t, want %t", test.check, test.substr, got, test.want)
+		}
+	}
+
+	// The nilfunc comment was not used.
+	for _, ig := range f.ignores {
+		if want := len(ig.checks) == 0 || ig.checks[0] != "nilfunc"; ig.used != want {
+			t.Errorf("ignore %v at %s: got used=%t, want %t", ig.checks, ig.posn, ig.used, want)
+		}
+	}
+
+	config = &Config{Suppress: []*Suppression{
+		{Checks: []string{"printf"}, Packages: []string{"example.com/legacy/..."}},
+		{Files: []string{"testdata/*.go"}},
+		{Files: []string{"*_test.go"}},
+	}}
+	defer func() { config = nil }()
+	if !suppressed("printf", `"e %d"`) || !suppressed("nilfunc", `"e %d"`) {
+		t.Errorf("diagnostics were not suppressed by the configuration")
+	}
+	for i, s := range config.Suppress {
+		if want := i < 2; s.used != want {
+			t.Errorf("suppression %d: got used=%t, want %t", i, s.used, want)
+		}
+	}
+}